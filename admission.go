@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// getAdmissionDenial returns the message with which an admission webhook or a ValidatingAdmissionPolicy denied a
+// request.
+//
+// Admission denials are usually reported as Forbidden, just like RBAC denials, so they can only be told apart by their
+// message.
+func getAdmissionDenial(err error) (string, bool) {
+	var status apierrors.APIStatus
+	if err == nil || !errors.As(err, &status) {
+		return "", false
+	}
+	message := status.Status().Message
+	deniedByWebhook := strings.Contains(message, "admission webhook") && strings.Contains(message, "denied the request")
+	deniedByPolicy := strings.Contains(message, "ValidatingAdmissionPolicy") && strings.Contains(message, "denied request")
+	if !deniedByWebhook && !deniedByPolicy {
+		return "", false
+	}
+	return message, true
+}
+
+// isRetryableDeletionError checks whether a failed deletion is worth retrying, which is not the case for deletions
+// denied by admission control if skipDeniedDeletionRetries is true, since they will keep being denied until the webhook
+// or policy changes
+func isRetryableDeletionError(err error) bool {
+	_, denied := getAdmissionDenial(err)
+	return !denied || !skipDeniedDeletionRetries
+}
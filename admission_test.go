@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestGetAdmissionDenial(t *testing.T) {
+	podsGroupResource := schema.GroupResource{Resource: "pods"}
+	scenarios := []struct {
+		name           string
+		err            error
+		expectedDenied bool
+	}{
+		{
+			name:           "webhook",
+			err:            apierrors.NewForbidden(podsGroupResource, "pod-name", errors.New(`admission webhook "deny.example.com" denied the request: pods may not be deleted`)),
+			expectedDenied: true,
+		},
+		{
+			name:           "webhook-with-custom-code",
+			err:            apierrors.NewBadRequest(`admission webhook "deny.example.com" denied the request without explanation`),
+			expectedDenied: true,
+		},
+		{
+			name:           "validating-admission-policy",
+			err:            apierrors.NewForbidden(podsGroupResource, "pod-name", errors.New(`ValidatingAdmissionPolicy 'deny-deletions' with binding 'deny-deletions' denied request: pods may not be deleted`)),
+			expectedDenied: true,
+		},
+		{
+			name:           "rbac",
+			err:            apierrors.NewForbidden(podsGroupResource, "pod-name", errors.New(`User "system:serviceaccount:kube-system:k8s-ttl-controller" cannot delete resource "pods"`)),
+			expectedDenied: false,
+		},
+		{
+			name:           "not-an-api-error",
+			err:            errors.New(`admission webhook "deny.example.com" denied the request`),
+			expectedDenied: false,
+		},
+		{
+			name:           "nil",
+			err:            nil,
+			expectedDenied: false,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			message, denied := getAdmissionDenial(scenario.err)
+			if denied != scenario.expectedDenied {
+				t.Errorf("expected denied=%t, got %t", scenario.expectedDenied, denied)
+			}
+			if denied && message != scenario.err.Error() {
+				t.Errorf("expected message '%s', got '%s'", scenario.err.Error(), message)
+			}
+		})
+	}
+}
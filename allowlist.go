@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// AllowlistEntry describes resources that are allowed to be deleted
+type AllowlistEntry struct {
+	// Resource is the name of the resource (e.g. pods, deployments)
+	Resource string `json:"resource"`
+
+	// Namespace is a glob pattern that the namespace of the resource must match (e.g. team-*).
+	// If empty, resources in any namespace match, as well as cluster-scoped resources.
+	Namespace string `json:"namespace,omitempty"`
+
+	// LabelSelector is a label selector that the resource must match (e.g. app=ephemeral,tier!=production).
+	// If empty, resources with any labels match.
+	LabelSelector string `json:"labelSelector,omitempty"`
+
+	selector labels.Selector
+}
+
+// Allowlist is a list of entries describing which resources are allowed to be deleted.
+//
+// When an allowlist is configured, resources that do not match any of its entries are never deleted.
+type Allowlist []AllowlistEntry
+
+// LoadAllowlist reads and validates the allowlist from the file at the given path
+func LoadAllowlist(filePath string) (Allowlist, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var allowlist Allowlist
+	if err = yaml.UnmarshalStrict(data, &allowlist); err != nil {
+		return nil, fmt.Errorf("invalid allowlist: %w", err)
+	}
+	for i := range allowlist {
+		if allowlist[i].Resource == "" {
+			return nil, fmt.Errorf("invalid allowlist: entry #%d has no resource", i+1)
+		}
+		if _, err = path.Match(allowlist[i].Namespace, ""); err != nil {
+			return nil, fmt.Errorf("invalid allowlist: entry #%d has an invalid namespace pattern '%s': %w", i+1, allowlist[i].Namespace, err)
+		}
+		if allowlist[i].selector, err = labels.Parse(allowlist[i].LabelSelector); err != nil {
+			return nil, fmt.Errorf("invalid allowlist: entry #%d has an invalid label selector '%s': %w", i+1, allowlist[i].LabelSelector, err)
+		}
+	}
+	return allowlist, nil
+}
+
+// Allows returns whether an item of the given resource matches at least one entry of the allowlist
+func (allowlist Allowlist) Allows(resource string, item unstructured.Unstructured) bool {
+	for _, entry := range allowlist {
+		if entry.Resource != resource {
+			continue
+		}
+		if entry.Namespace != "" {
+			if matched, _ := path.Match(entry.Namespace, item.GetNamespace()); !matched {
+				continue
+			}
+		}
+		if entry.selector != nil && !entry.selector.Matches(labels.Set(item.GetLabels())) {
+			continue
+		}
+		return true
+	}
+	return false
+}
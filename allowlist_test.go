@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestLoadAllowlist(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		content       string
+		expectedError bool
+	}{
+		{
+			name:    "valid",
+			content: "- resource: pods\n  namespace: team-*\n  labelSelector: app=ephemeral\n- resource: jobs\n",
+		},
+		{
+			name:          "missing-resource",
+			content:       "- namespace: team-*\n",
+			expectedError: true,
+		},
+		{
+			name:          "invalid-label-selector",
+			content:       "- resource: pods\n  labelSelector: app in (\n",
+			expectedError: true,
+		},
+		{
+			name:          "invalid-namespace-pattern",
+			content:       "- resource: pods\n  namespace: team-[\n",
+			expectedError: true,
+		},
+		{
+			name:          "unknown-field",
+			content:       "- resource: pods\n  namespaces: team-*\n",
+			expectedError: true,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "allowlist.yaml")
+			if err := os.WriteFile(filePath, []byte(scenario.content), 0o600); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_, err := LoadAllowlist(filePath)
+			if scenario.expectedError != (err != nil) {
+				t.Errorf("expected error to be %t, got %v", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAllowlist_Allows(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "allowlist.yaml")
+	if err := os.WriteFile(filePath, []byte("- resource: pods\n  namespace: team-*\n  labelSelector: app=ephemeral\n- resource: jobs\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	allowlist, err := LoadAllowlist(filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ephemeralPod := newUnstructuredWithAnnotations("v1", "Pod", "team-a", "pod-name", time.Now(), map[string]interface{}{})
+	ephemeralPod.SetLabels(map[string]string{"app": "ephemeral"})
+	ephemeralPodInOtherNamespace := newUnstructuredWithAnnotations("v1", "Pod", "kube-system", "pod-name", time.Now(), map[string]interface{}{})
+	ephemeralPodInOtherNamespace.SetLabels(map[string]string{"app": "ephemeral"})
+	unlabeledPod := newUnstructuredWithAnnotations("v1", "Pod", "team-a", "pod-name", time.Now(), map[string]interface{}{})
+	job := newUnstructuredWithAnnotations("batch/v1", "Job", "anywhere", "job-name", time.Now(), map[string]interface{}{})
+	deployment := newUnstructuredWithAnnotations("apps/v1", "Deployment", "team-a", "deployment-name", time.Now(), map[string]interface{}{})
+	deployment.SetLabels(map[string]string{"app": "ephemeral"})
+	if !allowlist.Allows("pods", *ephemeralPod) {
+		t.Error("expected pod matching namespace pattern and label selector to be allowed")
+	}
+	if allowlist.Allows("pods", *ephemeralPodInOtherNamespace) {
+		t.Error("expected pod not matching namespace pattern to not be allowed")
+	}
+	if allowlist.Allows("pods", *unlabeledPod) {
+		t.Error("expected pod not matching label selector to not be allowed")
+	}
+	if !allowlist.Allows("jobs", *job) {
+		t.Error("expected job to be allowed, because the entry has no namespace pattern or label selector")
+	}
+	if allowlist.Allows("deployments", *deployment) {
+		t.Error("expected deployment to not be allowed, because no entry matches its resource")
+	}
+}
+
+func TestReconcile_withAllowlist(t *testing.T) {
+	defer func(previous Allowlist) { allowlist = previous }(allowlist)
+	filePath := filepath.Join(t.TempDir(), "allowlist.yaml")
+	if err := os.WriteFile(filePath, []byte("- resource: pods\n  labelSelector: app=ephemeral\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var err error
+	if allowlist, err = LoadAllowlist(filePath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	allowedPod := newUnstructuredWithAnnotations("v1", "Pod", "default", "allowed-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	allowedPod.SetLabels(map[string]string{"app": "ephemeral"})
+	unlistedPod := newUnstructuredWithAnnotations("v1", "Pod", "default", "unlisted-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	for _, pod := range []*unstructured.Unstructured{allowedPod, unlistedPod} {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 1 || !remaining["unlisted-pod-name"] {
+		t.Errorf("expected only the expired pod matching no entry of the allowlist to be left, got %v", remaining)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ArchiveWebhookTimeout is the maximum time to wait for the archive webhook to respond
+const ArchiveWebhookTimeout = 10 * time.Second
+
+var ErrArchiveFailed = errors.New("failed to archive resource")
+
+// WebhookArchiver archives resources before they are deleted by POSTing their manifest to a webhook, which provides a
+// way to recover resources that should not have been deleted
+type WebhookArchiver struct {
+	url                   string
+	client                *http.Client
+	failureBlocksDeletion bool
+}
+
+// NewWebhookArchiver creates a new WebhookArchiver.
+//
+// If failureBlocksDeletion is true, resources that fail to be archived are not deleted.
+func NewWebhookArchiver(url string, failureBlocksDeletion bool) *WebhookArchiver {
+	return &WebhookArchiver{url: url, client: &http.Client{Timeout: ArchiveWebhookTimeout}, failureBlocksDeletion: failureBlocksDeletion}
+}
+
+// Archive POSTs the manifest of an item to the webhook as JSON, and returns an error unless the webhook responds with a
+// 2xx status code
+func (a *WebhookArchiver) Archive(item unstructured.Unstructured) error {
+	payload, err := item.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrArchiveFailed, err)
+	}
+	response, err := a.client.Post(a.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrArchiveFailed, err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("%w: webhook responded with status code %d", ErrArchiveFailed, response.StatusCode)
+	}
+	return nil
+}
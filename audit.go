@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// AuditCommand is the command that scans the cluster for resources with an invalid TTL annotation without deleting
+// anything
+const AuditCommand = "audit"
+
+// invalidTTL is a resource whose TTL annotation could not be parsed
+type invalidTTL struct {
+	gvr        schema.GroupVersionResource
+	namespace  string
+	name       string
+	annotation string
+	ttl        string
+	err        error
+}
+
+// Audit lists every resource that can be reconciled and returns those whose TTL annotation could not be parsed.
+//
+// Resources that fail to be listed are skipped and returned as list failures, so that a single unlistable resource
+// doesn't prevent the rest of the cluster from being audited.
+func Audit(kubernetesClient kubernetes.Interface, dynamicClient dynamic.Interface) ([]invalidTTL, map[schema.GroupVersionResource]error, error) {
+	groups, resources, err := kubernetesClient.Discovery().ServerGroupsAndResources()
+	if err != nil {
+		return nil, nil, &DiscoveryError{Err: err}
+	}
+	if preferredVersionOnly {
+		resources = filterPreferredVersions(groups, resources)
+	}
+	resources = coalesceVersions(groups, resources)
+	var invalidTTLs []invalidTTL
+	listFailures := make(map[schema.GroupVersionResource]error)
+	for _, resource := range resources {
+		gv, err := schema.ParseGroupVersion(resource.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, apiResource := range resource.APIResources {
+			gvr := gv.WithResource(apiResource.Name)
+			if strings.Contains(apiResource.Name, "/") || !isReconcilable(gvr, apiResource) {
+				continue
+			}
+			for _, namespace := range getNamespacesToList(apiResource) {
+				continueToken := ""
+				for {
+					list, err := listItems(context.TODO(), dynamicClient, gvr, apiResource, namespace, metav1.ListOptions{TimeoutSeconds: &listTimeoutSeconds, Continue: continueToken, Limit: ListLimit})
+					if err != nil {
+						logger.Debug(fmt.Sprintf("Skipping %s from %s during audit: %s", gvr.Resource, gvr.GroupVersion(), err))
+						listFailures[gvr] = err
+						break
+					}
+					for _, item := range list.Items {
+						annotation, ttl, exists := getTTLAnnotation(item.GetAnnotations())
+						if !exists || isTTLDisabled(ttl) {
+							continue
+						}
+						if _, err := parseTTL(ttl); err != nil {
+							invalidTTLs = append(invalidTTLs, invalidTTL{gvr: gvr, namespace: item.GetNamespace(), name: item.GetName(), annotation: annotation, ttl: ttl, err: err})
+						}
+					}
+					throttler.Sleep()
+					if continueToken = list.GetContinue(); continueToken == "" {
+						break
+					}
+				}
+			}
+		}
+	}
+	return invalidTTLs, listFailures, nil
+}
+
+// printAuditReport prints every resource whose TTL annotation could not be parsed, along with the parse error
+func printAuditReport(writer io.Writer, invalidTTLs []invalidTTL, listFailures map[schema.GroupVersionResource]error) {
+	tw := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "RESOURCE\tNAMESPACE\tNAME\tANNOTATION\tTTL\tERROR")
+	for _, invalid := range invalidTTLs {
+		namespace := invalid.namespace
+		if namespace == "" {
+			namespace = "<cluster>"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", invalid.gvr.GroupResource().String(), namespace, invalid.name, invalid.annotation, invalid.ttl, invalid.err)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintf(writer, "\n%d resources have an invalid TTL\n", len(invalidTTLs))
+	if len(listFailures) > 0 {
+		_, _ = fmt.Fprintf(writer, "%d resources could not be audited, because they failed to be listed\n", len(listFailures))
+	}
+}
+
+// runAudit audits the cluster and prints the report, returning the exit code reflecting its outcome
+func runAudit(writer io.Writer) int {
+	kubernetesClient, dynamicClient, err := CreateClients()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to create Kubernetes clients: %s", err))
+		return ExitCodeFailure
+	}
+	invalidTTLs, listFailures, err := Audit(kubernetesClient, dynamicClient)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Audit failed: %s", err))
+		return ExitCodeDiscoveryFailed
+	}
+	printAuditReport(writer, invalidTTLs, listFailures)
+	if len(invalidTTLs) > 0 {
+		return ExitCodeInvalidTTLsFound
+	}
+	return ExitCodeSuccess
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAudit(t *testing.T) {
+	kubernetesClient, dynamicClient, _ := newTestClients(podsAPIResourceList)
+	ttlByPodName := map[string]string{
+		"valid-pod-name":    "5m",
+		"disabled-pod-name": "never",
+		"invalid-pod-name":  "5 minutes",
+	}
+	for name, ttl := range ttlByPodName {
+		pod := newUnstructuredWithAnnotations("v1", "Pod", "default", name, time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: ttl})
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	invalidTTLs, listFailures, err := Audit(kubernetesClient, dynamicClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(listFailures) != 0 {
+		t.Errorf("expected no list failures, got %v", listFailures)
+	}
+	if len(invalidTTLs) != 1 || invalidTTLs[0].name != "invalid-pod-name" || invalidTTLs[0].ttl != "5 minutes" {
+		t.Fatalf("expected only the pod with an invalid TTL to be reported, got %+v", invalidTTLs)
+	}
+	// Nothing must be deleted during an audit, not even expired resources
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != len(ttlByPodName) {
+		t.Errorf("expected no resources to have been deleted, got %v", remaining)
+	}
+	var buffer bytes.Buffer
+	printAuditReport(&buffer, invalidTTLs, listFailures)
+	if report := buffer.String(); !strings.Contains(report, "invalid-pod-name") || !strings.Contains(report, "1 resources have an invalid TTL") {
+		t.Errorf("expected the report to contain the pod with an invalid TTL, got:\n%s", report)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/TwiN/kevent"
+)
+
+// DefaultCircuitBreakerMinimumAttempts is the default number of deletion attempts below which the circuit breaker never
+// trips, so that a couple of failures early in an execution don't halt it
+const DefaultCircuitBreakerMinimumAttempts = 10
+
+// CircuitBreaker halts deletions for the rest of an execution once the ratio of failed deletions exceeds a threshold,
+// since that usually means that something is systemically wrong (e.g. an admission webhook rejecting every deletion, or
+// a degraded API server) and that carrying on would only make matters worse.
+type CircuitBreaker struct {
+	threshold       float64 // Ratio of failed deletions from which deletions are halted
+	minimumAttempts int     // Number of deletion attempts below which deletions are never halted
+
+	eventManager *kevent.EventManager
+	open         bool // Whether deletions are halted for the current execution
+}
+
+// NewCircuitBreaker creates a new CircuitBreaker
+func NewCircuitBreaker(threshold float64, minimumAttempts int) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, minimumAttempts: minimumAttempts}
+}
+
+// NewExecution resets the circuit breaker, which is only ever open for the execution during which it tripped
+func (b *CircuitBreaker) NewExecution(eventManager *kevent.EventManager) {
+	b.eventManager = eventManager
+	b.open = false
+}
+
+// Allow checks whether deletions are allowed, tripping the circuit breaker if too many deletions failed during the
+// current execution.
+//
+// Must be called with the deletionMutex held.
+func (b *CircuitBreaker) Allow() bool {
+	if b.open {
+		return false
+	}
+	attempts := deletedResourcesInExecution + failedDeletionsInExecution
+	if attempts < b.minimumAttempts || float64(failedDeletionsInExecution)/float64(attempts) < b.threshold {
+		return true
+	}
+	b.open = true
+	message := fmt.Sprintf("Halting deletions for the rest of the execution, because %d out of %d deletions failed, which exceeds the threshold of %.0f%%", failedDeletionsInExecution, attempts, b.threshold*100)
+	logger.Error("[CircuitBreaker] " + message)
+	circuitBreakerTrippedTotal.Inc()
+	if b.eventManager != nil {
+		createControllerEvent(b.eventManager, "DeletionsHalted", message, true)
+	}
+	return false
+}
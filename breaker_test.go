@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCircuitBreaker_Allow(t *testing.T) {
+	defer func(deleted, failed int) {
+		deletedResourcesInExecution, failedDeletionsInExecution = deleted, failed
+	}(deletedResourcesInExecution, failedDeletionsInExecution)
+	scenarios := []struct {
+		name     string
+		deleted  int
+		failed   int
+		expected bool
+	}{
+		{name: "no-attempts", deleted: 0, failed: 0, expected: true},
+		{name: "below-minimum-attempts", deleted: 0, failed: 9, expected: true},
+		{name: "below-threshold", deleted: 6, failed: 4, expected: true},
+		{name: "at-threshold", deleted: 5, failed: 5, expected: false},
+		{name: "above-threshold", deleted: 1, failed: 20, expected: false},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			breaker := NewCircuitBreaker(0.5, 10)
+			breaker.NewExecution(nil)
+			deletedResourcesInExecution, failedDeletionsInExecution = scenario.deleted, scenario.failed
+			if allowed := breaker.Allow(); allowed != scenario.expected {
+				t.Errorf("expected %t, got %t", scenario.expected, allowed)
+			}
+			// Once open, the circuit breaker remains open until the next execution
+			deletedResourcesInExecution, failedDeletionsInExecution = 100, 0
+			if allowed := breaker.Allow(); allowed != scenario.expected {
+				t.Errorf("expected the circuit breaker to remain in the same state, got %t", allowed)
+			}
+			breaker.NewExecution(nil)
+			if !breaker.Allow() {
+				t.Error("expected the circuit breaker to be closed at the start of a new execution")
+			}
+		})
+	}
+}
+
+func TestReconcile_withCircuitBreaker(t *testing.T) {
+	defer func(previous *CircuitBreaker) { circuitBreaker = previous }(circuitBreaker)
+	circuitBreaker = NewCircuitBreaker(0.5, 4)
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	for i := 0; i < 10; i++ {
+		pod := newUnstructuredWithAnnotations("v1", "Pod", "default", fmt.Sprintf("expired-pod-name-%d", i), time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	deleteAttempts := 0
+	dynamicClient.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deleteAttempts++
+		return true, nil, apierrors.NewInternalError(fmt.Errorf("admission webhook unavailable"))
+	})
+	trippedBefore := testutil.ToFloat64(circuitBreakerTrippedTotal)
+	result, err := Reconcile(kubernetesClient, dynamicClient, eventManager)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if deleteAttempts != 4 || result.FailedDeletions != 4 {
+		t.Errorf("expected deletions to have been halted after 4 failed attempts, got %d attempts and %d failed deletions", deleteAttempts, result.FailedDeletions)
+	}
+	if tripped := testutil.ToFloat64(circuitBreakerTrippedTotal) - trippedBefore; tripped != 1 {
+		t.Errorf("expected the circuit breaker to have tripped once, got %v", tripped)
+	}
+	// Deletions are attempted again in the next execution
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if deleteAttempts != 8 {
+		t.Errorf("expected deletions to have been attempted again in the next execution, got %d attempts in total", deleteAttempts)
+	}
+}
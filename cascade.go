@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/TwiN/kevent"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	LabelCascadeID   = "k8s-ttl-controller.twin.sh/cascade-id"   // Set on parents, identifies the parent of the resources labeled with LabelCascadeFrom
+	LabelCascadeFrom = "k8s-ttl-controller.twin.sh/cascade-from" // Set on children, references the LabelCascadeID of their parent
+)
+
+// CascadeTracker keeps track of parents labeled with LabelCascadeID across executions so that, once a parent is
+// deleted, the children labeled with LabelCascadeFrom can be reaped.
+//
+// Since parents and children may be of any kind and therefore listed in any order, whether a parent still exists can
+// only be determined once all resources have been listed, which is why children are collected during the execution and
+// only reaped by Finish.
+//
+// The resource of each parent is tracked along with its ID, since a parent that is no longer listed has only
+// disappeared if its resource was listed, as opposed to excluded by a narrower configuration.
+type CascadeTracker struct {
+	known    map[string]schema.GroupVersionResource // Parents found in the last complete execution, with their resource
+	deleted  map[string]bool                        // Parents that have disappeared, and whose children are being reaped
+	seen     map[string]schema.GroupVersionResource // Parents found in the current execution, with their resource
+	children []cascadeChild                         // Children found in the current execution
+}
+
+// cascadeParent is a parent tracked by the CascadeTracker
+type cascadeParent struct {
+	gvr schema.GroupVersionResource
+	id  string
+}
+
+type cascadeChild struct {
+	gvr    schema.GroupVersionResource
+	item   unstructured.Unstructured
+	parent string
+}
+
+// NewCascadeTracker creates a new CascadeTracker
+func NewCascadeTracker() *CascadeTracker {
+	return &CascadeTracker{
+		known:   make(map[string]schema.GroupVersionResource),
+		deleted: make(map[string]bool),
+		seen:    make(map[string]schema.GroupVersionResource),
+	}
+}
+
+// NewExecution forgets the parents and children found in the previous execution
+func (c *CascadeTracker) NewExecution() {
+	c.seen = make(map[string]schema.GroupVersionResource)
+	c.children = nil
+}
+
+// Observe records an item listed during the current execution if it is either a parent or a child
+func (c *CascadeTracker) Observe(gvr schema.GroupVersionResource, item unstructured.Unstructured) {
+	labels := item.GetLabels()
+	if id := labels[LabelCascadeID]; id != "" {
+		c.seen[id] = gvr
+	}
+	if parent := labels[LabelCascadeFrom]; parent != "" && item.GetDeletionTimestamp() == nil {
+		c.children = append(c.children, cascadeChild{gvr: gvr, item: item, parent: parent})
+	}
+}
+
+// Finish compares the parents found in the current execution with those found in the last complete execution, and
+// returns the children of the parents that have since disappeared.
+//
+// If the execution was incomplete (e.g. a resource could not be listed), the absence of a parent proves nothing, so
+// no children are returned and the parents found are not remembered. Likewise, a parent whose resource was not listed
+// during the current execution (e.g. because the resources to watch were narrowed) is still remembered, but is not
+// considered deleted.
+func (c *CascadeTracker) Finish(complete bool, listed map[schema.GroupVersionResource]bool) []cascadeChild {
+	if !complete {
+		logger.Info("[Cascade] Not all resources could be listed, skipping the detection of deleted parents")
+		return nil
+	}
+	for id, gvr := range c.known {
+		if _, seen := c.seen[id]; seen || c.deleted[id] {
+			continue
+		}
+		if !listed[gvr] {
+			c.seen[id] = gvr
+			continue
+		}
+		logger.Info(fmt.Sprintf("[Cascade] Parent %s no longer exists, its children will be deleted", id))
+		c.deleted[id] = true
+	}
+	for id := range c.seen {
+		// A parent recreated with the same ID is no longer considered deleted
+		delete(c.deleted, id)
+	}
+	c.known = c.seen
+	var orphans []cascadeChild
+	remaining := make(map[string]bool)
+	for _, child := range c.children {
+		if c.deleted[child.parent] {
+			orphans = append(orphans, child)
+			remaining[child.parent] = true
+		}
+	}
+	// Forget the deleted parents that no longer have any children
+	for id := range c.deleted {
+		if !remaining[id] {
+			delete(c.deleted, id)
+		}
+	}
+	return orphans
+}
+
+// Snapshot returns the parents found in the last complete execution and those whose children are being reaped, both
+// sorted
+func (c *CascadeTracker) Snapshot() (known []cascadeParent, deleted []string) {
+	for id, gvr := range c.known {
+		known = append(known, cascadeParent{gvr: gvr, id: id})
+	}
+	for id := range c.deleted {
+		deleted = append(deleted, id)
+	}
+	sort.Slice(known, func(i, j int) bool { return known[i].id < known[j].id })
+	sort.Strings(deleted)
+	return known, deleted
+}
+
+// Restore restores the parents returned by Snapshot
+func (c *CascadeTracker) Restore(known []cascadeParent, deleted []string) {
+	for _, parent := range known {
+		c.known[parent.id] = parent.gvr
+	}
+	for _, id := range deleted {
+		c.deleted[id] = true
+	}
+}
+
+// deleteCascadedResource deletes an item whose parent no longer exists and creates an event reflecting the outcome
+//
+// Returns whether the item was deleted, or would have been if dry run is enabled
+func deleteCascadedResource(dynamicClient dynamic.Interface, eventManager *kevent.EventManager, namespaces *NamespaceCache, gvr schema.GroupVersionResource, item unstructured.Unstructured, parent string) bool {
+	waitForDeletionPause()
+	var attempted bool
+	defer throttleAfterDeletion(&attempted)
+	deletionMutex.Lock()
+	defer deletionMutex.Unlock()
+	logger.Info(fmt.Sprintf("[%s/%s] is a child of parent %s, which no longer exists", gvr.Resource, item.GetName(), parent))
+	if !isDeletionAllowed(namespaces, gvr, item) {
+		return false
+	}
+	deleted, err := NewDeleter(dynamicClient).Delete(gvr, item, fmt.Sprintf("parent %s deleted", parent))
+	if err == nil && !deleted {
+		// The deletion was only simulated (e.g. dry run)
+		return true
+	}
+	if apierrors.IsNotFound(err) {
+		// The item was already deleted, e.g. because its own TTL expired earlier in the same execution
+		return true
+	}
+	if err != nil {
+		logger.Info(fmt.Sprintf("[%s/%s] failed to delete: %s", gvr.Resource, item.GetName(), err))
+		recordFailedDeletion(item)
+		createDeletionEvent(eventManager, gvr, item, "FailedToDeleteCascaded", "Unable to delete resource whose parent was deleted:"+err.Error(), true, "", time.Time{})
+	} else {
+		logger.Info(fmt.Sprintf("[%s/%s] deleted (uid=%s, resourceVersion=%s)", gvr.Resource, item.GetName(), item.GetUID(), item.GetResourceVersion()))
+		createDeletionEvent(eventManager, gvr, item, "DeletedCascaded", fmt.Sprintf("Deleted resource because its parent %s no longer exists (uid=%s, resourceVersion=%s)", parent, item.GetUID(), item.GetResourceVersion()), false, "", time.Time{})
+		recordDeletion(gvr, item)
+		publishDeletion(gvr, item, "")
+	}
+	attempted = true
+	return err == nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DeletionCeiling prevents the number of resources deleted during an execution from exceeding a percentage of the number
+// of resources scanned, which scales the blast radius of a misconfiguration (e.g. a TTL annotation added to every
+// resource by mistake) with the size of the cluster.
+//
+// Since resources are deleted while they are being scanned, the number of resources scanned during the previous
+// execution is used as long as it is higher than the number of resources scanned so far. During the first execution,
+// this means that deletions are deferred whenever they get ahead of the scan.
+type DeletionCeiling struct {
+	ratio float64 // Maximum ratio of deleted to scanned resources per execution
+
+	scanned           int  // Number of resources scanned during the current execution
+	previouslyScanned int  // Number of resources scanned during the previous execution
+	reached           bool // Whether the ceiling has been reached during the current execution, which is only logged once per execution
+
+	mutex sync.Mutex
+}
+
+// NewDeletionCeiling creates a new DeletionCeiling
+func NewDeletionCeiling(ratio float64) *DeletionCeiling {
+	return &DeletionCeiling{ratio: ratio}
+}
+
+// NewExecution resets the number of resources scanned, keeping track of those of the execution that just ended
+func (c *DeletionCeiling) NewExecution() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.previouslyScanned = c.scanned
+	c.scanned = 0
+	c.reached = false
+}
+
+// Scanned records that a resource has been scanned
+func (c *DeletionCeiling) Scanned() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.scanned++
+}
+
+// Allow checks whether deletions are allowed, which is the case as long as the ceiling hasn't been reached.
+//
+// Must be called with the deletionMutex held.
+func (c *DeletionCeiling) Allow() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	scanned := max(c.scanned, c.previouslyScanned)
+	if float64(deletedResourcesInExecution) < c.ratio*float64(scanned) {
+		return true
+	}
+	if !c.reached {
+		c.reached = true
+		logger.Info(fmt.Sprintf("[DeletionCeiling] Deferring deletions, because %d resources have been deleted during this execution, which reaches the ceiling of %s of the %d resources scanned", deletedResourcesInExecution, c, scanned))
+	}
+	return false
+}
+
+// String returns the ceiling as a percentage
+func (c *DeletionCeiling) String() string {
+	return fmt.Sprintf("%.0f%%", c.ratio*100)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDeletionCeiling_Allow(t *testing.T) {
+	defer func(previous int) { deletedResourcesInExecution = previous }(deletedResourcesInExecution)
+	scenarios := []struct {
+		name              string
+		previouslyScanned int
+		scanned           int
+		deleted           int
+		expected          bool
+	}{
+		{name: "nothing-scanned", expected: false},
+		{name: "below-ceiling", scanned: 100, deleted: 9, expected: true},
+		{name: "at-ceiling", scanned: 100, deleted: 10, expected: false},
+		{name: "below-ceiling-of-previous-execution", previouslyScanned: 100, scanned: 10, deleted: 9, expected: true},
+		{name: "at-ceiling-of-previous-execution", previouslyScanned: 100, scanned: 10, deleted: 10, expected: false},
+		{name: "below-ceiling-of-current-execution", previouslyScanned: 10, scanned: 100, deleted: 9, expected: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			ceiling := NewDeletionCeiling(0.1)
+			for i := 0; i < scenario.previouslyScanned; i++ {
+				ceiling.Scanned()
+			}
+			ceiling.NewExecution()
+			for i := 0; i < scenario.scanned; i++ {
+				ceiling.Scanned()
+			}
+			deletedResourcesInExecution = scenario.deleted
+			if allowed := ceiling.Allow(); allowed != scenario.expected {
+				t.Errorf("expected %t, got %t", scenario.expected, allowed)
+			}
+		})
+	}
+}
+
+func TestReconcile_withMaxDeletionPercent(t *testing.T) {
+	defer func(previous *DeletionCeiling) { deletionCeiling = previous }(deletionCeiling)
+	deletionCeiling = NewDeletionCeiling(0.5)
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	for i := 0; i < 10; i++ {
+		pod := newUnstructuredWithAnnotations("v1", "Pod", "default", fmt.Sprintf("expired-pod-name-%d", i), time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// Deletions can't get ahead of the scan, so only half of the pods are deleted
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 5 {
+		t.Errorf("expected 5 pods to be left after the first execution, got %v", remaining)
+	}
+	// The 10 pods scanned during the previous execution allow 5 deletions from the start of the next execution
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 0 {
+		t.Errorf("expected no pods to be left after the second execution, got %v", remaining)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"text/template"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ExternalCheckTimeout is the maximum time to wait for the external check to respond
+const ExternalCheckTimeout = 10 * time.Second
+
+var ErrExternalCheckFailed = errors.New("external check did not pass")
+
+// ExternalCheckData is the data available to the URL template specified through ExternalCheckURLEnv.
+//
+// Every value is URL-encoded, so that it can be used anywhere in the URL.
+type ExternalCheckData struct {
+	Name      string
+	Namespace string
+	Kind      string
+	Group     string
+	Version   string
+	Resource  string
+	UID       string
+}
+
+// ExternalCheck asks an external system (e.g. an environment reservation system) whether a resource may be deleted by
+// sending a GET request to a URL templated with the resource's details
+type ExternalCheck struct {
+	url    *template.Template
+	client *http.Client
+}
+
+// NewExternalCheck creates a new ExternalCheck from a URL template such as
+// "https://example.com/environments/{{.Namespace}}/deletable"
+func NewExternalCheck(urlTemplate string) (*ExternalCheck, error) {
+	tmpl, err := template.New("external-check").Option("missingkey=error").Parse(urlTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalCheck{url: tmpl, client: &http.Client{Timeout: ExternalCheckTimeout}}, nil
+}
+
+// Check returns an error unless the external check responds to the GET request for an item with a 200 status code
+func (c *ExternalCheck) Check(gvr schema.GroupVersionResource, item unstructured.Unstructured) error {
+	var buffer bytes.Buffer
+	err := c.url.Execute(&buffer, ExternalCheckData{
+		Name:      url.QueryEscape(item.GetName()),
+		Namespace: url.QueryEscape(item.GetNamespace()),
+		Kind:      url.QueryEscape(item.GetKind()),
+		Group:     url.QueryEscape(gvr.Group),
+		Version:   url.QueryEscape(gvr.Version),
+		Resource:  url.QueryEscape(gvr.Resource),
+		UID:       url.QueryEscape(string(item.GetUID())),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrExternalCheckFailed, err)
+	}
+	response, err := c.client.Get(buffer.String())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrExternalCheckFailed, err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: responded with status code %d", ErrExternalCheckFailed, response.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExternalCheck_Check(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		statusCode    int
+		expectedError error
+	}{
+		{
+			name:          "ok",
+			statusCode:    http.StatusOK,
+			expectedError: nil,
+		},
+		{
+			name:          "no-content",
+			statusCode:    http.StatusNoContent,
+			expectedError: ErrExternalCheckFailed,
+		},
+		{
+			name:          "conflict",
+			statusCode:    http.StatusConflict,
+			expectedError: ErrExternalCheckFailed,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			var requestedPath string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requestedPath = r.URL.Path
+				w.WriteHeader(scenario.statusCode)
+			}))
+			defer server.Close()
+			check, err := NewExternalCheck(server.URL + "/{{.Kind}}/{{.Namespace}}/{{.Name}}")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			item := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now(), map[string]interface{}{})
+			if err := check.Check(podsGVR, *item); !errors.Is(err, scenario.expectedError) {
+				t.Errorf("expected error %v, got %v", scenario.expectedError, err)
+			}
+			if requestedPath != "/Pod/default/pod-name" {
+				t.Errorf("expected path /Pod/default/pod-name, got %s", requestedPath)
+			}
+		})
+	}
+}
+
+func TestNewExternalCheck_withInvalidTemplate(t *testing.T) {
+	if _, err := NewExternalCheck("https://example.com/{{.Name"); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -14,38 +15,58 @@ import (
 // CreateClients initializes a Kubernetes client and a dynamic client using either the kubeconfig file
 // (if ENVIRONMENT is set to dev) or the in-cluster config otherwise.
 func CreateClients() (kubernetes.Interface, dynamic.Interface, error) {
+	cfg, err := getConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	kubernetesClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return kubernetesClient, dynamicClient, nil
+}
+
+// CreateMetadataClient initializes a metadata client, which retrieves resources as PartialObjectMetadata, using the
+// same configuration as CreateClients
+func CreateMetadataClient() (metadata.Interface, error) {
+	cfg, err := getConfig()
+	if err != nil {
+		return nil, err
+	}
+	return metadata.NewForConfig(cfg)
+}
+
+// getConfig returns the configuration used to create clients, which is read from the kubeconfig file if ENVIRONMENT is
+// set to dev, or from the in-cluster config otherwise
+func getConfig() (*rest.Config, error) {
 	var cfg *rest.Config
 	if os.Getenv("ENVIRONMENT") == "dev" {
 		var kubeconfig string
 		if home := homeDir(); home != "" {
 			kubeconfig = filepath.Join(home, ".kube", "config")
 		} else {
-			return nil, nil, errors.New("home directory not found")
+			return nil, errors.New("home directory not found")
 		}
 		// use the current context in kubeconfig
 		clientConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		cfg = clientConfig
 	} else {
 		clientConfig, err := rest.InClusterConfig()
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		cfg = clientConfig
 	}
 	cfg.WarningHandler = rest.NoWarnings{}
 	cfg.UserAgent = "k8s-ttl-controller/1.0"
-	kubernetesClient, err := kubernetes.NewForConfig(cfg)
-	if err != nil {
-		return nil, nil, err
-	}
-	dynamicClient, err := dynamic.NewForConfig(cfg)
-	if err != nil {
-		return nil, nil, err
-	}
-	return kubernetesClient, dynamicClient, nil
+	return cfg, nil
 }
 
 func homeDir() string {
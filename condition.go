@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// DeleteCondition is a JSONPath expression that must evaluate to a truthy value for a resource to be deleted
+type DeleteCondition struct {
+	expression string
+	jsonPath   *jsonpath.JSONPath
+}
+
+// ParseDeleteCondition parses a JSONPath expression such as {.status.phase} into a DeleteCondition.
+//
+// The surrounding braces are optional.
+func ParseDeleteCondition(expression string) (*DeleteCondition, error) {
+	template := strings.TrimSpace(expression)
+	if !strings.HasPrefix(template, "{") {
+		template = "{" + template + "}"
+	}
+	jsonPath := jsonpath.New("delete-condition").AllowMissingKeys(true)
+	if err := jsonPath.Parse(template); err != nil {
+		return nil, fmt.Errorf("invalid JSONPath expression '%s': %w", expression, err)
+	}
+	return &DeleteCondition{expression: expression, jsonPath: jsonPath}, nil
+}
+
+// IsMet evaluates the condition against an item.
+//
+// The condition is met if the expression evaluates to anything other than an empty value or "false".
+func (c *DeleteCondition) IsMet(item unstructured.Unstructured) (bool, error) {
+	buffer := &bytes.Buffer{}
+	if err := c.jsonPath.Execute(buffer, item.Object); err != nil {
+		return false, err
+	}
+	result := strings.TrimSpace(buffer.String())
+	return result != "" && !strings.EqualFold(result, "false"), nil
+}
+
+// String returns the expression of the condition
+func (c *DeleteCondition) String() string {
+	return c.expression
+}
+
+// getMatchingConditionReason returns the reason of the first condition in the item's status whose reason is one of the
+// given reasons.
+//
+// Returns false if the item has no status conditions, or if none of them has one of the given reasons.
+func getMatchingConditionReason(item unstructured.Unstructured, reasons []string) (string, bool) {
+	conditions, found, err := unstructured.NestedSlice(item.Object, "status", "conditions")
+	if err != nil || !found {
+		return "", false
+	}
+	for _, condition := range conditions {
+		fields, ok := condition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		reason, _, _ := unstructured.NestedString(fields, "reason")
+		if reason != "" && contains(reasons, reason) {
+			return reason, true
+		}
+	}
+	return "", false
+}
+
+// ConditionDuration requires a status condition of a resource to have been in a given status for a minimum duration,
+// as reported by its lastTransitionTime, which prevents resources that only transiently entered a state from being
+// deleted
+type ConditionDuration struct {
+	conditionType string
+	status        string
+	minimum       time.Duration
+}
+
+// ParseConditionDuration parses a condition duration in the format Type[=Status]:duration, such as Failed:30m or
+// Ready=False:1h.
+//
+// The status defaults to True.
+func ParseConditionDuration(value string) (*ConditionDuration, error) {
+	condition, duration, found := strings.Cut(value, ":")
+	if !found {
+		return nil, fmt.Errorf("missing duration in '%s', expected Type[=Status]:duration", value)
+	}
+	conditionType, status, hasStatus := strings.Cut(condition, "=")
+	conditionType, status = strings.TrimSpace(conditionType), strings.TrimSpace(status)
+	if !hasStatus {
+		status = "True"
+	}
+	if conditionType == "" || status == "" {
+		return nil, fmt.Errorf("invalid condition '%s', expected Type[=Status]", condition)
+	}
+	minimum, err := parseTTL(strings.TrimSpace(duration))
+	if err != nil || minimum <= 0 {
+		return nil, fmt.Errorf("invalid duration '%s'", duration)
+	}
+	return &ConditionDuration{conditionType: conditionType, status: status, minimum: minimum}, nil
+}
+
+// Since returns for how long the condition of an item has been in the required status.
+//
+// Returns false if the item doesn't have the condition, if the condition isn't in the required status, or if its
+// lastTransitionTime is missing or invalid.
+func (c *ConditionDuration) Since(item unstructured.Unstructured) (time.Duration, bool) {
+	conditions, found, err := unstructured.NestedSlice(item.Object, "status", "conditions")
+	if err != nil || !found {
+		return 0, false
+	}
+	for _, condition := range conditions {
+		fields, ok := condition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if conditionType, _, _ := unstructured.NestedString(fields, "type"); conditionType != c.conditionType {
+			continue
+		}
+		if status, _, _ := unstructured.NestedString(fields, "status"); !strings.EqualFold(status, c.status) {
+			return 0, false
+		}
+		lastTransitionTime, _, _ := unstructured.NestedString(fields, "lastTransitionTime")
+		transitionedAt, err := time.Parse(time.RFC3339, lastTransitionTime)
+		if err != nil {
+			return 0, false
+		}
+		return time.Since(transitionedAt), true
+	}
+	return 0, false
+}
+
+// IsMet checks whether the condition of an item has been in the required status for at least the minimum duration
+func (c *ConditionDuration) IsMet(item unstructured.Unstructured) bool {
+	since, inStatus := c.Since(item)
+	return inStatus && since >= c.minimum
+}
+
+// Condition returns the type and required status of the condition, such as Failed=True
+func (c *ConditionDuration) Condition() string {
+	return c.conditionType + "=" + c.status
+}
+
+// String returns the condition duration in the format it was parsed from
+func (c *ConditionDuration) String() string {
+	return fmt.Sprintf("%s:%s", c.Condition(), c.minimum)
+}
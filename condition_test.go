@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDeleteCondition_IsMet(t *testing.T) {
+	job := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata":   map[string]interface{}{"name": "job-name", "namespace": "default"},
+		"spec":       map[string]interface{}{"suspend": false},
+		"status": map[string]interface{}{
+			"succeeded": int64(1),
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Complete", "status": "True"},
+			},
+		},
+	}}
+	scenarios := []struct {
+		name        string
+		expression  string
+		expectedMet bool
+	}{
+		{name: "field-set", expression: "{.status.succeeded}", expectedMet: true},
+		{name: "field-set-without-braces", expression: ".status.succeeded", expectedMet: true},
+		{name: "field-missing", expression: "{.status.failed}", expectedMet: false},
+		{name: "field-false", expression: "{.spec.suspend}", expectedMet: false},
+		{name: "filter-matching", expression: `{.status.conditions[?(@.type=="Complete")].status}`, expectedMet: true},
+		{name: "filter-not-matching", expression: `{.status.conditions[?(@.type=="Failed")].status}`, expectedMet: false},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			condition, err := ParseDeleteCondition(scenario.expression)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			met, err := condition.IsMet(job)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if met != scenario.expectedMet {
+				t.Errorf("expected condition %s to be met=%t, got %t", scenario.expression, scenario.expectedMet, met)
+			}
+		})
+	}
+	if _, err := ParseDeleteCondition("{.status[}"); err == nil {
+		t.Error("expected an error for an invalid expression")
+	}
+}
+
+func TestGetMatchingConditionReason(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		status         map[string]interface{}
+		reasons        []string
+		expectedReason string
+		expectedMatch  bool
+	}{
+		{
+			name: "matching-reason",
+			status: map[string]interface{}{"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "False", "reason": "Reconciling"},
+				map[string]interface{}{"type": "Stalled", "status": "True", "reason": "Failed"},
+			}},
+			reasons:        []string{"Failed", "Abandoned"},
+			expectedReason: "Failed",
+			expectedMatch:  true,
+		},
+		{
+			name: "no-matching-reason",
+			status: map[string]interface{}{"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "True", "reason": "Succeeded"},
+			}},
+			reasons:       []string{"Failed"},
+			expectedMatch: false,
+		},
+		{
+			name: "condition-without-reason",
+			status: map[string]interface{}{"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "True"},
+			}},
+			reasons:       []string{"Failed"},
+			expectedMatch: false,
+		},
+		{
+			name:          "no-conditions",
+			status:        map[string]interface{}{"phase": "Failed"},
+			reasons:       []string{"Failed"},
+			expectedMatch: false,
+		},
+		{
+			name:          "invalid-conditions",
+			status:        map[string]interface{}{"conditions": "Failed"},
+			reasons:       []string{"Failed"},
+			expectedMatch: false,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			item := unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "example.com/v1",
+				"kind":       "Workflow",
+				"metadata":   map[string]interface{}{"name": "workflow-name", "namespace": "default"},
+				"status":     scenario.status,
+			}}
+			reason, matched := getMatchingConditionReason(item, scenario.reasons)
+			if matched != scenario.expectedMatch || reason != scenario.expectedReason {
+				t.Errorf("expected (%s, %t), got (%s, %t)", scenario.expectedReason, scenario.expectedMatch, reason, matched)
+			}
+		})
+	}
+}
+
+func TestParseConditionDuration(t *testing.T) {
+	scenarios := []struct {
+		value         string
+		expected      string
+		expectedError bool
+	}{
+		{value: "Failed:30m", expected: "Failed=True:30m0s"},
+		{value: "Ready=False:1h", expected: "Ready=False:1h0m0s"},
+		{value: " Ready = Unknown : 1d", expected: "Ready=Unknown:24h0m0s"},
+		{value: "Failed", expectedError: true},
+		{value: ":30m", expectedError: true},
+		{value: "Ready=:30m", expectedError: true},
+		{value: "Failed:soon", expectedError: true},
+		{value: "Failed:0s", expectedError: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.value, func(t *testing.T) {
+			conditionDuration, err := ParseConditionDuration(scenario.value)
+			if scenario.expectedError != (err != nil) {
+				t.Fatalf("expected error to be %t, got %v", scenario.expectedError, err)
+			}
+			if err == nil && conditionDuration.String() != scenario.expected {
+				t.Errorf("expected %s, got %s", scenario.expected, conditionDuration)
+			}
+		})
+	}
+}
+
+func TestConditionDuration_IsMet(t *testing.T) {
+	conditionDuration, _ := ParseConditionDuration("Failed:30m")
+	scenarios := []struct {
+		name        string
+		conditions  []interface{}
+		expectedMet bool
+	}{
+		{
+			name:        "in-status-for-long-enough",
+			conditions:  []interface{}{map[string]interface{}{"type": "Failed", "status": "True", "lastTransitionTime": time.Now().Add(-time.Hour).Format(time.RFC3339)}},
+			expectedMet: true,
+		},
+		{
+			name:        "in-status-for-too-short",
+			conditions:  []interface{}{map[string]interface{}{"type": "Failed", "status": "True", "lastTransitionTime": time.Now().Add(-5 * time.Minute).Format(time.RFC3339)}},
+			expectedMet: false,
+		},
+		{
+			name:        "not-in-status",
+			conditions:  []interface{}{map[string]interface{}{"type": "Failed", "status": "False", "lastTransitionTime": time.Now().Add(-time.Hour).Format(time.RFC3339)}},
+			expectedMet: false,
+		},
+		{
+			name:        "without-last-transition-time",
+			conditions:  []interface{}{map[string]interface{}{"type": "Failed", "status": "True"}},
+			expectedMet: false,
+		},
+		{
+			name:        "without-condition",
+			conditions:  []interface{}{map[string]interface{}{"type": "Complete", "status": "True", "lastTransitionTime": time.Now().Add(-time.Hour).Format(time.RFC3339)}},
+			expectedMet: false,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			item := unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "batch/v1",
+				"kind":       "Job",
+				"metadata":   map[string]interface{}{"name": "job-name", "namespace": "default"},
+				"status":     map[string]interface{}{"conditions": scenario.conditions},
+			}}
+			if met := conditionDuration.IsMet(item); met != scenario.expectedMet {
+				t.Errorf("expected condition duration to be met=%t, got %t", scenario.expectedMet, met)
+			}
+		})
+	}
+}
+
+func TestReconcile_withDeleteConditionDuration(t *testing.T) {
+	defer func(previous map[string]*ConditionDuration) { deleteConditionDurations = previous }(deleteConditionDurations)
+	conditionDuration, _ := ParseConditionDuration("Failed:30m")
+	deleteConditionDurations = map[string]*ConditionDuration{"Deployment": conditionDuration}
+	kubernetesClient, dynamicClient, eventManager := newTestClients(deploymentsAPIResourceList)
+	failedAtByName := map[string]time.Time{
+		"failed-long-ago-deployment-name": time.Now().Add(-time.Hour),
+		"failed-recently-deployment-name": time.Now().Add(-time.Minute),
+	}
+	for name, failedAt := range failedAtByName {
+		deployment := newUnstructuredWithAnnotations("apps/v1", "Deployment", "default", name, time.Now().Add(-2*time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+		_ = unstructured.SetNestedSlice(deployment.Object, []interface{}{
+			map[string]interface{}{"type": "Failed", "status": "True", "lastTransitionTime": failedAt.Format(time.RFC3339)},
+		}, "status", "conditions")
+		if _, err := dynamicClient.Resource(deploymentsGVR).Namespace("default").Create(context.TODO(), deployment, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, deploymentsGVR, "default"); len(remaining) != 1 || !remaining["failed-recently-deployment-name"] {
+		t.Errorf("expected only the deployment that failed recently to be left, got %v", remaining)
+	}
+}
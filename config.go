@@ -0,0 +1,255 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xhit/go-str2duration/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// logEffectiveConfiguration logs the configuration that the controller is effectively running with, which allows
+// operators to confirm which settings were parsed from the environment without having to guess.
+func logEffectiveConfiguration() {
+	for _, line := range getEffectiveConfiguration() {
+		logger.Info(line)
+	}
+	logger.Info(fmt.Sprintf("[Configuration] Version: %s", getConfigurationVersion()))
+}
+
+// getEffectiveConfiguration returns the configuration that the controller is effectively running with, one setting
+// group per line
+func getEffectiveConfiguration() []string {
+	live := currentLiveConfiguration()
+	return []string{
+		fmt.Sprintf("[Configuration] Annotations: ttl=%s, refreshed-at=%s, last-used-at=%s, ttl-relative-to-owner=%s, keep-last=%s, deletion-window=%s, deletion-jitter=%s, lock=%s", strings.Join(ttlAnnotations, ","), AnnotationRefreshedAt, AnnotationLastUsedAt, AnnotationTTLRelativeToOwner, AnnotationKeepLast, AnnotationDeletionWindow, AnnotationDeletionJitter, AnnotationLock),
+		fmt.Sprintf("[Configuration] Execution: interval=%s, timeout=%s, throttle=%s, list-limit=%d, metadata-only-list=%t, list-checkpointing=%t, max-failed-executions=%d, max-transient-failed-executions=%d", ExecutionInterval, ExecutionTimeout, ThrottleDuration, ListLimit, metadataOnlyList, listCheckpoints != nil, MaximumFailedExecutionBeforePanic, MaximumTransientFailedExecutionBeforePanic),
+		fmt.Sprintf("[Configuration] Adaptive throttle: enabled=%t, minimum=%s, maximum=%s, latency-threshold=%s, jitter=%s", throttler.adaptive, throttler.minimum, throttler.maximum, throttler.latencyThreshold, throttler.jitter),
+		fmt.Sprintf("[Configuration] Deletion window: global=%s", formatDeletionWindow(deletionWindow)),
+		fmt.Sprintf("[Configuration] Allowlist: file=%s, entries=%d", os.Getenv(DeletionAllowlistFileEnv), len(allowlist)),
+		fmt.Sprintf("[Configuration] Delete conditions: %v, reasons=%v, durations=%v", deleteConditions, deleteConditionReasons, deleteConditionDurations),
+		fmt.Sprintf("[Configuration] Retention: max-ttl-by-kind=%v, max-count-per-owner=%v, max-deletions-per-namespace=%d, skip-terminating-namespaces=%t", maxTTLByKind, maxCountPerOwner, maxDeletionsPerNamespace, skipTerminatingNamespaces),
+		fmt.Sprintf("[Configuration] Batches: size=%d, pause=%s", deletionBatchSize, deletionBatchPause),
+		fmt.Sprintf("[Configuration] Namespace deletions: max-per-execution=%d, throttle=%s", maxNamespaceDeletionsPerExecution, namespaceDeletionThrottle),
+		fmt.Sprintf("[Configuration] Scheduling: max-scheduled-deletions-per-execution=%d", maxScheduledDeletionsPerExecution),
+		fmt.Sprintf("[Configuration] Resource intervals: %s", formatResourceIntervals(resourceIntervals)),
+		fmt.Sprintf("[Configuration] Mode: run-once=%t, fail-if-nothing-deleted=%t, watch=%t, low-memory=%t, dry-run=%t, observe=%t, observe-format=%s, paused=%t", runOnce, failIfNothingDeleted, watchMode, lowMemoryMode, live.DryRun, observeMode, observeFormat, live.Paused),
+		fmt.Sprintf("[Configuration] Startup: grace-period=%s, reconcile-align=%s", startupGracePeriod, reconcileAlign),
+		fmt.Sprintf("[Configuration] Clock skew: tolerance=%s", clockSkewTolerance),
+		fmt.Sprintf("[Configuration] Start time: strategy=%s, strict-refreshed-at=%t", startTimeStrategy, strictRefreshedAt),
+		fmt.Sprintf("[Configuration] Global TTL extension: %s", live.GlobalTTLExtension),
+		fmt.Sprintf("[Configuration] Max valid TTL: %s", str2duration.String(maxValidTTL)),
+		fmt.Sprintf("[Configuration] Propagation: default=%s, by-kind=%v, evict-pods=%t, delete-statefulset-pvcs=%t", formatPropagationPolicy(propagationPolicy), propagationPolicyByKind, evictPods, deleteStatefulSetPVCs),
+		fmt.Sprintf("[Configuration] Retries: budget-per-execution=%d, max-requeued-deletions=%d, skip-denied-deletion-retries=%t", retryBudgetPerExecution, maxRequeuedDeletions, skipDeniedDeletionRetries),
+		fmt.Sprintf("[Configuration] Circuit breaker: %s", formatCircuitBreaker(circuitBreaker)),
+		fmt.Sprintf("[Configuration] Deletion ceiling: %s", formatDeletionCeiling(deletionCeiling)),
+		fmt.Sprintf("[Configuration] Protected labels: %v", protectedLabels),
+		fmt.Sprintf("[Configuration] Label TTLs: %v", labelTTLs),
+		fmt.Sprintf("[Configuration] Protected kinds: %v", protectedKinds),
+		fmt.Sprintf("[Configuration] Excluded namespaces: %v", namespacesToExclude),
+		fmt.Sprintf("[Configuration] Owners: inherit-ttl-from-owner=%t, excluded-owner-kinds=%v, reap-orphans=%t, orphan-grace-period=%s, reap-cascade=%t", inheritTTLFromOwner, excludedOwnerKinds, reapOrphans, orphanGracePeriod, cascadeTracker != nil),
+		fmt.Sprintf("[Configuration] Unannotated purge: %v", purgePolicies),
+		fmt.Sprintf("[Configuration] Filters: %s=%s, %s=%s, %s=%s, preferred-version-only=%t", WatchNamespaceEnv, formatList(watchNamespaces), APIGroupsToWatchEnv, formatAPIGroups(live.APIGroupsToWatch), APIResourcesToWatchEnv, formatList(live.APIResourcesToWatch), preferredVersionOnly),
+		fmt.Sprintf("[Configuration] Metrics: enabled=%t, port=%d, strict=%t, report-unannotated-resources=%t", metricsEnabled, metricsPort, metricsStrict, reportUnannotatedResources),
+		fmt.Sprintf("[Configuration] State: persisted=%t, configmap=%s", stateConfigMap != "", stateConfigMap),
+		fmt.Sprintf("[Configuration] Live configuration: configmap=%s", configConfigMap),
+		fmt.Sprintf("[Configuration] Publishing: nats-url=%s, nats-subject=%s", redactURL(natsURL), natsSubject),
+		fmt.Sprintf("[Configuration] Archiving: webhook=%s, failure-blocks-deletion=%t", redactURL(os.Getenv(ArchiveWebhookURLEnv)), archiver == nil || archiver.failureBlocksDeletion),
+		fmt.Sprintf("[Configuration] External check: url=%s", redactURL(os.Getenv(ExternalCheckURLEnv))),
+		fmt.Sprintf("[Configuration] Events: custom-deletion-message-template=%t, pre-deletion-warning=%s, last-evaluated-interval=%s, lifecycle=%t, structured=%s", deletionMessageTemplate != nil, preDeletionWarning, lastEvaluatedInterval, lifecycleEvents, formatStructuredEvents()),
+		fmt.Sprintf("[Configuration] Logging: json=%t, level=%s, aggregate=%t", os.Getenv("JSON_LOG") == "true", programLevel.Level(), logAggregate),
+		fmt.Sprintf("[Configuration] Execution summary: destination=%s", os.Getenv(ExecutionSummaryEnv)),
+	}
+}
+
+// getConfigurationVersion returns a short hash of the effective configuration, which makes it easy to tell whether
+// two instances of the controller (e.g. before and after a restart) were running with the same configuration
+func getConfigurationVersion() string {
+	hash := sha256.Sum256([]byte(strings.Join(getEffectiveConfiguration(), "\n")))
+	return hex.EncodeToString(hash[:])[:12]
+}
+
+// formatList formats a list of values for the configuration logs, making it explicit when the list is empty
+func formatList(values []string) string {
+	if len(values) == 0 {
+		return "<all>"
+	}
+	return strings.Join(values, ",")
+}
+
+// formatAPIGroups formats a list of API groups for the configuration logs, referring to the core group as CoreAPIGroup
+func formatAPIGroups(groups []string) string {
+	formatted := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if group == "" {
+			group = CoreAPIGroup
+		}
+		formatted = append(formatted, group)
+	}
+	return formatList(formatted)
+}
+
+// formatPropagationPolicy formats a propagation policy for the configuration logs, making it explicit when there is none
+func formatPropagationPolicy(policy *metav1.DeletionPropagation) string {
+	if policy == nil {
+		return "<api-server-default>"
+	}
+	return string(*policy)
+}
+
+// formatCircuitBreaker formats a circuit breaker for the configuration logs, making it explicit when there is none
+func formatCircuitBreaker(breaker *CircuitBreaker) string {
+	if breaker == nil {
+		return "<disabled>"
+	}
+	return fmt.Sprintf("threshold=%.0f%%, minimum-attempts=%d", breaker.threshold*100, breaker.minimumAttempts)
+}
+
+// formatDeletionCeiling formats a deletion ceiling for the configuration logs, making it explicit when there is none
+func formatDeletionCeiling(ceiling *DeletionCeiling) string {
+	if ceiling == nil {
+		return "<disabled>"
+	}
+	return ceiling.String() + " of scanned resources"
+}
+
+// formatResourceIntervals formats the interval of each resource for the configuration logs, making it explicit when
+// every resource is reconciled on every execution
+func formatResourceIntervals(intervals *ResourceIntervals) string {
+	if intervals == nil {
+		return "<every execution>"
+	}
+	return intervals.String()
+}
+
+// formatStructuredEvents formats the level of structured events for the configuration logs, making it explicit when
+// they are disabled
+func formatStructuredEvents() string {
+	if structuredEvents == "" {
+		return "<disabled>"
+	}
+	return structuredEvents
+}
+
+// formatDeletionWindow formats a deletion window for the configuration logs, making it explicit when there is none
+func formatDeletionWindow(window *DeletionWindow) string {
+	if window == nil {
+		return "<always>"
+	}
+	return window.String() + " (UTC)"
+}
+
+// redactURL redacts the credentials that may be present in a URL, either as its userinfo or as the values of its query
+// parameters (e.g. ?token=...), so that it can safely be logged
+func redactURL(rawURL string) string {
+	if rawURL == "" {
+		return "<none>"
+	}
+	// A URL may contain multiple comma-separated servers (e.g. NATS_URL)
+	urls := strings.Split(rawURL, ",")
+	for i, u := range urls {
+		parsedURL, err := url.Parse(strings.TrimSpace(u))
+		if err != nil {
+			urls[i] = "<redacted>"
+			continue
+		}
+		if parsedURL.User != nil {
+			parsedURL.User = url.User("redacted")
+		}
+		if parsedURL.RawQuery != "" {
+			query := parsedURL.Query()
+			for key := range query {
+				query.Set(key, "redacted")
+			}
+			parsedURL.RawQuery = query.Encode()
+		}
+		urls[i] = parsedURL.String()
+	}
+	return strings.Join(urls, ",")
+}
+
+// getEnvAsDuration parses the environment variable with the given key as a duration, returning defaultValue if the
+// environment variable is not set.
+//
+// Panics if the value of the environment variable is not a valid duration.
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := str2duration.ParseDuration(value)
+	if err != nil {
+		panic(fmt.Sprintf("invalid duration '%s' for environment variable %s: %s", value, key, err))
+	}
+	return duration
+}
+
+// getEnvAsInt parses the environment variable with the given key as an integer, returning defaultValue if the
+// environment variable is not set.
+//
+// Panics if the value of the environment variable is not a valid integer.
+func getEnvAsInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		panic(fmt.Sprintf("invalid integer '%s' for environment variable %s: %s", value, key, err))
+	}
+	return number
+}
+
+// parseAPIGroups parses a comma-separated list of API groups such as "core,apps,batch", in which the core group, whose
+// actual name is the empty string, is referred to as CoreAPIGroup.
+//
+// For backward compatibility, an empty entry (e.g. ",apps") also refers to the core group. Returns nil if the value is
+// empty, which means that every group is allowed.
+func parseAPIGroups(value string) []string {
+	if value == "" {
+		return nil
+	}
+	var groups []string
+	for _, group := range strings.Split(value, ",") {
+		group = strings.TrimSpace(group)
+		if group == CoreAPIGroup {
+			group = ""
+		}
+		if !contains(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// getEnvAsMap parses the environment variable with the given key as a comma-separated list of key=value pairs, such
+// as "Deployment=Foreground,ConfigMap=Background".
+//
+// Panics if one of the pairs is not in the key=value format.
+func getEnvAsMap(key string) map[string]string {
+	return getEnvAsMapWithSeparator(key, ",")
+}
+
+// getEnvAsMapWithSeparator is like getEnvAsMap, but for values that may contain commas and must therefore be
+// separated by something else.
+func getEnvAsMapWithSeparator(key, separator string) map[string]string {
+	result := make(map[string]string)
+	value := os.Getenv(key)
+	if value == "" {
+		return result
+	}
+	for _, pair := range strings.Split(value, separator) {
+		k, v, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(k) == "" {
+			panic(fmt.Sprintf("invalid key=value pair '%s' for environment variable %s", pair, key))
+		}
+		result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// Deleter deletes resources on behalf of the controller, which allows the way resources are deleted (e.g. dry run,
+// archiving, eviction) to be chosen through the configuration rather than by every caller
+type Deleter interface {
+	// Delete deletes an item for the given reason, returning whether the item was actually deleted, which is not the
+	// case if the deletion was only simulated (e.g. dry run)
+	Delete(gvr schema.GroupVersionResource, item unstructured.Unstructured, reason string) (bool, error)
+}
+
+// NewDeleter creates the Deleter matching the configuration
+func NewDeleter(dynamicClient dynamic.Interface) Deleter {
+	if currentLiveConfiguration().DryRun {
+		return &DryRunDeleter{}
+	}
+	var deleter Deleter = &APIDeleter{dynamicClient: dynamicClient}
+	if evictPods {
+		deleter = &EvictionDeleter{dynamicClient: dynamicClient, deleter: deleter}
+	}
+	if archiver != nil {
+		deleter = &ArchivingDeleter{archiver: archiver, deleter: deleter}
+	}
+	return deleter
+}
+
+// APIDeleter deletes resources through the API server, retrying failed attempts for as long as the retry budget of the
+// current execution has not been exhausted
+type APIDeleter struct {
+	dynamicClient dynamic.Interface
+}
+
+// Delete deletes an item through the API server
+func (d *APIDeleter) Delete(gvr schema.GroupVersionResource, item unstructured.Unstructured, _ string) (bool, error) {
+	for {
+		start := time.Now()
+		err := d.dynamicClient.Resource(gvr).Namespace(item.GetNamespace()).Delete(context.TODO(), item.GetName(), metav1.DeleteOptions{PropagationPolicy: getPropagationPolicy(item.GetKind())})
+		throttler.Observe(time.Since(start))
+		if err == nil || apierrors.IsNotFound(err) || remainingRetryBudget <= 0 || !isRetryableDeletionError(err) {
+			return err == nil, err
+		}
+		remainingRetryBudget--
+		logger.Info(fmt.Sprintf("[%s/%s] failed to delete, retrying (%d retries left in budget): %s", gvr.Resource, item.GetName(), remainingRetryBudget, err))
+		throttler.Sleep()
+	}
+}
+
+// DryRunDeleter only records the resources that would have been deleted
+type DryRunDeleter struct{}
+
+// Delete records an item as a deletion candidate without deleting it
+func (d *DryRunDeleter) Delete(gvr schema.GroupVersionResource, item unstructured.Unstructured, reason string) (bool, error) {
+	recordDeletionCandidate(gvr, item, reason)
+	return false, nil
+}
+
+// EvictionDeleter evicts Pods rather than deleting them, so that PodDisruptionBudgets are respected, and delegates the
+// deletion of every other resource to another Deleter
+type EvictionDeleter struct {
+	dynamicClient dynamic.Interface
+	deleter       Deleter
+}
+
+// Delete evicts an item if it is a Pod, and deletes it through the underlying Deleter otherwise
+func (d *EvictionDeleter) Delete(gvr schema.GroupVersionResource, item unstructured.Unstructured, reason string) (bool, error) {
+	if gvr.Group != "" || gvr.Resource != "pods" {
+		return d.deleter.Delete(gvr, item, reason)
+	}
+	eviction := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "policy/v1",
+		"kind":       "Eviction",
+		"metadata":   map[string]interface{}{"name": item.GetName(), "namespace": item.GetNamespace()},
+	}}
+	start := time.Now()
+	_, err := d.dynamicClient.Resource(gvr).Namespace(item.GetNamespace()).Create(context.TODO(), eviction, metav1.CreateOptions{}, "eviction")
+	throttler.Observe(time.Since(start))
+	return err == nil, err
+}
+
+// ArchivingDeleter archives resources before deleting them through another Deleter
+type ArchivingDeleter struct {
+	archiver *WebhookArchiver
+	deleter  Deleter
+}
+
+// Delete archives an item, then deletes it through the underlying Deleter.
+//
+// If the item fails to be archived, it is only deleted if the archiver's failures don't block deletions.
+func (d *ArchivingDeleter) Delete(gvr schema.GroupVersionResource, item unstructured.Unstructured, reason string) (bool, error) {
+	if err := d.archiver.Archive(item); err != nil {
+		if d.archiver.failureBlocksDeletion {
+			return false, err
+		}
+		logger.Info(fmt.Sprintf("[%s/%s] failed to archive, deleting anyway: %s", gvr.Resource, item.GetName(), err))
+	} else {
+		logger.Debug(fmt.Sprintf("[%s/%s] archived before deletion", gvr.Resource, item.GetName()))
+	}
+	return d.deleter.Delete(gvr, item, reason)
+}
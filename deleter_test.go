@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestAPIDeleter_Delete(t *testing.T) {
+	_, dynamicClient, _ := newTestClients(podsAPIResourceList)
+	pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now(), map[string]interface{}{})
+	if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deleter := &APIDeleter{dynamicClient: dynamicClient}
+	if deleted, err := deleter.Delete(podsGVR, *pod, "test"); err != nil || !deleted {
+		t.Errorf("expected the pod to have been deleted, got deleted=%t and error %v", deleted, err)
+	}
+	if deleted, err := deleter.Delete(podsGVR, *pod, "test"); !apierrors.IsNotFound(err) || deleted {
+		t.Errorf("expected a not found error, got deleted=%t and error %v", deleted, err)
+	}
+}
+
+func TestAPIDeleter_Delete_withAdmissionDenial(t *testing.T) {
+	defer func(previous bool) { skipDeniedDeletionRetries = previous }(skipDeniedDeletionRetries)
+	defer func(previous int) { remainingRetryBudget = previous }(remainingRetryBudget)
+	scenarios := []struct {
+		name                      string
+		skipDeniedDeletionRetries bool
+		expectedAttempts          int
+	}{
+		{name: "retried", skipDeniedDeletionRetries: false, expectedAttempts: 3},
+		{name: "not-retried", skipDeniedDeletionRetries: true, expectedAttempts: 1},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			skipDeniedDeletionRetries, remainingRetryBudget = scenario.skipDeniedDeletionRetries, 2
+			_, dynamicClient, _ := newTestClients(podsAPIResourceList)
+			attempts := 0
+			dynamicClient.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				attempts++
+				return true, nil, apierrors.NewForbidden(podsGVR.GroupResource(), "pod-name", errors.New(`admission webhook "deny.example.com" denied the request: pods may not be deleted`))
+			})
+			pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now(), map[string]interface{}{})
+			if _, err := (&APIDeleter{dynamicClient: dynamicClient}).Delete(podsGVR, *pod, "test"); !apierrors.IsForbidden(err) {
+				t.Errorf("expected a forbidden error, got %v", err)
+			}
+			if attempts != scenario.expectedAttempts {
+				t.Errorf("expected %d attempts, got %d", scenario.expectedAttempts, attempts)
+			}
+		})
+	}
+}
+
+func TestDryRunDeleter_Delete(t *testing.T) {
+	defer func() { deletionCandidates = nil }()
+	deletionCandidates = nil
+	pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now(), map[string]interface{}{})
+	if deleted, err := (&DryRunDeleter{}).Delete(podsGVR, *pod, "test"); err != nil || deleted {
+		t.Errorf("expected the deletion to only have been simulated, got deleted=%t and error %v", deleted, err)
+	}
+	if len(deletionCandidates) != 1 || deletionCandidates[0].reason != "test" {
+		t.Errorf("expected the pod to have been recorded as a deletion candidate, got %v", deletionCandidates)
+	}
+}
+
+func TestEvictionDeleter_Delete(t *testing.T) {
+	_, dynamicClient, _ := newTestClients(podsAPIResourceList, deploymentsAPIResourceList)
+	var evicted []string
+	dynamicClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		evicted = append(evicted, action.GetNamespace())
+		return true, nil, nil
+	})
+	deployment := newUnstructuredWithAnnotations("apps/v1", "Deployment", "default", "deployment-name", time.Now(), map[string]interface{}{})
+	if _, err := dynamicClient.Resource(deploymentsGVR).Namespace("default").Create(context.TODO(), deployment, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deleter := &EvictionDeleter{dynamicClient: dynamicClient, deleter: &APIDeleter{dynamicClient: dynamicClient}}
+	pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now(), map[string]interface{}{})
+	if deleted, err := deleter.Delete(podsGVR, *pod, "test"); err != nil || !deleted {
+		t.Errorf("expected the pod to have been evicted, got deleted=%t and error %v", deleted, err)
+	}
+	if len(evicted) != 1 {
+		t.Errorf("expected the pod to have been evicted once, got %d evictions", len(evicted))
+	}
+	// Other resources can't be evicted, so they are deleted as usual
+	if deleted, err := deleter.Delete(deploymentsGVR, *deployment, "test"); err != nil || !deleted {
+		t.Errorf("expected the deployment to have been deleted, got deleted=%t and error %v", deleted, err)
+	}
+	if len(evicted) != 1 {
+		t.Errorf("expected only the pod to have been evicted, got %d evictions", len(evicted))
+	}
+}
+
+func TestArchivingDeleter_Delete(t *testing.T) {
+	scenarios := []struct {
+		name                  string
+		statusCode            int
+		failureBlocksDeletion bool
+		expectedDeleted       bool
+	}{
+		{
+			name:                  "archived",
+			statusCode:            http.StatusOK,
+			failureBlocksDeletion: true,
+			expectedDeleted:       true,
+		},
+		{
+			name:                  "failed-to-archive-and-blocking",
+			statusCode:            http.StatusInternalServerError,
+			failureBlocksDeletion: true,
+			expectedDeleted:       false,
+		},
+		{
+			name:                  "failed-to-archive-but-not-blocking",
+			statusCode:            http.StatusInternalServerError,
+			failureBlocksDeletion: false,
+			expectedDeleted:       true,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(scenario.statusCode)
+			}))
+			defer server.Close()
+			_, dynamicClient, _ := newTestClients(podsAPIResourceList)
+			pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now(), map[string]interface{}{})
+			if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			deleter := &ArchivingDeleter{archiver: NewWebhookArchiver(server.URL, scenario.failureBlocksDeletion), deleter: &APIDeleter{dynamicClient: dynamicClient}}
+			deleted, err := deleter.Delete(podsGVR, *pod, "test")
+			if deleted != scenario.expectedDeleted {
+				t.Errorf("expected deleted to be %t, got %t", scenario.expectedDeleted, deleted)
+			}
+			if !scenario.expectedDeleted && !errors.Is(err, ErrArchiveFailed) {
+				t.Errorf("expected ErrArchiveFailed, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// DeletionMessageData is the data available to the template specified through DeletionMessageTemplateEnv
+type DeletionMessageData struct {
+	Name            string
+	Namespace       string
+	Kind            string
+	Resource        string
+	UID             string
+	ResourceVersion string
+	TTL             string
+	ExpiredSince    string
+}
+
+// parseDeletionMessageTemplate parses the template used to generate the message of the event created when a resource
+// is deleted.
+//
+// Returns nil if the template is empty or invalid, in which case the default message is used.
+func parseDeletionMessageTemplate(text string) *template.Template {
+	if text == "" {
+		return nil
+	}
+	tmpl, err := template.New("deletion-message").Option("missingkey=error").Parse(text)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Invalid %s, falling back to the default deletion message: %s", DeletionMessageTemplateEnv, err))
+		return nil
+	}
+	return tmpl
+}
+
+// getDeletionMessage returns the message describing the deletion of an expired item, rendered using the
+// deletion message template if one is configured
+func getDeletionMessage(resource string, item unstructured.Unstructured, ttl string, expiresAt time.Time) string {
+	defaultMessage := fmt.Sprintf("Deleted resource because %s or more has elapsed (uid=%s, resourceVersion=%s)", ttl, item.GetUID(), item.GetResourceVersion())
+	if deletionMessageTemplate == nil {
+		return defaultMessage
+	}
+	var buffer bytes.Buffer
+	err := deletionMessageTemplate.Execute(&buffer, DeletionMessageData{
+		Name:            item.GetName(),
+		Namespace:       item.GetNamespace(),
+		Kind:            item.GetKind(),
+		Resource:        resource,
+		UID:             string(item.GetUID()),
+		ResourceVersion: item.GetResourceVersion(),
+		TTL:             ttl,
+		ExpiredSince:    time.Since(expiresAt).Round(time.Second).String(),
+	})
+	if err != nil {
+		logger.Info(fmt.Sprintf("[%s/%s] failed to render deletion message template, falling back to the default message: %s", resource, item.GetName(), err))
+		return defaultMessage
+	}
+	return buffer.String()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetDeletionMessage(t *testing.T) {
+	defer func() { deletionMessageTemplate = nil }()
+	item := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	item.SetUID("a1b2c3")
+	item.SetResourceVersion("42")
+	scenarios := []struct {
+		name            string
+		template        string
+		expectedMessage string
+	}{
+		{
+			name:            "no-template",
+			template:        "",
+			expectedMessage: "Deleted resource because 5m or more has elapsed (uid=a1b2c3, resourceVersion=42)",
+		},
+		{
+			name:            "template",
+			template:        "Deleted {{.Kind}} {{.Namespace}}/{{.Name}} as per POLICY-123 (ttl={{.TTL}}, expired {{.ExpiredSince}} ago)",
+			expectedMessage: "Deleted Pod default/pod-name as per POLICY-123 (ttl=5m, expired 1m0s ago)",
+		},
+		{
+			name:            "invalid-template",
+			template:        "Deleted {{.Name",
+			expectedMessage: "Deleted resource because 5m or more has elapsed (uid=a1b2c3, resourceVersion=42)",
+		},
+		{
+			name:            "template-with-unknown-field",
+			template:        "Deleted {{.Unknown}}",
+			expectedMessage: "Deleted resource because 5m or more has elapsed (uid=a1b2c3, resourceVersion=42)",
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			deletionMessageTemplate = parseDeletionMessageTemplate(scenario.template)
+			if message := getDeletionMessage("pods", *item, "5m", time.Now().Add(-time.Minute)); message != scenario.expectedMessage {
+				t.Errorf("expected %q, got %q", scenario.expectedMessage, message)
+			}
+		})
+	}
+}
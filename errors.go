@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	ErrTimedOut        = errors.New("execution timed out")
+	ErrDiscoveryFailed = errors.New("failed to discover API resources")
+	ErrListFailed      = errors.New("failed to list resources")
+)
+
+// ReconcileError is implemented by every error returned by Reconcile, which allows distinguishing problems that are
+// likely to resolve themselves by the next execution from those that are not
+type ReconcileError interface {
+	error
+	// Transient returns whether the problem is likely to resolve itself by the next execution
+	Transient() bool
+	// Reason returns a short, stable description of the problem, such as "discovery", which is used as metric label
+	Reason() string
+}
+
+// DiscoveryError is returned when the API resources could not be discovered, which is usually caused by a blip in the
+// availability of the API server or of an aggregated API
+type DiscoveryError struct {
+	Err error
+}
+
+func (e *DiscoveryError) Error() string   { return fmt.Sprintf("%s: %s", ErrDiscoveryFailed, e.Err) }
+func (e *DiscoveryError) Unwrap() []error { return []error{ErrDiscoveryFailed, e.Err} }
+func (e *DiscoveryError) Transient() bool { return true }
+func (e *DiscoveryError) Reason() string  { return "discovery" }
+
+// TimeoutError is returned when an execution lasts for longer than its timeout, which usually means that the cluster
+// has too many resources for the controller's configuration
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string   { return fmt.Sprintf("%s after %s", ErrTimedOut, e.Timeout) }
+func (e *TimeoutError) Unwrap() error   { return ErrTimedOut }
+func (e *TimeoutError) Transient() bool { return false }
+func (e *TimeoutError) Reason() string  { return "timeout" }
+
+// PartialListError is returned when an execution completed, but some resources could not be listed
+type PartialListError struct {
+	Failures map[schema.GroupVersionResource]error
+}
+
+func (e *PartialListError) Error() string {
+	failures := make([]string, 0, len(e.Failures))
+	for gvr, err := range e.Failures {
+		failures = append(failures, fmt.Sprintf("%s: %s", gvr.GroupResource(), err))
+	}
+	sort.Strings(failures)
+	return fmt.Sprintf("%s (%d): %s", ErrListFailed, len(e.Failures), strings.Join(failures, "; "))
+}
+func (e *PartialListError) Unwrap() error   { return ErrListFailed }
+func (e *PartialListError) Transient() bool { return true }
+func (e *PartialListError) Reason() string  { return "partial_list" }
+
+// isTransientError checks whether an error is a ReconcileError that is likely to resolve itself by the next execution
+func isTransientError(err error) bool {
+	var reconcileError ReconcileError
+	return errors.As(err, &reconcileError) && reconcileError.Transient()
+}
+
+// getErrorReason returns the reason of a ReconcileError, or "unknown" if the error is not a ReconcileError
+func getErrorReason(err error) string {
+	var reconcileError ReconcileError
+	if errors.As(err, &reconcileError) {
+		return reconcileError.Reason()
+	}
+	return "unknown"
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestReconcileError(t *testing.T) {
+	scenarios := []struct {
+		name              string
+		err               error
+		expectedSentinel  error
+		expectedTransient bool
+		expectedReason    string
+	}{
+		{name: "discovery", err: &DiscoveryError{Err: errors.New("unreachable")}, expectedSentinel: ErrDiscoveryFailed, expectedTransient: true, expectedReason: "discovery"},
+		{name: "timeout", err: &TimeoutError{Timeout: ExecutionTimeout}, expectedSentinel: ErrTimedOut, expectedTransient: false, expectedReason: "timeout"},
+		{name: "partial-list", err: &PartialListError{Failures: map[schema.GroupVersionResource]error{podsGVR: errors.New("unavailable")}}, expectedSentinel: ErrListFailed, expectedTransient: true, expectedReason: "partial_list"},
+		{name: "wrapped", err: fmt.Errorf("wrapped: %w", &DiscoveryError{Err: errors.New("unreachable")}), expectedSentinel: ErrDiscoveryFailed, expectedTransient: true, expectedReason: "discovery"},
+		{name: "unknown", err: errors.New("unknown"), expectedTransient: false, expectedReason: "unknown"},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			if scenario.expectedSentinel != nil && !errors.Is(scenario.err, scenario.expectedSentinel) {
+				t.Errorf("expected %v to wrap %v", scenario.err, scenario.expectedSentinel)
+			}
+			if transient := isTransientError(scenario.err); transient != scenario.expectedTransient {
+				t.Errorf("expected transient=%t, got %t", scenario.expectedTransient, transient)
+			}
+			if reason := getErrorReason(scenario.err); reason != scenario.expectedReason {
+				t.Errorf("expected reason %s, got %s", scenario.expectedReason, reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// EvaluationFieldManager is the field manager used by the controller when writing AnnotationLastEvaluatedAt, which
+// makes it clear who owns the annotation without conflicting with the other managers of the resource
+const EvaluationFieldManager = "k8s-ttl-controller-evaluation"
+
+// markEvaluated stamps an item with AnnotationLastEvaluatedAt, which lets operators confirm that the controller is
+// actively considering it.
+//
+// The annotation is written through server-side apply as EvaluationFieldManager, so that only the annotation is owned
+// by the controller. To avoid churning through resource versions, the annotation is only updated once it is older than
+// lastEvaluatedInterval. Does nothing if lastEvaluatedInterval is 0 or if the controller is in dry run mode.
+func markEvaluated(ctx context.Context, dynamicClient dynamic.Interface, gvr schema.GroupVersionResource, item unstructured.Unstructured) {
+	if lastEvaluatedInterval <= 0 || currentLiveConfiguration().DryRun {
+		return
+	}
+	now := time.Now()
+	if lastEvaluatedAt, err := time.Parse(time.RFC3339, item.GetAnnotations()[AnnotationLastEvaluatedAt]); err == nil && now.Sub(lastEvaluatedAt) < lastEvaluatedInterval {
+		return
+	}
+	metadata := map[string]interface{}{
+		"name":        item.GetName(),
+		"annotations": map[string]string{AnnotationLastEvaluatedAt: now.UTC().Format(time.RFC3339)},
+	}
+	if item.GetNamespace() != "" {
+		metadata["namespace"] = item.GetNamespace()
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"apiVersion": item.GetAPIVersion(),
+		"kind":       item.GetKind(),
+		"metadata":   metadata,
+	})
+	if err != nil {
+		return
+	}
+	force := true
+	_, err = dynamicClient.Resource(gvr).Namespace(item.GetNamespace()).Patch(ctx, item.GetName(), types.ApplyPatchType, patch, metav1.PatchOptions{FieldManager: EvaluationFieldManager, Force: &force})
+	if err != nil {
+		logger.Debug(fmt.Sprintf("[%s/%s] failed to update %s annotation: %s", gvr.Resource, item.GetName(), AnnotationLastEvaluatedAt, err))
+	}
+}
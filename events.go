@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TwiN/kevent"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	typedv1core "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	StructuredEventsBasic = "basic" // Events are annotated with the UID and GVR of the resource
+	StructuredEventsFull  = "full"  // Events are also annotated with the TTL, expiry and resource version of the resource, and with the actor
+
+	EventAnnotationUID             = "k8s-ttl-controller.twin.sh/uid"
+	EventAnnotationGroup           = "k8s-ttl-controller.twin.sh/group"
+	EventAnnotationVersion         = "k8s-ttl-controller.twin.sh/version"
+	EventAnnotationResource        = "k8s-ttl-controller.twin.sh/resource"
+	EventAnnotationTTL             = "k8s-ttl-controller.twin.sh/ttl"
+	EventAnnotationExpiresAt       = "k8s-ttl-controller.twin.sh/expires-at"
+	EventAnnotationResourceVersion = "k8s-ttl-controller.twin.sh/resource-version"
+	EventAnnotationActor           = "k8s-ttl-controller.twin.sh/actor"
+	EventAnnotationDryRun          = "k8s-ttl-controller.twin.sh/dry-run"
+)
+
+// StructuredEventRecorder records events annotated with structured information about the resource they are about, so
+// that tools ingesting events (e.g. a SIEM) can index them without having to parse their message.
+//
+// This is needed because kevent.EventManager doesn't support annotating events.
+type StructuredEventRecorder struct {
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
+}
+
+// NewStructuredEventRecorder creates a new StructuredEventRecorder
+func NewStructuredEventRecorder(kubernetesClient kubernetes.Interface) *StructuredEventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedv1core.EventSinkImpl{Interface: kubernetesClient.CoreV1().Events("")})
+	return &StructuredEventRecorder{
+		broadcaster: broadcaster,
+		recorder:    broadcaster.NewRecorder(runtime.NewScheme(), corev1.EventSource{Component: "k8s-ttl-controller"}),
+	}
+}
+
+// Create records an event about an item with the given annotations
+func (r *StructuredEventRecorder) Create(item unstructured.Unstructured, reason, message string, isWarning bool, annotations map[string]string) {
+	eventType := corev1.EventTypeNormal
+	if isWarning {
+		eventType = corev1.EventTypeWarning
+	}
+	involvedObject := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": item.GetAPIVersion(),
+		"kind":       item.GetKind(),
+		"metadata": map[string]interface{}{
+			"namespace": item.GetNamespace(),
+			"name":      item.GetName(),
+			"uid":       string(item.GetUID()),
+		},
+	}}
+	r.recorder.AnnotatedEventf(involvedObject, annotations, eventType, reason, "%s", message)
+}
+
+// getEventAnnotations returns the annotations of an event about the deletion of an item, based on structuredEvents.
+//
+// ttl and expiresAt may be empty if the deletion isn't due to a TTL (e.g. orphaned resources).
+func getEventAnnotations(gvr schema.GroupVersionResource, item unstructured.Unstructured, ttl string, expiresAt time.Time) map[string]string {
+	annotations := map[string]string{
+		EventAnnotationUID:      string(item.GetUID()),
+		EventAnnotationGroup:    gvr.Group,
+		EventAnnotationVersion:  gvr.Version,
+		EventAnnotationResource: gvr.Resource,
+	}
+	if structuredEvents != StructuredEventsFull {
+		return annotations
+	}
+	if ttl != "" {
+		annotations[EventAnnotationTTL] = ttl
+	}
+	if !expiresAt.IsZero() {
+		annotations[EventAnnotationExpiresAt] = expiresAt.UTC().Format(time.RFC3339)
+	}
+	annotations[EventAnnotationResourceVersion] = item.GetResourceVersion()
+	annotations[EventAnnotationActor] = getActor()
+	annotations[EventAnnotationDryRun] = fmt.Sprintf("%t", currentLiveConfiguration().DryRun)
+	return annotations
+}
+
+// getActor returns the identity of the controller that made a decision, which includes the Pod it is running in if it
+// can be determined
+func getActor() string {
+	if namespace, name, ok := getControllerPod(); ok {
+		return fmt.Sprintf("k8s-ttl-controller/%s/%s", namespace, name)
+	}
+	return "k8s-ttl-controller"
+}
+
+// createDeletionEvent creates an event about the deletion of an item, which is annotated with structured information
+// about the item if structuredEvents is set.
+//
+// ttl and expiresAt may be empty if the deletion isn't due to a TTL (e.g. orphaned resources).
+func createDeletionEvent(eventManager *kevent.EventManager, gvr schema.GroupVersionResource, item unstructured.Unstructured, reason, message string, isWarning bool, ttl string, expiresAt time.Time) {
+	if structuredEventRecorder == nil {
+		eventManager.Create(item.GetNamespace(), item.GetKind(), item.GetName(), reason, message, isWarning)
+		return
+	}
+	structuredEventRecorder.Create(item, reason, message, isWarning, getEventAnnotations(gvr, item, ttl, expiresAt))
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TwiN/kevent"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetEventAnnotations(t *testing.T) {
+	defer func(previous string) { structuredEvents = previous }(structuredEvents)
+	t.Setenv(PodNamespaceEnv, "kube-system")
+	t.Setenv(PodNameEnv, "k8s-ttl-controller-abc12")
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	item := unstructured.Unstructured{}
+	item.SetUID(types.UID("a1b2c3"))
+	item.SetResourceVersion("42")
+	expiresAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	scenarios := []struct {
+		name                string
+		level               string
+		ttl                 string
+		expiresAt           time.Time
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:      "basic",
+			level:     StructuredEventsBasic,
+			ttl:       "1h",
+			expiresAt: expiresAt,
+			expectedAnnotations: map[string]string{
+				EventAnnotationUID:      "a1b2c3",
+				EventAnnotationGroup:    "apps",
+				EventAnnotationVersion:  "v1",
+				EventAnnotationResource: "deployments",
+			},
+		},
+		{
+			name:      "full",
+			level:     StructuredEventsFull,
+			ttl:       "1h",
+			expiresAt: expiresAt,
+			expectedAnnotations: map[string]string{
+				EventAnnotationUID:             "a1b2c3",
+				EventAnnotationGroup:           "apps",
+				EventAnnotationVersion:         "v1",
+				EventAnnotationResource:        "deployments",
+				EventAnnotationTTL:             "1h",
+				EventAnnotationExpiresAt:       "2026-01-02T03:04:05Z",
+				EventAnnotationResourceVersion: "42",
+				EventAnnotationActor:           "k8s-ttl-controller/kube-system/k8s-ttl-controller-abc12",
+				EventAnnotationDryRun:          "false",
+			},
+		},
+		{
+			name:  "full-without-ttl",
+			level: StructuredEventsFull,
+			expectedAnnotations: map[string]string{
+				EventAnnotationUID:             "a1b2c3",
+				EventAnnotationGroup:           "apps",
+				EventAnnotationVersion:         "v1",
+				EventAnnotationResource:        "deployments",
+				EventAnnotationResourceVersion: "42",
+				EventAnnotationActor:           "k8s-ttl-controller/kube-system/k8s-ttl-controller-abc12",
+				EventAnnotationDryRun:          "false",
+			},
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			structuredEvents = scenario.level
+			annotations := getEventAnnotations(gvr, item, scenario.ttl, scenario.expiresAt)
+			if len(annotations) != len(scenario.expectedAnnotations) {
+				t.Errorf("expected %d annotations, got %d: %v", len(scenario.expectedAnnotations), len(annotations), annotations)
+			}
+			for key, expectedValue := range scenario.expectedAnnotations {
+				if annotations[key] != expectedValue {
+					t.Errorf("expected annotation %s to be '%s', got '%s'", key, expectedValue, annotations[key])
+				}
+			}
+		})
+	}
+}
+
+func TestCreateDeletionEvent_withStructuredEvents(t *testing.T) {
+	defer func(previous string, previousRecorder *StructuredEventRecorder) {
+		structuredEvents, structuredEventRecorder = previous, previousRecorder
+	}(structuredEvents, structuredEventRecorder)
+	kubernetesClient := fakekubernetes.NewSimpleClientset()
+	structuredEvents = StructuredEventsBasic
+	structuredEventRecorder = NewStructuredEventRecorder(kubernetesClient)
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	item := unstructured.Unstructured{}
+	item.SetAPIVersion("v1")
+	item.SetKind("Pod")
+	item.SetNamespace("default")
+	item.SetName("pod-1")
+	item.SetUID(types.UID("a1b2c3"))
+	createDeletionEvent(kevent.NewEventManager(kubernetesClient, "k8s-ttl-controller"), gvr, item, "DeletedExpiredTTL", "Deleted resource because it is configured with a TTL of 1h", false, "1h", time.Now())
+	// Events are sent asynchronously
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		events, err := kubernetesClient.CoreV1().Events("default").List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(events.Items) > 0 {
+			event := events.Items[0]
+			if event.Reason != "DeletedExpiredTTL" || event.Type != corev1.EventTypeNormal || event.InvolvedObject.Kind != "Pod" || event.InvolvedObject.Name != "pod-1" || event.InvolvedObject.UID != "a1b2c3" {
+				t.Errorf("expected a DeletedExpiredTTL event on Pod pod-1, got %s %s event on %s %s (uid=%s)", event.Type, event.Reason, event.InvolvedObject.Kind, event.InvolvedObject.Name, event.InvolvedObject.UID)
+			}
+			if event.Annotations[EventAnnotationUID] != "a1b2c3" || event.Annotations[EventAnnotationResource] != "pods" {
+				t.Errorf("expected event to be annotated with the uid and resource of the pod, got %v", event.Annotations)
+			}
+			if _, exists := event.Annotations[EventAnnotationTTL]; exists {
+				t.Error("expected event not to be annotated with the TTL at the basic level")
+			}
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Error("expected a deletion event to have been created")
+}
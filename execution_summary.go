@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExecutionSummaryTopResources is the number of resources with the most deletions included in each execution summary
+const ExecutionSummaryTopResources = 10
+
+// ExecutionSummary is a machine-readable summary of an execution, written as a single line of JSON to the destination
+// configured through ExecutionSummaryEnv, separately from the logs
+type ExecutionSummary struct {
+	StartedAt       time.Time           `json:"startedAt"`
+	DurationMs      int64               `json:"durationMs"`
+	Scanned         int                 `json:"scanned"`
+	Deleted         int                 `json:"deleted"`
+	FailedDeletions int                 `json:"failedDeletions"`
+	ListFailures    int                 `json:"listFailures"`
+	DryRun          bool                `json:"dryRun"`
+	TopResources    []ResourceDeletions `json:"topResources"`
+	Error           string              `json:"error,omitempty"`
+}
+
+// ResourceDeletions is the number of items of a resource deleted during an execution
+type ResourceDeletions struct {
+	Resource string `json:"resource"` // Resource in the resource.group format, e.g. deployments.apps
+	Deleted  int    `json:"deleted"`
+}
+
+// newExecutionSummary creates the summary of an execution from its result
+func newExecutionSummary(start time.Time, result ExecutionResult, err error) ExecutionSummary {
+	summary := ExecutionSummary{
+		StartedAt:       start.UTC(),
+		DurationMs:      time.Since(start).Milliseconds(),
+		Scanned:         result.Scanned,
+		Deleted:         result.Deleted,
+		FailedDeletions: result.FailedDeletions,
+		ListFailures:    len(result.ListFailures),
+		DryRun:          currentLiveConfiguration().DryRun,
+		TopResources:    make([]ResourceDeletions, 0, len(result.DeletedPerResource)),
+	}
+	for resource, deleted := range result.DeletedPerResource {
+		summary.TopResources = append(summary.TopResources, ResourceDeletions{Resource: resource, Deleted: deleted})
+	}
+	sort.Slice(summary.TopResources, func(i, j int) bool {
+		if summary.TopResources[i].Deleted != summary.TopResources[j].Deleted {
+			return summary.TopResources[i].Deleted > summary.TopResources[j].Deleted
+		}
+		return summary.TopResources[i].Resource < summary.TopResources[j].Resource
+	})
+	if len(summary.TopResources) > ExecutionSummaryTopResources {
+		summary.TopResources = summary.TopResources[:ExecutionSummaryTopResources]
+	}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+	return summary
+}
+
+// writeExecutionSummary writes the summary of an execution to executionSummaryWriter, if configured
+func writeExecutionSummary(start time.Time, result ExecutionResult, err error) {
+	if executionSummaryWriter == nil {
+		return
+	}
+	data, _ := json.Marshal(newExecutionSummary(start, result, err))
+	if _, err := executionSummaryWriter.Write(append(data, '\n')); err != nil {
+		logger.Error(fmt.Sprintf("Failed to write execution summary: %s", err))
+	}
+}
+
+// openExecutionSummaryWriter opens the destination of the execution summaries, which is either "stdout", a file
+// descriptor inherited from the parent process in the format "fd:N", or the path of a file to append to
+func openExecutionSummaryWriter(destination string) (io.Writer, error) {
+	if destination == "stdout" {
+		return os.Stdout, nil
+	}
+	if fd, found := strings.CutPrefix(destination, "fd:"); found {
+		number, err := strconv.ParseUint(fd, 10, 32)
+		if err != nil || number <= 2 {
+			return nil, fmt.Errorf("invalid file descriptor '%s', must be greater than 2", fd)
+		}
+		return os.NewFile(uintptr(number), destination), nil
+	}
+	return os.OpenFile(destination, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+}
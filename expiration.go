@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ErrNoTTL is returned by resolveExpiration for items without a TTL, which are not reported since most resources don't
+// have one
+var ErrNoTTL = errors.New("resource has no TTL")
+
+// Expiration describes when an item expires
+type Expiration struct {
+	TTL       string    // TTL of the item, or the equivalent of its delete-at timestamp
+	ExpiresAt time.Time // Time at which the item expires
+	Source    string    // Where the TTL was read from
+
+	UncappedTTL        string // TTL of the item before it was capped at the maximum TTL of its kind, empty if it wasn't capped
+	RelativeToOwnerErr error  // Why the TTL relative to the item's owner could not be resolved, if its own TTL was used instead
+}
+
+// ExpirationError explains why the expiration of an item could not be resolved, in which case the item is skipped
+type ExpirationError struct {
+	Reason       string // Reason why the expiration could not be resolved, e.g. "has an invalid TTL '1x': ..."
+	Notable      bool   // Whether the reason is worth logging at the info level rather than at the debug level
+	EventReason  string // Reason of the warning event to create on the item, empty if no event should be created
+	EventMessage string // Message of the warning event to create on the item
+}
+
+func (e *ExpirationError) Error() string {
+	return e.Reason
+}
+
+// hasTTL checks whether an item has a delete-at timestamp, a TTL or a TTL relative to its owner, as opposed to items
+// whose TTL can only be inherited from their owners
+func hasTTL(item unstructured.Unstructured) bool {
+	if _, exists, _ := getDeleteAt(item); exists {
+		return true
+	}
+	if _, _, exists := getTTL(item); exists {
+		return true
+	}
+	_, existsRelativeToOwner := item.GetAnnotations()[AnnotationTTLRelativeToOwner]
+	return existsRelativeToOwner
+}
+
+// resolveExpiration resolves when an item expires from its delete-at timestamp, its TTL, or the TTL of its owners.
+//
+// This is shared by DoReconcile and the status endpoint, so that the latter reports exactly what the former does. It
+// has no side effects: creating events and logging the outcome is left to the caller.
+func resolveExpiration(ownerResolver *OwnerResolver, item unstructured.Unstructured) (Expiration, error) {
+	annotations := item.GetAnnotations()
+	ttlSource, ttl, exists := getTTL(item)
+	if deleteAt, existsDeleteAt, err := getDeleteAt(item); existsDeleteAt {
+		if err != nil {
+			return Expiration{}, &ExpirationError{
+				Reason:       fmt.Sprintf("has an invalid delete-at timestamp '%s', skipping: %s", annotations[AnnotationDeleteAt], err),
+				Notable:      true,
+				EventReason:  "InvalidDeleteAt",
+				EventMessage: fmt.Sprintf("Unable to parse delete-at timestamp '%s': %s", annotations[AnnotationDeleteAt], err),
+			}
+		}
+		return Expiration{TTL: getDeleteAtTTL(item, deleteAt), ExpiresAt: deleteAt, Source: AnnotationDeleteAt}, nil
+	}
+	if exists && isTTLDisabled(ttl) {
+		return Expiration{}, &ExpirationError{Reason: fmt.Sprintf("has its TTL explicitly disabled with '%s', skipping", ttl)}
+	}
+	ttlRelativeToOwner, existsRelativeToOwner := annotations[AnnotationTTLRelativeToOwner]
+	if invalid, err := hasInvalidRefreshedAt(item); invalid && (exists || existsRelativeToOwner) {
+		return Expiration{}, &ExpirationError{
+			Reason:       fmt.Sprintf("has an invalid refreshed-at timestamp '%s', skipping: %s", annotations[AnnotationRefreshedAt], err),
+			Notable:      true,
+			EventReason:  "InvalidRefreshedAt",
+			EventMessage: fmt.Sprintf("Unable to parse refreshed-at timestamp '%s': %s", annotations[AnnotationRefreshedAt], err),
+		}
+	}
+	if isWaitingToBecomeReady(item) {
+		return Expiration{}, &ExpirationError{Reason: "is not ready yet, so its TTL has not started"}
+	}
+	expiration := Expiration{Source: ttlSource}
+	startTime := getStartTime(item)
+	if !exists && !existsRelativeToOwner {
+		if !inheritTTLFromOwner {
+			return Expiration{}, ErrNoTTL
+		}
+		var err error
+		if ttl, startTime, err = ownerResolver.GetInheritedTTL(item); err != nil {
+			return Expiration{}, &ExpirationError{Reason: fmt.Sprintf("has no TTL and could not inherit one from its owners: %s", err)}
+		}
+		if isTTLDisabled(ttl) {
+			return Expiration{}, &ExpirationError{Reason: fmt.Sprintf("inherited a TTL explicitly disabled with '%s' from its owners, skipping", ttl)}
+		}
+		expiration.Source = "its owners"
+	}
+	var ttlInDuration time.Duration
+	resolvedTTL := false
+	if existsRelativeToOwner {
+		if ttlRelativeToOwnerInDuration, err := ownerResolver.GetTTLRelativeToOwner(item, ttlRelativeToOwner); err == nil {
+			ttl, ttlInDuration, resolvedTTL = ttlRelativeToOwnerInDuration.String(), ttlRelativeToOwnerInDuration, true
+			expiration.Source = AnnotationTTLRelativeToOwner
+		} else if errors.Is(err, ErrOwnerTTLDisabled) {
+			return Expiration{}, &ExpirationError{Reason: "has a TTL relative to an owner whose TTL is explicitly disabled, skipping"}
+		} else if exists {
+			expiration.RelativeToOwnerErr = err
+		} else {
+			return Expiration{}, &ExpirationError{
+				Reason:       fmt.Sprintf("failed to resolve TTL relative to owner: %s", err),
+				Notable:      true,
+				EventReason:  "FailedToResolveOwnerTTL",
+				EventMessage: "Unable to resolve TTL relative to owner: " + err.Error(),
+			}
+		}
+	}
+	if !resolvedTTL {
+		var err error
+		if ttlInDuration, err = parseTTL(ttl); err != nil {
+			return Expiration{}, &ExpirationError{
+				Reason:       fmt.Sprintf("has an invalid TTL '%s': %s", ttl, err),
+				Notable:      true,
+				EventReason:  "InvalidTTL",
+				EventMessage: fmt.Sprintf("Unable to parse TTL '%s': %s", ttl, err),
+			}
+		}
+	}
+	if cappedTTL, cappedTTLInDuration, capped := capTTL(item, ttl, ttlInDuration); capped {
+		expiration.UncappedTTL = ttl
+		ttl, ttlInDuration = cappedTTL, cappedTTLInDuration
+	}
+	expiration.TTL = ttl
+	expiration.ExpiresAt = startTime.Add(ttlInDuration).Add(getDeletionJitter(item)).Add(currentLiveConfiguration().GlobalTTLExtension)
+	return expiration, nil
+}
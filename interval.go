@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ResourceIntervals keeps track of when each resource was last reconciled, so that resources configured with an
+// interval of their own (e.g. namespaces, which rarely change) are only reconciled once that interval has elapsed,
+// rather than on every execution
+type ResourceIntervals struct {
+	intervals        map[string]time.Duration // Interval of each resource, keyed by either resource or resource.group
+	lastReconciledAt map[schema.GroupResource]time.Time
+	mutex            sync.Mutex
+}
+
+// NewResourceIntervals creates a new ResourceIntervals
+func NewResourceIntervals(intervals map[string]time.Duration) *ResourceIntervals {
+	return &ResourceIntervals{intervals: intervals, lastReconciledAt: make(map[schema.GroupResource]time.Time)}
+}
+
+// Get returns the interval of a resource, if it has one.
+//
+// An interval configured for resource.group (e.g. events.events.k8s.io) takes precedence over one configured for the
+// resource name alone (e.g. events).
+func (r *ResourceIntervals) Get(gvr schema.GroupVersionResource) (time.Duration, bool) {
+	if interval, exists := r.intervals[gvr.GroupResource().String()]; exists {
+		return interval, true
+	}
+	interval, exists := r.intervals[gvr.Resource]
+	return interval, exists
+}
+
+// IsDue checks whether a resource should be reconciled, which is the case unless it has an interval of its own that
+// hasn't elapsed since it was last reconciled
+func (r *ResourceIntervals) IsDue(gvr schema.GroupVersionResource) bool {
+	interval, exists := r.Get(gvr)
+	if !exists {
+		return true
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	lastReconciledAt, reconciled := r.lastReconciledAt[gvr.GroupResource()]
+	return !reconciled || time.Since(lastReconciledAt) >= interval
+}
+
+// Reconciled records that a resource was reconciled at a given time
+func (r *ResourceIntervals) Reconciled(gvr schema.GroupVersionResource, at time.Time) {
+	if _, exists := r.Get(gvr); !exists {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.lastReconciledAt[gvr.GroupResource()] = at
+}
+
+// String returns the interval of each resource, for the configuration logs
+func (r *ResourceIntervals) String() string {
+	return fmt.Sprintf("%v", r.intervals)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestResourceIntervals_IsDue(t *testing.T) {
+	eventsGVR := schema.GroupVersionResource{Group: "events.k8s.io", Version: "v1", Resource: "events"}
+	coreEventsGVR := schema.GroupVersionResource{Version: "v1", Resource: "events"}
+	intervals := NewResourceIntervals(map[string]time.Duration{"namespaces": time.Hour, "events": 10 * time.Minute, "events.events.k8s.io": time.Minute})
+	scenarios := []struct {
+		name             string
+		gvr              schema.GroupVersionResource
+		lastReconciledAt time.Time
+		expected         bool
+	}{
+		{name: "without-interval", gvr: podsGVR, lastReconciledAt: time.Now(), expected: true},
+		{name: "never-reconciled", gvr: namespacesGVR, expected: true},
+		{name: "interval-not-elapsed", gvr: namespacesGVR, lastReconciledAt: time.Now().Add(-59 * time.Minute), expected: false},
+		{name: "interval-elapsed", gvr: namespacesGVR, lastReconciledAt: time.Now().Add(-time.Hour), expected: true},
+		{name: "interval-by-name", gvr: coreEventsGVR, lastReconciledAt: time.Now().Add(-5 * time.Minute), expected: false},
+		{name: "interval-by-name-and-group", gvr: eventsGVR, lastReconciledAt: time.Now().Add(-5 * time.Minute), expected: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			intervals.lastReconciledAt = make(map[schema.GroupResource]time.Time)
+			if !scenario.lastReconciledAt.IsZero() {
+				intervals.Reconciled(scenario.gvr, scenario.lastReconciledAt)
+			}
+			if due := intervals.IsDue(scenario.gvr); due != scenario.expected {
+				t.Errorf("expected due=%t, got %t", scenario.expected, due)
+			}
+		})
+	}
+}
+
+func TestReconcile_withResourceIntervals(t *testing.T) {
+	defer func(previous *ResourceIntervals) { resourceIntervals = previous }(resourceIntervals)
+	resourceIntervals = NewResourceIntervals(map[string]time.Duration{"deployments.apps": time.Hour})
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList, deploymentsAPIResourceList)
+	createExpiredItems := func() {
+		pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		deployment := newUnstructuredWithAnnotations("apps/v1", "Deployment", "default", "expired-deployment-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+		if _, err := dynamicClient.Resource(deploymentsGVR).Namespace("default").Create(context.TODO(), deployment, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// The first execution reconciles every resource
+	createExpiredItems()
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := len(listNames(t, dynamicClient, podsGVR, "default")) + len(listNames(t, dynamicClient, deploymentsGVR, "default")); remaining != 0 {
+		t.Fatalf("expected every expired resource to have been deleted by the first execution, got %d remaining", remaining)
+	}
+	// The second execution only reconciles the resources without an interval, as that of deployments hasn't elapsed
+	createExpiredItems()
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 0 {
+		t.Errorf("expected the expired pod to have been deleted, got %v", remaining)
+	}
+	if remaining := listNames(t, dynamicClient, deploymentsGVR, "default"); len(remaining) != 1 {
+		t.Errorf("expected the expired deployment not to have been deleted yet, got %v", remaining)
+	}
+	// Once the interval has elapsed, deployments are reconciled again
+	resourceIntervals.Reconciled(deploymentsGVR, time.Now().Add(-time.Hour))
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, deploymentsGVR, "default"); len(remaining) != 0 {
+		t.Errorf("expected the expired deployment to have been deleted, got %v", remaining)
+	}
+}
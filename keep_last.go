@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// expiredResource is a resource whose TTL has expired
+type expiredResource struct {
+	item      unstructured.Unstructured
+	ttl       string
+	expiresAt time.Time
+	keepLast  int
+}
+
+// KeepLastGroups groups expired resources annotated with AnnotationKeepLast so that the most recent ones of each
+// group can be exempted from deletion.
+//
+// Resources are grouped by namespace and controller owner, meaning that for instance, the Jobs created by a
+// CronJob form one group. Resources without an owner are grouped by namespace.
+type KeepLastGroups struct {
+	resource string
+	groups   map[string][]expiredResource
+}
+
+// NewKeepLastGroups creates a new KeepLastGroups for the given resource
+func NewKeepLastGroups(resource string) *KeepLastGroups {
+	return &KeepLastGroups{resource: resource, groups: make(map[string][]expiredResource)}
+}
+
+// Add adds an expired resource to its group
+func (g *KeepLastGroups) Add(item unstructured.Unstructured, keepLast, ttl string, expiresAt time.Time) {
+	numberToKeep, err := strconv.Atoi(keepLast)
+	if err != nil || numberToKeep < 0 {
+		logger.Info(fmt.Sprintf("[%s/%s] has an invalid keep-last value '%s', ignoring", g.resource, item.GetName(), keepLast))
+		numberToKeep = 0
+	}
+	key := item.GetNamespace()
+	if ownerReference, exists := getOwnerReference(item); exists {
+		key += "/" + string(ownerReference.UID)
+	}
+	g.groups[key] = append(g.groups[key], expiredResource{item: item, ttl: ttl, expiresAt: expiresAt, keepLast: numberToKeep})
+}
+
+// ResourcesToDelete returns the expired resources of each group that are not among the most recent ones.
+//
+// The number of resources to keep for a group is determined by the keep-last value of its most recent resource.
+func (g *KeepLastGroups) ResourcesToDelete() []expiredResource {
+	var resourcesToDelete []expiredResource
+	for _, group := range g.groups {
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].item.GetCreationTimestamp().After(group[j].item.GetCreationTimestamp().Time)
+		})
+		for i, expired := range group {
+			if i < group[0].keepLast {
+				logger.Info(fmt.Sprintf("[%s/%s] has expired, but is kept because it is among the %d most recent of its group", g.resource, expired.item.GetName(), group[0].keepLast))
+				continue
+			}
+			resourcesToDelete = append(resourcesToDelete, expired)
+		}
+	}
+	return resourcesToDelete
+}
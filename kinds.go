@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+)
+
+// DefaultProtectedKinds are the kinds that are never deleted unless explicitly allowed through AllowDangerousKindsEnv,
+// because deleting them by accident (e.g. because of a stray annotation) could take down an entire cluster
+var DefaultProtectedKinds = []string{
+	"APIService",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"CustomResourceDefinition",
+	"MutatingWebhookConfiguration",
+	"Namespace",
+	"Node",
+	"PersistentVolume",
+	"PriorityClass",
+	"StorageClass",
+	"ValidatingWebhookConfiguration",
+}
+
+// parseProtectedKinds returns the kinds that must never be deleted.
+//
+// kinds is a comma-separated list of kinds that replaces DefaultProtectedKinds if not empty, while allowed is either
+// "true" to allow every kind to be deleted, or a comma-separated list of kinds to remove from the protected kinds.
+func parseProtectedKinds(kinds, allowed string) []string {
+	if allowed == "true" {
+		return nil
+	}
+	candidates := DefaultProtectedKinds
+	if kinds != "" {
+		candidates = splitKinds(kinds)
+	}
+	allowedKinds := splitKinds(allowed)
+	var protected []string
+	for _, kind := range candidates {
+		if !contains(allowedKinds, kind) {
+			protected = append(protected, kind)
+		}
+	}
+	return protected
+}
+
+// splitKinds splits a comma-separated list of kinds, ignoring surrounding spaces and empty entries
+func splitKinds(value string) []string {
+	var kinds []string
+	for _, kind := range strings.Split(value, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
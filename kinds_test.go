@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProtectedKinds(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		kinds    string
+		allowed  string
+		expected []string
+	}{
+		{
+			name:     "default",
+			expected: DefaultProtectedKinds,
+		},
+		{
+			name:     "allow-some",
+			kinds:    "Namespace,Node,PersistentVolume",
+			allowed:  "Node, PersistentVolume",
+			expected: []string{"Namespace"},
+		},
+		{
+			name:     "allow-all",
+			allowed:  "true",
+			expected: nil,
+		},
+		{
+			name:     "override",
+			kinds:    "Namespace, ,Secret",
+			expected: []string{"Namespace", "Secret"},
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			if protectedKinds := parseProtectedKinds(scenario.kinds, scenario.allowed); !reflect.DeepEqual(protectedKinds, scenario.expected) {
+				t.Errorf("expected %v, got %v", scenario.expected, protectedKinds)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// parseProtectedLabels parses a comma-separated list of labels such as "app.kubernetes.io/managed-by=Helm,do-not-delete".
+//
+// A label without a value protects resources with any value for that label, which is represented by an empty value.
+func parseProtectedLabels(value string) map[string]string {
+	protected := make(map[string]string)
+	if value == "" {
+		return protected
+	}
+	for _, label := range strings.Split(value, ",") {
+		key, labelValue, _ := strings.Cut(label, "=")
+		if key = strings.TrimSpace(key); key != "" {
+			protected[key] = strings.TrimSpace(labelValue)
+		}
+	}
+	return protected
+}
+
+// getProtectingLabel returns the first label of an item that matches protectedLabels, if any
+func getProtectingLabel(item unstructured.Unstructured) (string, bool) {
+	for key, value := range item.GetLabels() {
+		protectedValue, exists := protectedLabels[key]
+		if !exists {
+			continue
+		}
+		if protectedValue == "" || protectedValue == value {
+			return key + "=" + value, true
+		}
+	}
+	return "", false
+}
+
+// LabelTTL is a TTL applied to resources that have a given label, see LabelTTLMapEnv
+type LabelTTL struct {
+	Key   string
+	Value string // Empty if resources with any value for the label match
+	TTL   string
+}
+
+// String returns the label matched by the LabelTTL
+func (l LabelTTL) String() string {
+	if l.Value == "" {
+		return l.Key
+	}
+	return l.Key + "=" + l.Value
+}
+
+// parseLabelTTLs parses a comma-separated list of labels mapped to a TTL such as "tier=ephemeral:1d,tekton.dev/pipelineRun:2h".
+//
+// A label without a value matches resources with any value for that label. Since neither label keys nor label values
+// may contain colons, the TTL is whatever follows the last colon. The order of the list is preserved, so that the first
+// label matching a resource determines its TTL.
+func parseLabelTTLs(value string) ([]LabelTTL, error) {
+	var labelTTLs []LabelTTL
+	if value == "" {
+		return labelTTLs, nil
+	}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		separatorIndex := strings.LastIndex(entry, ":")
+		if separatorIndex == -1 {
+			return nil, fmt.Errorf("missing TTL for label %s", entry)
+		}
+		label, ttl := strings.TrimSpace(entry[:separatorIndex]), strings.TrimSpace(entry[separatorIndex+1:])
+		if _, err := parseTTL(ttl); err != nil {
+			return nil, fmt.Errorf("invalid TTL for label %s: %w", label, err)
+		}
+		key, labelValue, _ := strings.Cut(label, "=")
+		if key = strings.TrimSpace(key); key == "" {
+			return nil, fmt.Errorf("missing label key in %s", entry)
+		}
+		labelTTLs = append(labelTTLs, LabelTTL{Key: key, Value: strings.TrimSpace(labelValue), TTL: ttl})
+	}
+	return labelTTLs, nil
+}
+
+// getTTLFromLabels returns the first LabelTTL from labelTTLs that matches the given labels, if any
+func getTTLFromLabels(labels map[string]string) (LabelTTL, bool) {
+	for _, labelTTL := range labelTTLs {
+		value, exists := labels[labelTTL.Key]
+		if exists && (labelTTL.Value == "" || labelTTL.Value == value) {
+			return labelTTL, true
+		}
+	}
+	return LabelTTL{}, false
+}
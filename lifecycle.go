@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TwiN/kevent"
+)
+
+const (
+	PodNameEnv      = "POD_NAME"
+	PodNamespaceEnv = "POD_NAMESPACE"
+
+	// ServiceAccountNamespaceFile is the file from which the namespace of the controller is read if PodNamespaceEnv is
+	// not set
+	ServiceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+	// LifecycleEventFlushDelay is how long to wait for the lifecycle event created on shutdown to be sent, since events
+	// are sent asynchronously
+	LifecycleEventFlushDelay = time.Second
+)
+
+// getControllerPod returns the namespace and name of the Pod the controller is running in, which lifecycle events are
+// created on.
+//
+// Returns false if the controller doesn't appear to be running in a Pod.
+func getControllerPod() (string, string, bool) {
+	namespace := os.Getenv(PodNamespaceEnv)
+	if namespace == "" {
+		if data, err := os.ReadFile(ServiceAccountNamespaceFile); err == nil {
+			namespace = strings.TrimSpace(string(data))
+		}
+	}
+	name := os.Getenv(PodNameEnv)
+	if name == "" {
+		// The hostname of a Pod is its name, unless spec.hostname is set
+		name, _ = os.Hostname()
+	}
+	return namespace, name, namespace != "" && name != ""
+}
+
+// createControllerEvent creates an event on the Pod the controller is running in, if it can be determined
+func createControllerEvent(eventManager *kevent.EventManager, reason, message string, isWarning bool) {
+	namespace, name, ok := getControllerPod()
+	if !ok {
+		logger.Debug(fmt.Sprintf("Unable to determine the Pod the controller is running in, skipping %s event", reason))
+		return
+	}
+	eventManager.Create(namespace, "Pod", name, reason, message, isWarning)
+}
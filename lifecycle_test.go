@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TwiN/kevent"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCreateControllerEvent(t *testing.T) {
+	t.Setenv(PodNamespaceEnv, "kube-system")
+	t.Setenv(PodNameEnv, "k8s-ttl-controller-abc12")
+	kubernetesClient := fakekubernetes.NewSimpleClientset()
+	createControllerEvent(kevent.NewEventManager(kubernetesClient, "k8s-ttl-controller"), "Started", "Controller started", false)
+	// Events are sent asynchronously
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		events, err := kubernetesClient.CoreV1().Events("kube-system").List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(events.Items) > 0 {
+			event := events.Items[0]
+			if event.Reason != "Started" || event.InvolvedObject.Kind != "Pod" || event.InvolvedObject.Name != "k8s-ttl-controller-abc12" {
+				t.Errorf("expected a Started event on Pod k8s-ttl-controller-abc12, got %s event on %s %s", event.Reason, event.InvolvedObject.Kind, event.InvolvedObject.Name)
+			}
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Error("expected a lifecycle event to have been created")
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
+)
+
+var (
+	metadataClient metadata.Interface // Client used to list resources as PartialObjectMetadata, if metadata-only listing is enabled
+
+	// Resources for which the API server does not support metadata-only listing, which are listed as full objects instead.
+	// Since an execution that timed out keeps running in the background, it may be updated concurrently.
+	metadataListUnsupportedResources sync.Map
+)
+
+// ListCheckpoints keeps track of the continue token of the last page listed for each resource and namespace, so that an
+// execution that timed out before listing every item of a resource can be resumed by the next execution instead of
+// starting over, which would otherwise prevent the last items of very large resources from ever being reconciled.
+//
+// Since an execution that timed out keeps running in the background, checkpoints may be updated concurrently.
+type ListCheckpoints struct {
+	continueTokens map[string]string
+	mutex          sync.Mutex
+}
+
+// NewListCheckpoints creates a new ListCheckpoints
+func NewListCheckpoints() *ListCheckpoints {
+	return &ListCheckpoints{continueTokens: make(map[string]string)}
+}
+
+// Get returns the continue token from which the listing of a resource in a namespace should be resumed, which is empty
+// if the resource should be listed from the start
+func (c *ListCheckpoints) Get(gvr schema.GroupVersionResource, namespace string) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.continueTokens[checkpointKey(gvr, namespace)]
+}
+
+// Set records the continue token of the next page to list of a resource in a namespace.
+//
+// An empty continue token means that every page has been listed, in which case the checkpoint is reset.
+func (c *ListCheckpoints) Set(gvr schema.GroupVersionResource, namespace, continueToken string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if continueToken == "" {
+		delete(c.continueTokens, checkpointKey(gvr, namespace))
+	} else {
+		c.continueTokens[checkpointKey(gvr, namespace)] = continueToken
+	}
+}
+
+// checkpointKey returns the key of the checkpoint of a resource in a namespace
+func checkpointKey(gvr schema.GroupVersionResource, namespace string) string {
+	return gvr.String() + "|" + namespace
+}
+
+// listItems lists a page of the items of a resource in a namespace, or in all namespaces if namespace is empty.
+//
+// Only the metadata of each item is retrieved if metadata-only listing is enabled, which significantly reduces the
+// memory and bandwidth used on clusters with large resources (e.g. ConfigMaps, Secrets). Full objects are retrieved
+// instead if the resource needs more than its metadata to be reconciled, or if the API server does not support
+// metadata-only listing for it.
+func listItems(ctx context.Context, dynamicClient dynamic.Interface, gvr schema.GroupVersionResource, apiResource metav1.APIResource, namespace string, options metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	if _, unsupported := metadataListUnsupportedResources.Load(gvr); metadataClient == nil || requiresFullObjects(apiResource) || unsupported {
+		return dynamicClient.Resource(gvr).Namespace(namespace).List(ctx, options)
+	}
+	metadataList, err := metadataClient.Resource(gvr).Namespace(namespace).List(ctx, options)
+	if err != nil {
+		if apierrors.IsNotAcceptable(err) || apierrors.IsUnsupportedMediaType(err) {
+			logger.Info(fmt.Sprintf("Listing only the metadata of %s from %s is not supported by the API server, falling back to full objects", gvr.Resource, gvr.GroupVersion()))
+			metadataListUnsupportedResources.Store(gvr, true)
+			return dynamicClient.Resource(gvr).Namespace(namespace).List(ctx, options)
+		}
+		return nil, err
+	}
+	return toUnstructuredList(gvr, apiResource, metadataList)
+}
+
+// requiresFullObjects checks whether the items of a resource need more than their metadata to be reconciled
+func requiresFullObjects(apiResource metav1.APIResource) bool {
+	if archiver != nil {
+		// Resources must be archived in full before being deleted
+		return true
+	}
+	_, hasDeleteCondition := deleteConditions[apiResource.Kind]
+	_, hasDeleteConditionReasons := deleteConditionReasons[apiResource.Kind]
+	_, hasDeleteConditionDuration := deleteConditionDurations[apiResource.Kind]
+	// The volume claim templates of StatefulSets are needed to find their PersistentVolumeClaims
+	needsVolumeClaimTemplates := deleteStatefulSetPVCs && apiResource.Kind == "StatefulSet"
+	// The phase of resources is needed to determine whether they should be purged
+	_, hasPurgePolicy := purgePolicies[apiResource.Kind]
+	return hasDeleteCondition || hasDeleteConditionReasons || hasDeleteConditionDuration || needsVolumeClaimTemplates || hasPurgePolicy
+}
+
+// toUnstructuredList converts a list of PartialObjectMetadata to an UnstructuredList, so that it can be reconciled the
+// same way as a list of full objects.
+//
+// The API version and kind of the items are set from the resource they were listed from, because the metadata API
+// returns them as meta.k8s.io/v1 PartialObjectMetadata.
+func toUnstructuredList(gvr schema.GroupVersionResource, apiResource metav1.APIResource, metadataList *metav1.PartialObjectMetadataList) (*unstructured.UnstructuredList, error) {
+	list := &unstructured.UnstructuredList{Items: make([]unstructured.Unstructured, 0, len(metadataList.Items))}
+	list.SetContinue(metadataList.GetContinue())
+	list.SetResourceVersion(metadataList.GetResourceVersion())
+	for i := range metadataList.Items {
+		item, err := toUnstructured(gvr, apiResource.Kind, &metadataList.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, *item)
+	}
+	return list, nil
+}
+
+// toUnstructured converts a PartialObjectMetadata of a resource with the given kind to an Unstructured
+func toUnstructured(gvr schema.GroupVersionResource, kind string, partialObjectMetadata *metav1.PartialObjectMetadata) (*unstructured.Unstructured, error) {
+	objectMeta, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&partialObjectMetadata.ObjectMeta)
+	if err != nil {
+		return nil, err
+	}
+	item := &unstructured.Unstructured{Object: map[string]interface{}{"metadata": objectMeta}}
+	item.SetAPIVersion(gvr.GroupVersion().String())
+	item.SetKind(kind)
+	return item, nil
+}
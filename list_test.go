@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+)
+
+// BenchmarkListItems compares listing large ConfigMaps as full objects with listing only their metadata.
+//
+// The API server is emulated so that the cost of transferring and decoding the response is taken into account.
+func BenchmarkListItems(b *testing.B) {
+	defer func(previous metadata.Interface) { metadataClient = previous }(metadataClient)
+	configMapsGVR := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	configMapsAPIResource := metav1.APIResource{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: []string{"delete", "get", "list"}}
+	fullList := &unstructured.UnstructuredList{Object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMapList"}}
+	metadataList := &metav1.PartialObjectMetadataList{TypeMeta: metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "PartialObjectMetadataList"}}
+	data := strings.Repeat("x", 64*1024)
+	for i := 0; i < 100; i++ {
+		configMap := newUnstructuredWithAnnotations("v1", "ConfigMap", "default", fmt.Sprintf("config-map-name-%d", i), time.Now(), map[string]interface{}{AnnotationTTL: "3d"})
+		configMap.Object["data"] = map[string]interface{}{"key": data}
+		fullList.Items = append(fullList.Items, *configMap)
+		metadataList.Items = append(metadataList.Items, metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "PartialObjectMetadata"},
+			ObjectMeta: metav1.ObjectMeta{Name: configMap.GetName(), Namespace: configMap.GetNamespace(), Annotations: configMap.GetAnnotations()},
+		})
+	}
+	fullListBody, _ := fullList.MarshalJSON()
+	metadataListBody, _ := json.Marshal(metadataList)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(r.Header.Get("Accept"), "as=PartialObjectMetadataList") {
+			_, _ = w.Write(metadataListBody)
+		} else {
+			_, _ = w.Write(fullListBody)
+		}
+	}))
+	defer server.Close()
+	cfg := &rest.Config{Host: server.URL}
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	benchmarkMetadataClient, err := metadata.NewForConfig(cfg)
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	scenarios := []struct {
+		name           string
+		metadataClient metadata.Interface
+	}{
+		{name: "full-objects", metadataClient: nil},
+		{name: "metadata-only", metadataClient: benchmarkMetadataClient},
+	}
+	for _, scenario := range scenarios {
+		b.Run(scenario.name, func(b *testing.B) {
+			metadataClient = scenario.metadataClient
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				list, err := listItems(context.TODO(), dynamicClient, configMapsGVR, configMapsAPIResource, metav1.NamespaceAll, metav1.ListOptions{})
+				if err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+				if len(list.Items) != 100 || list.Items[0].GetKind() != "ConfigMap" {
+					b.Fatalf("expected 100 ConfigMaps, got %d items", len(list.Items))
+				}
+			}
+		})
+	}
+}
+
+func TestListCheckpoints(t *testing.T) {
+	checkpoints := NewListCheckpoints()
+	configMapsGVR := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	if continueToken := checkpoints.Get(configMapsGVR, "default"); continueToken != "" {
+		t.Errorf("expected no checkpoint, got '%s'", continueToken)
+	}
+	checkpoints.Set(configMapsGVR, "default", "token")
+	if continueToken := checkpoints.Get(configMapsGVR, "default"); continueToken != "token" {
+		t.Errorf("expected 'token', got '%s'", continueToken)
+	}
+	if continueToken := checkpoints.Get(configMapsGVR, "other"); continueToken != "" {
+		t.Errorf("expected checkpoints to be per namespace, got '%s'", continueToken)
+	}
+	checkpoints.Set(configMapsGVR, "default", "")
+	if continueToken := checkpoints.Get(configMapsGVR, "default"); continueToken != "" {
+		t.Errorf("expected the checkpoint to have been reset, got '%s'", continueToken)
+	}
+}
+
+func TestDoReconcile_withListCheckpointing(t *testing.T) {
+	defer func(previous *ListCheckpoints) { listCheckpoints = previous }(listCheckpoints)
+	listCheckpoints = NewListCheckpoints()
+	// The fake dynamic client ignores continue tokens, so the API server is emulated instead
+	pages := make(map[string][]byte)
+	for i, continueToken := range []string{"", "page-2"} {
+		pod := newUnstructuredWithAnnotations("v1", "Pod", "default", fmt.Sprintf("expired-pod-name-%d", i+1), time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+		list := &unstructured.UnstructuredList{Object: map[string]interface{}{"apiVersion": "v1", "kind": "PodList"}, Items: []unstructured.Unstructured{*pod}}
+		if continueToken == "" {
+			list.SetContinue("page-2")
+		}
+		pages[continueToken], _ = list.MarshalJSON()
+	}
+	var requestedContinueTokens, deleted []string
+	failSecondPage := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodDelete {
+			deleted = append(deleted, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+			_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Success"}`))
+			return
+		}
+		if r.URL.Path != "/api/v1/pods" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+			return
+		}
+		continueToken := r.URL.Query().Get("continue")
+		requestedContinueTokens = append(requestedContinueTokens, continueToken)
+		switch {
+		case continueToken == "expired-token":
+			w.WriteHeader(http.StatusGone)
+			_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Expired","code":410}`))
+		case continueToken == "page-2" && failSecondPage:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"InternalError","code":500}`))
+		default:
+			_, _ = w.Write(pages[continueToken])
+		}
+	}))
+	defer server.Close()
+	dynamicClient, err := dynamic.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _, eventManager := newTestClients()
+	// The first execution fails to list the second page, so that's where the next execution should resume
+	if result := DoReconcile(context.TODO(), dynamicClient, eventManager, []*metav1.APIResourceList{podsAPIResourceList}); len(result.ListFailures) != 1 {
+		t.Errorf("expected the first execution to have failed to list the second page, got %+v", result)
+	}
+	if checkpoint := listCheckpoints.Get(podsGVR, ""); checkpoint != "page-2" {
+		t.Errorf("expected the listing of pods to have been checkpointed at page-2, got '%s'", checkpoint)
+	}
+	failSecondPage = false
+	requestedContinueTokens = nil
+	DoReconcile(context.TODO(), dynamicClient, eventManager, []*metav1.APIResourceList{podsAPIResourceList})
+	if strings.Join(requestedContinueTokens, ",") != "page-2" {
+		t.Errorf("expected the second execution to have resumed from page-2, got %q", requestedContinueTokens)
+	}
+	if strings.Join(deleted, ",") != "expired-pod-name-1,expired-pod-name-2" {
+		t.Errorf("expected each expired pod to have been deleted once, got %v", deleted)
+	}
+	if checkpoint := listCheckpoints.Get(podsGVR, ""); checkpoint != "" {
+		t.Errorf("expected the checkpoint to have been reset after a full pass, got '%s'", checkpoint)
+	}
+	// An expired continue token must not prevent the resource from being listed
+	listCheckpoints.Set(podsGVR, "", "expired-token")
+	requestedContinueTokens = nil
+	DoReconcile(context.TODO(), dynamicClient, eventManager, []*metav1.APIResourceList{podsAPIResourceList})
+	if strings.Join(requestedContinueTokens, ",") != "expired-token,,page-2" {
+		t.Errorf("expected the listing to have started over after the continue token expired, got %q", requestedContinueTokens)
+	}
+}
+
+func TestDoReconcile_withPagination(t *testing.T) {
+	// The fake dynamic client ignores both the limit and the continue token, so the API server is emulated instead.
+	// Like the actual API server, continue tokens point to the first item of the next page, so deleting items between
+	// pages doesn't cause any item to be skipped.
+	defer func(previous *Throttler) { throttler = previous }(throttler)
+	throttler = NewThrottler(false, 0, 0, DefaultAdaptiveThrottleLatencyThreshold, 0)
+	numberOfPods := 2*ListLimit + ListLimit/2
+	var names []string
+	pods := make(map[string]*unstructured.Unstructured)
+	for i := 0; i < numberOfPods; i++ {
+		name := fmt.Sprintf("pod-name-%04d", i)
+		ttl := "5m"
+		if i%3 == 0 {
+			ttl = "3d"
+		}
+		names = append(names, name)
+		pods[name] = newUnstructuredWithAnnotations("v1", "Pod", "default", name, time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: ttl})
+	}
+	var requestedLimits []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodDelete {
+			delete(pods, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+			_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Success"}`))
+			return
+		}
+		if r.URL.Path != "/api/v1/pods" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+			return
+		}
+		requestedLimits = append(requestedLimits, r.URL.Query().Get("limit"))
+		var limit int
+		_, _ = fmt.Sscanf(r.URL.Query().Get("limit"), "%d", &limit)
+		continueToken := r.URL.Query().Get("continue")
+		list := &unstructured.UnstructuredList{Object: map[string]interface{}{"apiVersion": "v1", "kind": "PodList"}}
+		for _, name := range names {
+			if pod, exists := pods[name]; exists && name >= continueToken {
+				if limit > 0 && len(list.Items) == limit {
+					list.SetContinue(name)
+					break
+				}
+				list.Items = append(list.Items, *pod)
+			}
+		}
+		body, _ := list.MarshalJSON()
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+	// Hundreds of pods are deleted, so the client-side rate limiting would make this test take minutes
+	dynamicClient, err := dynamic.NewForConfig(&rest.Config{Host: server.URL, QPS: -1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _, eventManager := newTestClients()
+	if result := DoReconcile(context.TODO(), dynamicClient, eventManager, []*metav1.APIResourceList{podsAPIResourceList}); len(result.ListFailures) != 0 {
+		t.Errorf("expected no list failures, got %v", result.ListFailures)
+	}
+	if len(requestedLimits) != 3 {
+		t.Errorf("expected %d pods to have been listed in 3 pages, got %d pages", numberOfPods, len(requestedLimits))
+	}
+	for _, limit := range requestedLimits {
+		if limit != fmt.Sprint(ListLimit) {
+			t.Errorf("expected every page to have been requested with a limit of %d, got %s", ListLimit, limit)
+		}
+	}
+	for i, name := range names {
+		_, exists := pods[name]
+		if expired := i%3 != 0; expired && exists {
+			t.Errorf("expected expired pod %s to have been deleted", name)
+		} else if !expired && !exists {
+			t.Errorf("expected pod %s, which has not expired, not to have been deleted", name)
+		}
+	}
+}
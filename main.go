@@ -4,126 +4,1259 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"text/template"
 	"time"
 
 	"github.com/TwiN/kevent"
-	"github.com/xhit/go-str2duration/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 )
 
 const (
 	AnnotationTTL         = "k8s-ttl-controller.twin.sh/ttl"
 	AnnotationRefreshedAt = "k8s-ttl-controller.twin.sh/refreshed-at"
+	AnnotationLastUsedAt  = "k8s-ttl-controller.twin.sh/last-used-at"
+	AnnotationDeleteAt    = "k8s-ttl-controller.twin.sh/delete-at"
 
-	MaximumFailedExecutionBeforePanic = 10                    // Maximum number of allowed failed executions before panicking
-	ExecutionTimeout                  = 20 * time.Minute      // Maximum time for each reconciliation before timing out
-	ExecutionInterval                 = 5 * time.Minute       // Interval between each reconciliation
-	ThrottleDuration                  = 50 * time.Millisecond // Duration to sleep for throttling purposes
+	AnnotationTTLRelativeToOwner = "k8s-ttl-controller.twin.sh/ttl-relative-to-owner"
+	AnnotationKeepLast           = "k8s-ttl-controller.twin.sh/keep-last"
+	AnnotationDeletionWindow     = "k8s-ttl-controller.twin.sh/deletion-window" // Only applicable to namespaces
+	AnnotationDeletionJitter     = "k8s-ttl-controller.twin.sh/deletion-jitter"
+	AnnotationLock               = "k8s-ttl-controller.twin.sh/lock" // Only applicable to namespaces
+	AnnotationLastEvaluatedAt    = "k8s-ttl-controller.twin.sh/last-evaluated-at"
+	AnnotationStartTTLWhenReady  = "k8s-ttl-controller.twin.sh/start-ttl-when-ready"
+
+	MaximumFailedExecutionBeforePanic          = 10                    // Maximum number of allowed failed executions before panicking
+	MaximumTransientFailedExecutionBeforePanic = 30                    // Maximum number of allowed executions failed due to transient errors before panicking
+	ExecutionTimeout                           = 20 * time.Minute      // Maximum time for each reconciliation before timing out
+	ExecutionInterval                          = 5 * time.Minute       // Interval between each reconciliation
+	ThrottleDuration                           = 50 * time.Millisecond // Duration to sleep for throttling purposes
 
 	ListLimit = 500 // Maximum number of items to list at once
 
-	APIResourcesToWatchEnv = "API_RESOURCES_TO_WATCH"
+	CoreAPIGroup = "core" // Token used to refer to the core API group in filters, since its actual name is the empty string
+
+	APIResourcesToWatchEnv  = "API_RESOURCES_TO_WATCH"
+	APIGroupsToWatchEnv     = "API_GROUPS_TO_WATCH"
+	WatchNamespaceEnv       = "WATCH_NAMESPACE"
+	PreferredVersionOnlyEnv = "PREFERRED_VERSION_ONLY"
+
+	AdaptiveThrottleEnv                 = "ADAPTIVE_THROTTLE"
+	AdaptiveThrottleMinimumEnv          = "ADAPTIVE_THROTTLE_MINIMUM"
+	AdaptiveThrottleMaximumEnv          = "ADAPTIVE_THROTTLE_MAXIMUM"
+	AdaptiveThrottleLatencyThresholdEnv = "ADAPTIVE_THROTTLE_LATENCY_THRESHOLD"
+	ThrottleJitterEnv                   = "THROTTLE_JITTER"
+
+	RetryBudgetEnv               = "RETRY_BUDGET"
+	SkipDeniedDeletionRetriesEnv = "SKIP_DENIED_DELETION_RETRIES"
+
+	PropagationPolicyEnv       = "PROPAGATION_POLICY"
+	PropagationPolicyByKindEnv = "PROPAGATION_BY_KIND"
+	EvictPodsEnv               = "EVICT_PODS"
+
+	InheritTTLFromOwnerEnv   = "INHERIT_TTL_FROM_OWNER"
+	ExcludedOwnerKindsEnv    = "EXCLUDED_OWNER_KINDS"
+	ReapOrphansEnv           = "REAP_ORPHANS"
+	UnannotatedPurgeEnv      = "UNANNOTATED_PURGE"
+	ReapCascadeEnv           = "REAP_CASCADE"
+	DeleteStatefulSetPVCsEnv = "DELETE_STATEFULSET_PVCS"
+	OrphanGracePeriodEnv     = "ORPHAN_GRACE_PERIOD"
+
+	ProtectedLabelsEnv     = "PROTECTED_LABELS"
+	LabelTTLMapEnv         = "LABEL_TTL_MAP"
+	ProtectedKindsEnv      = "PROTECTED_KINDS"
+	AllowDangerousKindsEnv = "ALLOW_DANGEROUS_KINDS"
+	NamespacesToExcludeEnv = "NAMESPACES_TO_EXCLUDE"
+
+	MetricsEnabledEnv             = "METRICS_ENABLED"
+	MetricsPortEnv                = "METRICS_PORT"
+	MetricsStrictEnv              = "METRICS_STRICT"
+	ReportUnannotatedResourcesEnv = "REPORT_UNANNOTATED_RESOURCES"
+
+	DefaultMetricsPort = 8080
+
+	ArchiveWebhookURLEnv            = "ARCHIVE_WEBHOOK_URL"
+	ArchiveFailureBlocksDeletionEnv = "ARCHIVE_FAILURE_BLOCKS_DELETION"
+
+	ExternalCheckURLEnv = "EXTERNAL_CHECK_URL"
+
+	NATSURLEnv     = "NATS_URL"
+	NATSSubjectEnv = "NATS_SUBJECT"
+
+	StateConfigMapEnv  = "STATE_CONFIGMAP"
+	ConfigConfigMapEnv = "CONFIG_CONFIGMAP"
+
+	DefaultNATSSubject = "k8s-ttl-controller.deletions"
+
+	DeletionMessageTemplateEnv = "DELETION_MESSAGE_TEMPLATE"
+
+	StartupGracePeriodEnv = "STARTUP_GRACE_PERIOD"
+	ReconcileAlignEnv     = "RECONCILE_ALIGN"
+
+	ClockSkewToleranceEnv = "CLOCK_SKEW_TOLERANCE"
+
+	StrictRefreshedAtEnv = "STRICT_REFRESHED_AT"
+
+	StartTimeStrategyEnv        = "START_TIME_STRATEGY"
+	StartTimeStrategyPrecedence = "precedence" // The start time is read from the first valid timestamp, see getStartTime
+	StartTimeStrategyLatest     = "latest"     // The start time is the most recent of all valid timestamps, see getStartTime
+
+	GlobalTTLExtensionEnv = "GLOBAL_TTL_EXTENSION"
+
+	TTLAnnotationsEnv = "TTL_ANNOTATIONS"
+	MaxValidTTLEnv    = "MAX_VALID_TTL"
+
+	DeletionWindowEnv = "DELETION_WINDOW"
+
+	MaxCountPerOwnerEnv = "MAX_COUNT_PER_OWNER"
+
+	MaxTTLByKindEnv = "MAX_TTL_BY_KIND"
+
+	ResourceIntervalsEnv = "RESOURCE_INTERVALS"
+
+	DeletionAllowlistFileEnv = "DELETION_ALLOWLIST_FILE"
+
+	MaxDeletionsPerNamespaceEnv = "MAX_DELETIONS_PER_NAMESPACE"
+
+	DeletionBatchSizeEnv  = "DELETION_BATCH_SIZE"
+	DeletionBatchPauseEnv = "DELETION_BATCH_PAUSE"
+
+	MaxNamespaceDeletionsPerRunEnv = "MAX_NAMESPACE_DELETIONS_PER_RUN"
+	NamespaceDeletionThrottleEnv   = "NAMESPACE_DELETION_THROTTLE"
+
+	SkipTerminatingNamespacesEnv = "SKIP_TERMINATING_NAMESPACES"
+
+	DeleteConditionEnv         = "DELETE_CONDITION"
+	DeleteConditionReasonEnv   = "DELETE_CONDITION_REASON"
+	DeleteConditionDurationEnv = "DELETE_CONDITION_DURATION"
+
+	MetadataOnlyListEnv = "METADATA_ONLY_LIST"
+
+	PreDeletionWarningEnv = "PRE_DELETION_WARNING"
+
+	LastEvaluatedIntervalEnv = "LAST_EVALUATED_INTERVAL"
+
+	LifecycleEventsEnv = "LIFECYCLE_EVENTS"
+
+	StructuredEventsEnv = "STRUCTURED_EVENTS"
+
+	LogAggregateEnv = "LOG_AGGREGATE"
+
+	RunOnceEnv              = "RUN_ONCE"
+	FailIfNothingDeletedEnv = "FAIL_IF_NOTHING_DELETED"
+	WatchModeEnv            = "WATCH_MODE"
+	LowMemoryModeEnv        = "LOW_MEMORY_MODE"
+	DryRunEnv               = "DRY_RUN"
+	PausedEnv               = "PAUSED"
+	ObserveFormatEnv        = "OBSERVE_FORMAT"
+
+	ExecutionSummaryEnv = "EXECUTION_SUMMARY"
+
+	MaxScheduledDeletionsPerExecutionEnv = "MAX_SCHEDULED_DELETIONS_PER_EXECUTION"
+
+	MaxRequeuedDeletionsEnv = "MAX_REQUEUED_DELETIONS"
+
+	DeletionFailureThresholdEnv       = "DELETION_FAILURE_THRESHOLD"
+	DeletionFailureMinimumAttemptsEnv = "DELETION_FAILURE_MINIMUM_ATTEMPTS"
+
+	MaxDeletionPercentEnv = "MAX_DELETION_PERCENT"
+
+	ListCheckpointingEnv = "LIST_CHECKPOINTING"
+
+	ExitCodeSuccess         = 0 // The execution succeeded
+	ExitCodeFailure         = 1 // The execution failed for a reason not covered by a more specific exit code
+	ExitCodeDiscoveryFailed = 2 // The API resources could not be discovered
+	ExitCodeTimedOut        = 3 // The execution lasted for longer than ExecutionTimeout
+	ExitCodeDeletionsFailed = 4 // The execution completed, but at least one deletion failed
+	ExitCodeNothingDeleted  = 5 // The execution completed without deleting anything while FAIL_IF_NOTHING_DELETED is true
+
+	ExitCodeInvalidTTLsFound = 6 // The audit found at least one resource with an invalid TTL, see AuditCommand
+
+	DefaultAdaptiveThrottleMaximum          = 5 * time.Second // Default maximum duration to sleep for throttling purposes when adaptive throttling is enabled
+	DefaultAdaptiveThrottleLatencyThreshold = time.Second     // Default API server latency above which the throttle duration is increased
 )
 
 var (
-	ErrTimedOut = errors.New("execution timed out")
+	listTimeoutSeconds              = int64(60)
+	executionFailedCounter          = 0
+	transientExecutionFailedCounter = 0
+	deletedResourcesInExecution     = 0 // Number of resources deleted during the current execution
+	failedDeletionsInExecution      = 0 // Number of resources that failed to be deleted during the current execution
+
+	deletedResourcesPerNamespaceInExecution = make(map[string]int) // Number of resources deleted during the current execution, by namespace
+	deletedResourcesPerResourceInExecution  = make(map[string]int) // Number of resources deleted during the current execution, by resource.group
 
-	listTimeoutSeconds     = int64(60)
-	executionFailedCounter = 0
+	// Resources for which listing has been rejected with 405 Method Not Allowed. Since an execution that timed out keeps
+	// running in the background, it may be updated concurrently.
+	unlistableResources sync.Map
+
+	executionTimeout = ExecutionTimeout // Maximum time for each reconciliation before it is cancelled, overridden in tests
 
 	logger       *slog.Logger  // Global logger
 	programLevel slog.LevelVar // Info by default
+	logAggregate bool          // Whether per-item logs should be replaced by a summary per resource
+
+	ttlAnnotations = []string{AnnotationTTL} // Annotations from which the TTL is read, in order of precedence
+	maxValidTTL    = DefaultMaxValidTTL      // TTLs longer than this are invalid, as they are most likely typos
+
+	apiResourcesToWatch  []string
+	apiGroupsToWatch     []string
+	watchNamespaces      []string // Namespaces to restrict the controller to, empty if the controller is cluster-wide
+	preferredVersionOnly bool     // Whether resources should only be reconciled through the preferred version of their group
+
+	retryBudgetPerExecution   int  // Maximum number of delete retries allowed per execution
+	remainingRetryBudget      int  // Number of delete retries left for the current execution
+	skipDeniedDeletionRetries bool // Whether deletions denied by admission control should neither be retried nor requeued
+
+	propagationPolicy       *metav1.DeletionPropagation           // Propagation policy used when deleting resources, nil if the API server's default should be used
+	propagationPolicyByKind map[string]metav1.DeletionPropagation // Propagation policy used when deleting resources of a given kind
+	evictPods               bool                                  // Whether Pods should be evicted rather than deleted
+
+	inheritTTLFromOwner bool          // Whether resources without a TTL should inherit the TTL of their owners
+	excludedOwnerKinds  []string      // Kinds of owners whose resources must never be deleted
+	reapOrphans         bool          // Whether resources without a TTL whose owners no longer exist should be deleted
+	orphanGracePeriod   time.Duration // Duration for which a resource must have been orphaned before being deleted
+
+	cascadeTracker *CascadeTracker // Tracker of the parents whose deletion triggers the deletion of their children, nil if disabled
+
+	deleteStatefulSetPVCs bool // Whether the PersistentVolumeClaims of StatefulSets deleted because of their TTL should be deleted as well
+
+	purgePolicies map[string]*PurgePolicy // Policies under which resources of a given kind without a TTL are deleted regardless of their annotations
+
+	protectedLabels map[string]string // Labels that make resources undeletable, where an empty value matches any value
+	labelTTLs       []LabelTTL        // TTLs applied to resources with a given label, unless they have a TTL annotation
+	protectedKinds  []string          // Kinds that are never deleted, see DefaultProtectedKinds
+
+	namespacesToExclude = DefaultNamespacesToExclude // Namespaces in which nothing is ever deleted
+
+	metricsEnabled             bool // Whether Prometheus metrics should be exposed
+	metricsPort                int  // Port on which the Prometheus metrics are exposed
+	metricsStrict              bool // Whether the controller should panic if the metrics server cannot be started
+	reportUnannotatedResources bool // Whether resources without any annotations should be logged and counted
+
+	natsURL     string // URL of the NATS server to publish deletion messages to, if any
+	natsSubject string // NATS subject to publish deletion messages to
+
+	stateConfigMap  string    // ConfigMap ("namespace/name") in which the state of the controller is persisted, empty if disabled
+	configConfigMap string    // ConfigMap ("namespace/name") from which the live configuration is reloaded, empty if disabled
+	publisher       Publisher // Publisher of deletion messages, nil if none is configured
+
+	archiver *WebhookArchiver // Archiver of resources before their deletion, nil if none is configured
+
+	externalCheck *ExternalCheck // External check that must pass before resources are deleted, nil if none is configured
 
-	apiResourcesToWatch []string
+	deletionMessageTemplate *template.Template // Template of the message of the events created upon deletion, nil if the default message should be used
+
+	startedAt          = time.Now()  // Time at which the controller started
+	startupGracePeriod time.Duration // Duration after startup during which expired resources are not deleted
+	reconcileAlign     time.Duration // Wall-clock boundary the first execution is aligned to, 0 if it starts immediately
+
+	clockSkewTolerance time.Duration // Duration by which resources must have expired before being deleted
+
+	strictRefreshedAt bool // Whether resources with an invalid refreshed-at timestamp should be skipped rather than falling back to their creation timestamp
+
+	startTimeStrategy string // Strategy used to determine the time from which the TTL of a resource is calculated, see getStartTime
+
+	globalTTLExtension time.Duration // Duration added to the expiration of every resource, e.g. to pause deletions during incidents
+
+	deletionWindow *DeletionWindow // Daily window during which deletions are allowed, nil if deletions are allowed at all times
+
+	maxCountPerOwner map[string]int // Maximum number of resources of a given kind that a single owner may own
+
+	maxTTLByKind map[string]time.Duration // Maximum TTL of resources of a given kind, regardless of their annotations
+
+	allowlist Allowlist // Resources allowed to be deleted, nil if all resources are allowed to be deleted
+
+	metadataOnlyList bool // Whether to only retrieve the metadata of resources when listing them
+
+	listCheckpoints *ListCheckpoints // Continue tokens from which to resume listing resources, nil if disabled
+
+	deleteConditions         map[string]*DeleteCondition   // Conditions that resources of a given kind must meet to be deleted
+	deleteConditionReasons   map[string][]string           // Reasons, one of which must be on a status condition of resources of a given kind for them to be deleted
+	deleteConditionDurations map[string]*ConditionDuration // Status conditions that resources of a given kind must have been in for a minimum duration to be deleted
+
+	maxDeletionsPerNamespace int // Maximum number of resources that may be deleted in a single namespace per execution, 0 if unlimited
+
+	deletionBatchSize  int           // Number of deletions after which a checkpoint is logged, 0 if disabled
+	deletionBatchPause time.Duration // Duration to pause for after each batch of deletions, 0 if disabled
+
+	maxNamespaceDeletionsPerExecution int           // Maximum number of namespaces that may be deleted per execution, 0 if unlimited
+	namespaceDeletionThrottle         time.Duration // Duration to pause for after each namespace deletion, 0 if disabled
+
+	skipTerminatingNamespaces bool // Whether resources in namespaces that are being deleted should be left to the namespace controller
+
+	preDeletionWarning time.Duration // Duration before expiry at which a warning event is created, 0 if disabled
+
+	// Expiry time of the items a warning event was created for, keyed by GVR and namespace/name. Since an execution that
+	// timed out keeps running in the background, it may be updated concurrently.
+	warnedBeforeDeletion sync.Map
+
+	lastEvaluatedInterval time.Duration // Minimum interval between two updates of AnnotationLastEvaluatedAt, 0 if disabled
+
+	lifecycleEvents bool // Whether events should be created on the controller's Pod when it starts and stops
+
+	structuredEvents        string                   // Level of structured annotations on deletion events, empty if disabled
+	structuredEventRecorder *StructuredEventRecorder // Recorder used to create deletion events if structuredEvents is set
+
+	runOnce              bool // Whether the controller should exit after a single execution
+	failIfNothingDeleted bool // Whether the controller should exit with a non-zero code if nothing was deleted in run-once mode
+
+	dryRun        bool   // Whether deletions should only be logged rather than made
+	paused        bool   // Whether deletions are paused, e.g. during an incident
+	observeMode   bool   // Whether the controller was started with ObserveCommand
+	observeFormat string // Format of the report printed at the end of ObserveCommand, either ObserveFormatTable or ObserveFormatKubectl
+
+	watchMode bool     // Whether resources should be watched so that they can be deleted as soon as they expire
+	watcher   *Watcher // Watcher of resources, nil if watch mode is disabled
+
+	lowMemoryMode bool // Whether the memory footprint of the controller should be kept to a minimum at the cost of some features
+
+	executionSummaryWriter io.Writer // Destination of the summary written after each execution, nil if disabled
+
+	resourceIntervals *ResourceIntervals // Interval at which specific resources are reconciled, nil if every resource is reconciled on every execution
+
+	maxScheduledDeletionsPerExecution int                // Maximum number of deletions of resources expiring before the next execution to schedule per execution
+	scheduler                         *DeletionScheduler // Scheduler of the deletion of resources expiring before the next execution, nil if disabled
+
+	maxRequeuedDeletions int              // Maximum number of failed deletions to retry at the start of the next execution
+	requeue              *DeletionRequeue // Failed deletions to retry at the start of the next execution, nil if disabled
+
+	circuitBreaker  *CircuitBreaker  // Circuit breaker halting deletions when too many of them fail, nil if disabled
+	deletionCeiling *DeletionCeiling // Ceiling on the percentage of scanned resources deleted per execution, nil if disabled
+
+	deletionMutex        sync.Mutex // Serializes deletions, which may be made by both the reconciliation and the watcher
+	deletionsPausedUntil time.Time  // Time until which deletions are paused (see pauseDeletions), guarded by deletionMutex
+
+	throttler = NewThrottler(false, ThrottleDuration, ThrottleDuration, DefaultAdaptiveThrottleLatencyThreshold, 0)
 )
 
 func init() {
 	// Create a new logger, either in JSON or text format
 	if os.Getenv("JSON_LOG") == "true" {
-		logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: &programLevel}))
 	} else {
-		logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: &programLevel}))
 	}
 
 	// Set the log level based on the DEBUG environment variable
 	if os.Getenv("DEBUG") == "true" {
 		programLevel.Set(slog.LevelDebug)
 	}
+	logAggregate = os.Getenv(LogAggregateEnv) == "true"
+	// Metadata-only listing is disabled unless explicitly enabled
+	metadataOnlyList = os.Getenv(MetadataOnlyListEnv) == "true"
+	// Determine whether executions that timed out should be resumed where they left off rather than starting over
+	if os.Getenv(ListCheckpointingEnv) == "true" {
+		listCheckpoints = NewListCheckpoints()
+	}
+
+	// Parse the annotations from which the TTL may be read, in order of precedence
+	if os.Getenv(TTLAnnotationsEnv) != "" {
+		ttlAnnotations = strings.Split(os.Getenv(TTLAnnotationsEnv), ",")
+	}
+	// Parse the longest valid TTL first, since every TTL parsed from now on is checked against it
+	if os.Getenv(MaxValidTTLEnv) != "" {
+		var err error
+		if maxValidTTL, err = parseDuration(os.Getenv(MaxValidTTLEnv)); err != nil || maxValidTTL <= 0 {
+			panic(fmt.Sprintf("invalid %s '%s'", MaxValidTTLEnv, os.Getenv(MaxValidTTLEnv)))
+		}
+	}
 
 	// Parse the trackable resources from the environment
 	if os.Getenv(APIResourcesToWatchEnv) != "" {
 		apiResourcesToWatch = strings.Split(os.Getenv(APIResourcesToWatchEnv), ",")
 	}
+	if os.Getenv(WatchNamespaceEnv) != "" {
+		watchNamespaces = strings.Split(os.Getenv(WatchNamespaceEnv), ",")
+	}
+	apiGroupsToWatch = parseAPIGroups(os.Getenv(APIGroupsToWatchEnv))
+	preferredVersionOnly = os.Getenv(PreferredVersionOnlyEnv) == "true"
+
+	// Parse the number of delete retries allowed per execution. By default, failed deletions are not retried.
+	retryBudgetPerExecution = getEnvAsInt(RetryBudgetEnv, 0)
+	skipDeniedDeletionRetries = os.Getenv(SkipDeniedDeletionRetriesEnv) == "true"
+
+	// Parse the propagation policy to use when deleting resources, which may be overridden on a per-kind basis
+	if os.Getenv(PropagationPolicyEnv) != "" {
+		policy, err := parsePropagationPolicy(os.Getenv(PropagationPolicyEnv))
+		if err != nil {
+			panic(fmt.Sprintf("invalid %s: %s", PropagationPolicyEnv, err))
+		}
+		propagationPolicy = &policy
+	}
+	propagationPolicyByKind = make(map[string]metav1.DeletionPropagation)
+	for kind, value := range getEnvAsMap(PropagationPolicyByKindEnv) {
+		policy, err := parsePropagationPolicy(value)
+		if err != nil {
+			panic(fmt.Sprintf("invalid propagation policy for kind %s in %s: %s", kind, PropagationPolicyByKindEnv, err))
+		}
+		propagationPolicyByKind[kind] = policy
+	}
+
+	// Determine whether Pods should be evicted rather than deleted, which respects PodDisruptionBudgets
+	evictPods = os.Getenv(EvictPodsEnv) == "true"
+
+	// Determine whether resources without a TTL should inherit the TTL of their owners
+	inheritTTLFromOwner = os.Getenv(InheritTTLFromOwnerEnv) == "true"
+
+	// Parse the kinds of owners whose resources must never be deleted (e.g. resources managed by GitOps tools)
+	if os.Getenv(ExcludedOwnerKindsEnv) != "" {
+		excludedOwnerKinds = strings.Split(os.Getenv(ExcludedOwnerKindsEnv), ",")
+	}
+
+	// Determine whether resources whose owners no longer exist should be deleted, and after how long
+	reapOrphans = os.Getenv(ReapOrphansEnv) == "true"
+
+	// Parse the policies under which resources of a given kind without a TTL are purged, such as Pod=2d:Failed|Succeeded
+	purgePolicies = make(map[string]*PurgePolicy)
+	for kind, value := range getEnvAsMap(UnannotatedPurgeEnv) {
+		policy, err := ParsePurgePolicy(value)
+		if err != nil {
+			panic(fmt.Sprintf("invalid purge policy for kind %s in %s: %s", kind, UnannotatedPurgeEnv, err))
+		}
+		purgePolicies[kind] = policy
+	}
+	orphanGracePeriod = getEnvAsDuration(OrphanGracePeriodEnv, DefaultOrphanGracePeriod)
+
+	// Determine whether the deletion of a parent should trigger the deletion of its children
+	if os.Getenv(ReapCascadeEnv) == "true" {
+		cascadeTracker = NewCascadeTracker()
+	}
+
+	// Determine whether the PersistentVolumeClaims of expired StatefulSets should be deleted along with them
+	deleteStatefulSetPVCs = os.Getenv(DeleteStatefulSetPVCsEnv) == "true"
+
+	// Parse the labels that make resources undeletable, regardless of their annotations
+	protectedLabels = parseProtectedLabels(os.Getenv(ProtectedLabelsEnv))
+
+	// Parse the TTLs applied to resources with a given label that don't have a TTL annotation (e.g. labels set by CI systems)
+	var err error
+	if labelTTLs, err = parseLabelTTLs(os.Getenv(LabelTTLMapEnv)); err != nil {
+		panic(fmt.Sprintf("invalid %s: %s", LabelTTLMapEnv, err))
+	}
+
+	// Parse the kinds that are never deleted, which are safe by default unless explicitly allowed
+	protectedKinds = parseProtectedKinds(os.Getenv(ProtectedKindsEnv), os.Getenv(AllowDangerousKindsEnv))
+
+	// Parse the namespaces in which nothing is ever deleted. System namespaces are excluded unless the variable is set,
+	// in which case an empty value disables the exclusion altogether.
+	if value, exists := os.LookupEnv(NamespacesToExcludeEnv); exists {
+		namespacesToExclude = parseNamespacesToExclude(value)
+	}
 
+	// Determine whether Prometheus metrics should be exposed, and on which port
+	metricsEnabled = os.Getenv(MetricsEnabledEnv) == "true"
+	metricsPort = getEnvAsInt(MetricsPortEnv, DefaultMetricsPort)
+
+	// Determine whether failing to start the metrics server should be fatal, which it isn't by default so that
+	// observability problems don't prevent resources from being cleaned up
+	metricsStrict = os.Getenv(MetricsStrictEnv) == "true"
+
+	// Determine whether resources without any annotations should be reported, which helps confirm coverage
+	reportUnannotatedResources = os.Getenv(ReportUnannotatedResourcesEnv) == "true"
+
+	// Parse the NATS configuration used to publish deletion messages
+	natsURL = os.Getenv(NATSURLEnv)
+	natsSubject = os.Getenv(NATSSubjectEnv)
+	if natsSubject == "" {
+		natsSubject = DefaultNATSSubject
+	}
+
+	// Parse the ConfigMap in which the state of the controller is persisted across restarts, if any
+	stateConfigMap = os.Getenv(StateConfigMapEnv)
+
+	// Parse the ConfigMap from which the settings that can be changed without restarting the controller are reloaded
+	configConfigMap = os.Getenv(ConfigConfigMapEnv)
+
+	// Parse the webhook to archive resources to before deleting them. Unless explicitly disabled, resources that fail
+	// to be archived are not deleted.
+	if archiveWebhookURL := os.Getenv(ArchiveWebhookURLEnv); archiveWebhookURL != "" {
+		archiver = NewWebhookArchiver(archiveWebhookURL, os.Getenv(ArchiveFailureBlocksDeletionEnv) != "false")
+	}
+
+	// Parse the URL of the external check that must pass before resources are deleted
+	if externalCheckURL := os.Getenv(ExternalCheckURLEnv); externalCheckURL != "" {
+		var err error
+		if externalCheck, err = NewExternalCheck(externalCheckURL); err != nil {
+			panic(fmt.Sprintf("invalid %s: %s", ExternalCheckURLEnv, err))
+		}
+	}
+
+	// Parse the template of the message of the events created upon deletion
+	deletionMessageTemplate = parseDeletionMessageTemplate(os.Getenv(DeletionMessageTemplateEnv))
+
+	// Parse the duration after startup during which deletions are deferred
+	startupGracePeriod = getEnvAsDuration(StartupGracePeriodEnv, 0)
+
+	// Parse the wall-clock boundary the first execution should be aligned to (e.g. 5m to start on a multiple of 5 minutes)
+	reconcileAlign = getEnvAsDuration(ReconcileAlignEnv, 0)
+
+	// Parse the duration by which resources must have expired before being deleted, which protects against clock skew
+	clockSkewTolerance = getEnvAsDuration(ClockSkewToleranceEnv, 0)
+
+	// Determine whether resources with an invalid refreshed-at timestamp should be skipped
+	strictRefreshedAt = os.Getenv(StrictRefreshedAtEnv) == "true"
+
+	// Parse the strategy used to determine the time from which the TTL of a resource is calculated
+	startTimeStrategy = os.Getenv(StartTimeStrategyEnv)
+	if startTimeStrategy == "" {
+		startTimeStrategy = StartTimeStrategyPrecedence
+	} else if startTimeStrategy != StartTimeStrategyPrecedence && startTimeStrategy != StartTimeStrategyLatest {
+		panic(fmt.Sprintf("invalid %s '%s', must be either %s or %s", StartTimeStrategyEnv, startTimeStrategy, StartTimeStrategyPrecedence, StartTimeStrategyLatest))
+	}
+
+	// Parse the duration added to the expiration of every resource, which gives breathing room during incidents
+	globalTTLExtension = getEnvAsDuration(GlobalTTLExtensionEnv, 0)
+
+	// Parse the global deletion window, which may be overridden on a per-namespace basis
+	if os.Getenv(DeletionWindowEnv) != "" {
+		window, err := ParseDeletionWindow(os.Getenv(DeletionWindowEnv))
+		if err != nil {
+			panic(err)
+		}
+		deletionWindow = window
+	}
+
+	// Parse the maximum number of resources of each kind that a single owner may own
+	maxCountPerOwner = make(map[string]int)
+	for kind, value := range getEnvAsMap(MaxCountPerOwnerEnv) {
+		maxCount, err := strconv.Atoi(value)
+		if err != nil || maxCount < 0 {
+			panic(fmt.Sprintf("invalid maximum count '%s' for kind %s in %s", value, kind, MaxCountPerOwnerEnv))
+		}
+		maxCountPerOwner[kind] = maxCount
+	}
+
+	// Parse the maximum TTL of each kind, which caps the TTL of resources regardless of their annotations
+	maxTTLByKind = make(map[string]time.Duration)
+	for kind, value := range getEnvAsMap(MaxTTLByKindEnv) {
+		maxTTL, err := parseTTL(value)
+		if err != nil || maxTTL <= 0 {
+			panic(fmt.Sprintf("invalid maximum TTL '%s' for kind %s in %s", value, kind, MaxTTLByKindEnv))
+		}
+		maxTTLByKind[kind] = maxTTL
+	}
+
+	// Parse the interval at which specific resources are reconciled, if longer than the interval between executions
+	if intervals := getEnvAsMap(ResourceIntervalsEnv); len(intervals) > 0 {
+		parsedIntervals := make(map[string]time.Duration, len(intervals))
+		for resource, value := range intervals {
+			interval, err := parseTTL(value)
+			if err != nil || interval <= 0 {
+				panic(fmt.Sprintf("invalid interval '%s' for resource %s in %s", value, resource, ResourceIntervalsEnv))
+			}
+			parsedIntervals[resource] = interval
+		}
+		resourceIntervals = NewResourceIntervals(parsedIntervals)
+	}
+
+	// Load the deletion allowlist, if any
+	if os.Getenv(DeletionAllowlistFileEnv) != "" {
+		var err error
+		if allowlist, err = LoadAllowlist(os.Getenv(DeletionAllowlistFileEnv)); err != nil {
+			panic(fmt.Sprintf("failed to load %s: %s", DeletionAllowlistFileEnv, err))
+		}
+	}
+
+	// Parse the conditions that resources of a given kind must meet to be deleted. Because JSONPath expressions may
+	// contain commas, each kind=expression pair is separated by a semicolon.
+	deleteConditions = make(map[string]*DeleteCondition)
+	for kind, expression := range getEnvAsMapWithSeparator(DeleteConditionEnv, ";") {
+		condition, err := ParseDeleteCondition(expression)
+		if err != nil {
+			panic(fmt.Sprintf("invalid delete condition for kind %s in %s: %s", kind, DeleteConditionEnv, err))
+		}
+		deleteConditions[kind] = condition
+	}
+
+	// Parse the status condition reasons that resources of a given kind must have to be deleted. Since pairs are
+	// separated by commas, the reasons of a given kind are separated by a pipe.
+	deleteConditionReasons = make(map[string][]string)
+	for kind, reasons := range getEnvAsMap(DeleteConditionReasonEnv) {
+		for _, reason := range strings.Split(reasons, "|") {
+			if reason = strings.TrimSpace(reason); reason != "" {
+				deleteConditionReasons[kind] = append(deleteConditionReasons[kind], reason)
+			}
+		}
+		if len(deleteConditionReasons[kind]) == 0 {
+			panic(fmt.Sprintf("no condition reason for kind %s in %s", kind, DeleteConditionReasonEnv))
+		}
+	}
+
+	// Parse the status conditions that resources of a given kind must have been in for a minimum duration to be deleted,
+	// such as Job=Failed:30m
+	deleteConditionDurations = make(map[string]*ConditionDuration)
+	for kind, value := range getEnvAsMap(DeleteConditionDurationEnv) {
+		conditionDuration, err := ParseConditionDuration(value)
+		if err != nil {
+			panic(fmt.Sprintf("invalid condition duration for kind %s in %s: %s", kind, DeleteConditionDurationEnv, err))
+		}
+		deleteConditionDurations[kind] = conditionDuration
+	}
+
+	// Parse the maximum number of resources that may be deleted in a single namespace per execution
+	maxDeletionsPerNamespace = getEnvAsInt(MaxDeletionsPerNamespaceEnv, 0)
+
+	// Parse the size of the batches of deletions after which a checkpoint is logged, and how long to pause between them
+	deletionBatchSize = getEnvAsInt(DeletionBatchSizeEnv, 0)
+	deletionBatchPause = getEnvAsDuration(DeletionBatchPauseEnv, 0)
+
+	// Parse the maximum number of namespaces that may be deleted per execution, and how long to pause after each of them.
+	// Deleting a namespace is far more expensive than deleting any other resource, as every resource it contains must be
+	// deleted along with it.
+	maxNamespaceDeletionsPerExecution = getEnvAsInt(MaxNamespaceDeletionsPerRunEnv, 0)
+	namespaceDeletionThrottle = getEnvAsDuration(NamespaceDeletionThrottleEnv, 0)
+
+	// Determine whether resources in terminating namespaces should be skipped, which is the case unless explicitly disabled
+	skipTerminatingNamespaces = os.Getenv(SkipTerminatingNamespacesEnv) != "false"
+
+	// Parse the duration before expiry at which owners are warned that their resources are about to be deleted
+	preDeletionWarning = getEnvAsDuration(PreDeletionWarningEnv, 0)
+
+	// Parse the minimum interval between two updates of the last-evaluated-at annotation of resources
+	lastEvaluatedInterval = getEnvAsDuration(LastEvaluatedIntervalEnv, 0)
+
+	// Determine whether events should be created when the controller starts and stops, which is the case unless explicitly disabled
+	lifecycleEvents = os.Getenv(LifecycleEventsEnv) != "false"
+
+	// Parse the level of structured annotations on deletion events
+	structuredEvents = os.Getenv(StructuredEventsEnv)
+	if structuredEvents != "" && structuredEvents != StructuredEventsBasic && structuredEvents != StructuredEventsFull {
+		panic(fmt.Sprintf("invalid %s '%s', must be either %s or %s", StructuredEventsEnv, structuredEvents, StructuredEventsBasic, StructuredEventsFull))
+	}
+
+	// Determine whether the controller should only run once, and whether doing so without deleting anything is a failure
+	runOnce = os.Getenv(RunOnceEnv) == "true"
+	failIfNothingDeleted = os.Getenv(FailIfNothingDeletedEnv) == "true"
+
+	// Determine whether deletions should only be logged
+	dryRun = os.Getenv(DryRunEnv) == "true"
+	paused = os.Getenv(PausedEnv) == "true"
+
+	// Parse the format of the report printed at the end of the observe command
+	switch observeFormat = os.Getenv(ObserveFormatEnv); observeFormat {
+	case "":
+		observeFormat = ObserveFormatTable
+	case ObserveFormatTable, ObserveFormatKubectl:
+	default:
+		panic(fmt.Sprintf("invalid %s '%s', must be either %s or %s", ObserveFormatEnv, observeFormat, ObserveFormatTable, ObserveFormatKubectl))
+	}
+
+	// Determine whether resources should be watched rather than only being listed periodically
+	watchMode = os.Getenv(WatchModeEnv) == "true"
+
+	// Determine whether the memory footprint of the controller should be kept to a minimum
+	lowMemoryMode = os.Getenv(LowMemoryModeEnv) == "true"
+
+	// Open the destination of the summary written after each execution, if any
+	if destination := os.Getenv(ExecutionSummaryEnv); len(destination) > 0 {
+		var err error
+		if executionSummaryWriter, err = openExecutionSummaryWriter(destination); err != nil {
+			panic(fmt.Sprintf("failed to open %s destination '%s': %s", ExecutionSummaryEnv, destination, err))
+		}
+	}
+
+	// Parse the maximum number of deletions of resources expiring before the next execution that may be scheduled per
+	// execution. By default, such resources are deleted by the next execution.
+	maxScheduledDeletionsPerExecution = getEnvAsInt(MaxScheduledDeletionsPerExecutionEnv, 0)
+	if maxScheduledDeletionsPerExecution > 0 {
+		scheduler = NewDeletionScheduler(maxScheduledDeletionsPerExecution)
+	}
+
+	// Parse the maximum number of failed deletions to retry at the start of the next execution. By default, failed
+	// deletions are retried whenever the next execution reaches them.
+	maxRequeuedDeletions = getEnvAsInt(MaxRequeuedDeletionsEnv, 0)
+	if maxRequeuedDeletions > 0 {
+		requeue = NewDeletionRequeue(maxRequeuedDeletions)
+	}
+
+	// Parse the ratio of failed deletions from which deletions are halted for the rest of the execution, if any
+	if threshold := os.Getenv(DeletionFailureThresholdEnv); threshold != "" {
+		ratio, err := parsePercentage(threshold)
+		if err != nil || !(ratio > 0 && ratio <= 1) {
+			panic(fmt.Sprintf("invalid %s '%s', must be a percentage between 0%% and 100%%", DeletionFailureThresholdEnv, threshold))
+		}
+		circuitBreaker = NewCircuitBreaker(ratio, getEnvAsInt(DeletionFailureMinimumAttemptsEnv, DefaultCircuitBreakerMinimumAttempts))
+	}
+
+	// Parse the maximum percentage of the resources scanned during an execution that may be deleted, if any
+	if percent := os.Getenv(MaxDeletionPercentEnv); percent != "" {
+		ratio, err := parsePercentage(percent)
+		if err != nil || !(ratio > 0 && ratio <= 1) {
+			panic(fmt.Sprintf("invalid %s '%s', must be a percentage between 0%% and 100%%", MaxDeletionPercentEnv, percent))
+		}
+		deletionCeiling = NewDeletionCeiling(ratio)
+	}
+
+	// Configure the throttler, which may adapt to the API server's latency and add a random jitter to each sleep if enabled
+	throttler = NewThrottler(
+		os.Getenv(AdaptiveThrottleEnv) == "true",
+		getEnvAsDuration(AdaptiveThrottleMinimumEnv, ThrottleDuration),
+		getEnvAsDuration(AdaptiveThrottleMaximumEnv, DefaultAdaptiveThrottleMaximum),
+		getEnvAsDuration(AdaptiveThrottleLatencyThresholdEnv, DefaultAdaptiveThrottleLatencyThreshold),
+		getEnvAsDuration(ThrottleJitterEnv, 0),
+	)
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == AuditCommand {
+		// Auditing doesn't delete anything, so none of the servers or background processes are needed
+		os.Exit(runAudit(os.Stdout))
+	}
+	if len(os.Args) > 1 && os.Args[1] == ObserveCommand {
+		// Observing consists of a single execution in dry run, at the end of which a report is printed
+		observeMode, dryRun, runOnce = true, true, true
+	}
+	logEffectiveConfiguration()
+	// Servers must only be started once, and must be shut down gracefully when the controller is asked to terminate
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	var metricsServer *http.Server
+	if metricsEnabled {
+		var err error
+		if metricsServer, err = startMetricsServer(metricsPort); err != nil {
+			if metricsStrict {
+				panic(fmt.Sprintf("failed to start metrics server: %s", err))
+			}
+			logger.Error(fmt.Sprintf("Failed to start metrics server, continuing without metrics: %s", err))
+		}
+	}
+	if natsURL != "" {
+		natsPublisher, err := NewNATSPublisher(natsURL, natsSubject)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to create NATS publisher, deletion messages will not be published: %s", err))
+		} else {
+			publisher = natsPublisher
+		}
+	}
+	var lifecycleEventManager *kevent.EventManager
+	if lifecycleEvents && !runOnce {
+		if kubernetesClient, _, err := CreateClients(); err != nil {
+			logger.Error(fmt.Sprintf("Failed to create Kubernetes clients, lifecycle events will not be created: %s", err))
+		} else {
+			lifecycleEventManager = kevent.NewEventManager(kubernetesClient, "k8s-ttl-controller")
+			createControllerEvent(lifecycleEventManager, "Started", fmt.Sprintf("Controller started with configuration version %s", getConfigurationVersion()), false)
+		}
+	}
+	if structuredEvents != "" {
+		if kubernetesClient, _, err := CreateClients(); err != nil {
+			logger.Error(fmt.Sprintf("Failed to create Kubernetes clients, deletion events will not be structured: %s", err))
+		} else {
+			structuredEventRecorder = NewStructuredEventRecorder(kubernetesClient)
+		}
+	}
+	var stateStore *StateStore
+	if stateConfigMap != "" && !runOnce {
+		var err error
+		if stateStore, err = loadState(); err != nil {
+			// Persisting the state without having loaded it first would overwrite it
+			logger.Error(fmt.Sprintf("Failed to load state from ConfigMap %s, state will not be persisted: %s", stateConfigMap, err))
+		}
+	}
+	var configReloader *ConfigReloader
+	if configConfigMap != "" && !runOnce {
+		if kubernetesClient, _, err := CreateClients(); err != nil {
+			logger.Error(fmt.Sprintf("Failed to create Kubernetes clients, configuration will not be reloaded: %s", err))
+		} else if configReloader, err = NewConfigReloader(kubernetesClient, configConfigMap); err != nil {
+			panic(fmt.Sprintf("invalid %s: %s", ConfigConfigMapEnv, err))
+		}
+	}
+	if watchMode && len(watchNamespaces) > 0 {
+		// Informers are cluster-wide, which namespace-scoped permissions don't allow
+		logger.Info(fmt.Sprintf("%s is not supported with %s, resources will only be listed periodically", WatchModeEnv, WatchNamespaceEnv))
+	} else if watchMode && lowMemoryMode {
+		// Informers keep every watched resource in memory, which is precisely what low memory mode is meant to avoid
+		logger.Info(fmt.Sprintf("%s is not supported with %s, resources will only be listed periodically", WatchModeEnv, LowMemoryModeEnv))
+	} else if watchMode && !runOnce {
+		if err := startWatcher(); err != nil {
+			panic("failed to start watcher: " + err.Error())
+		}
+	}
+	if reconcileAlign > 0 && !runOnce {
+		delay := getAlignmentDelay(time.Now(), reconcileAlign)
+		logger.Info(fmt.Sprintf("Delaying the first execution by %s to align it to a %s boundary", delay.Round(time.Millisecond), reconcileAlign))
+		select {
+		case <-ctx.Done():
+			shutdown(metricsServer, lifecycleEventManager)
+			return
+		case <-time.After(delay):
+		}
+	}
+	var lastStart time.Time
 	for {
 		start := time.Now()
+		if !lastStart.IsZero() {
+			reconcileGapSeconds.Set(start.Sub(lastStart).Seconds())
+		}
+		lastStart = start
+		if configReloader != nil {
+			if err := configReloader.Reload(); err != nil {
+				logger.Error(fmt.Sprintf("Failed to reload configuration from ConfigMap %s, keeping the current configuration: %s", configConfigMap, err))
+			}
+		}
 		kubernetesClient, dynamicClient, err := CreateClients()
 		if err != nil {
 			panic("failed to create Kubernetes clients: " + err.Error())
 		}
+		if metadataOnlyList {
+			if metadataClient, err = CreateMetadataClient(); err != nil {
+				panic("failed to create Kubernetes metadata client: " + err.Error())
+			}
+		}
 		eventManager := kevent.NewEventManager(kubernetesClient, "k8s-ttl-controller")
-		if err = Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		result, err := Reconcile(kubernetesClient, dynamicClient, eventManager)
+		writeExecutionSummary(start, result, err)
+		if runOnce {
+			exitAfterSingleExecution(start, result, err)
+		}
+		if err != nil {
 			logger.Info(fmt.Sprintf("Error during execution: %s", err.Error()))
-			executionFailedCounter++
-			if executionFailedCounter > MaximumFailedExecutionBeforePanic {
-				panic(fmt.Errorf("execution failed %d times: %w", executionFailedCounter, err))
+			executionFailuresTotal.WithLabelValues(getErrorReason(err)).Inc()
+			// Transient errors, such as a blip in the availability of the API server, are more tolerated than
+			// persistent ones
+			if isTransientError(err) {
+				transientExecutionFailedCounter++
+				if transientExecutionFailedCounter > MaximumTransientFailedExecutionBeforePanic {
+					panic(fmt.Errorf("execution failed %d times due to transient errors: %w", transientExecutionFailedCounter, err))
+				}
+			} else {
+				executionFailedCounter++
+				consecutiveFailures.Set(float64(executionFailedCounter))
+				if executionFailedCounter > MaximumFailedExecutionBeforePanic {
+					panic(fmt.Errorf("execution failed %d times: %w", executionFailedCounter, err))
+				}
 			}
-		} else if executionFailedCounter > 0 {
-			logger.Info(fmt.Sprintf("Execution was successful after %d failed attempts, resetting counter to 0", executionFailedCounter))
+		} else if executionFailedCounter > 0 || transientExecutionFailedCounter > 0 {
+			logger.Info(fmt.Sprintf("Execution was successful after %d failed attempts, resetting counter to 0", executionFailedCounter+transientExecutionFailedCounter))
 			executionFailedCounter = 0
+			transientExecutionFailedCounter = 0
+			consecutiveFailures.Set(0)
+		}
+		var timeoutError *TimeoutError
+		if stateStore != nil && !errors.As(err, &timeoutError) {
+			// A timed out execution may still be running, so its state can't be captured safely
+			if err := stateStore.Save(captureState()); err != nil {
+				logger.Error(fmt.Sprintf("Failed to persist state to ConfigMap %s: %s", stateConfigMap, err))
+			}
 		}
 		logger.Info(fmt.Sprintf("Execution took %dms, sleeping for %s", time.Since(start).Milliseconds(), ExecutionInterval))
-		time.Sleep(ExecutionInterval)
+		select {
+		case <-ctx.Done():
+			shutdown(metricsServer, lifecycleEventManager)
+			return
+		case <-time.After(ExecutionInterval):
+		}
+	}
+}
+
+// shutdown gracefully stops the metrics server, if any, flushes the deletion messages that have yet to be published and
+// creates a lifecycle event if lifecycleEventManager is not nil
+func shutdown(metricsServer *http.Server, lifecycleEventManager *kevent.EventManager) {
+	logger.Info("Received termination signal, shutting down")
+	if metricsServer != nil {
+		shutdownMetricsServer(metricsServer)
+	}
+	if publisher != nil {
+		publisher.Close()
+	}
+	if lifecycleEventManager != nil {
+		createControllerEvent(lifecycleEventManager, "Stopped", fmt.Sprintf("Controller with configuration version %s shut down gracefully", getConfigurationVersion()), false)
+		time.Sleep(LifecycleEventFlushDelay)
+	}
+}
+
+// getAlignmentDelay returns the duration until the next wall-clock boundary that is a multiple of align, which is zero
+// if now is already on such a boundary
+func getAlignmentDelay(now time.Time, align time.Duration) time.Duration {
+	if align <= 0 {
+		return 0
+	}
+	truncated := now.Truncate(align)
+	if truncated.Equal(now) {
+		return 0
+	}
+	return truncated.Add(align).Sub(now)
+}
+
+// exitAfterSingleExecution terminates the process once the only execution of the run-once mode is over
+func exitAfterSingleExecution(start time.Time, result ExecutionResult, err error) {
+	if publisher != nil {
+		publisher.Close()
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("Execution failed after %dms: %s", time.Since(start).Milliseconds(), err.Error()))
+		os.Exit(getExitCode(result, err))
 	}
+	if observeMode {
+		printObservationReport(os.Stdout)
+		os.Exit(ExitCodeSuccess)
+	}
+	logger.Info(fmt.Sprintf("Execution took %dms and deleted %d resources (%d failed)", time.Since(start).Milliseconds(), result.Deleted, result.FailedDeletions))
+	exitCode := getExitCode(result, nil)
+	if exitCode == ExitCodeNothingDeleted {
+		logger.Error(fmt.Sprintf("No resources were deleted and %s is set to true, exiting with a non-zero code", FailIfNothingDeletedEnv))
+	}
+	os.Exit(exitCode)
+}
+
+// getExitCode returns the exit code that reflects the outcome of the only execution of the run-once mode
+func getExitCode(result ExecutionResult, err error) int {
+	if errors.Is(err, ErrListFailed) {
+		// The execution completed despite some resources failing to be listed, so its outcome is based on its result
+		err = nil
+	}
+	switch {
+	case errors.Is(err, ErrDiscoveryFailed):
+		return ExitCodeDiscoveryFailed
+	case errors.Is(err, ErrTimedOut):
+		return ExitCodeTimedOut
+	case err != nil:
+		return ExitCodeFailure
+	case result.FailedDeletions > 0:
+		return ExitCodeDeletionsFailed
+	case failIfNothingDeleted && result.Deleted == 0:
+		return ExitCodeNothingDeleted
+	default:
+		return ExitCodeSuccess
+	}
+}
+
+// startWatcher creates the watcher and starts watching all API resources that can be reconciled in the background
+func startWatcher() error {
+	kubernetesClient, dynamicClient, err := CreateClients()
+	if err != nil {
+		return err
+	}
+	groups, resources, err := kubernetesClient.Discovery().ServerGroupsAndResources()
+	if err != nil {
+		return err
+	}
+	if preferredVersionOnly {
+		resources = filterPreferredVersions(groups, resources)
+	}
+	resources = coalesceVersions(groups, resources)
+	var watcherMetadataClient metadata.Interface
+	if metadataOnlyList {
+		if watcherMetadataClient, err = CreateMetadataClient(); err != nil {
+			return err
+		}
+	}
+	watcher = NewWatcher(dynamicClient, watcherMetadataClient, kevent.NewEventManager(kubernetesClient, "k8s-ttl-controller"), resources)
+	watcher.Start(make(chan struct{}))
+	return nil
+}
+
+// ExecutionResult summarizes the outcome of an execution
+type ExecutionResult struct {
+	Scanned         int // Number of resources scanned
+	Deleted         int // Number of resources deleted
+	FailedDeletions int // Number of resources that failed to be deleted
+
+	DeletedPerResource map[string]int // Number of resources deleted, by resource.group
+
+	ListFailures map[schema.GroupVersionResource]error // Resources that could not be listed, excluding those that don't support listing
+
+	InaccessibleNamespaces []string // Namespaces from WatchNamespaceEnv in which at least one resource could not be listed due to RBAC
 }
 
 // Reconcile loops over all resources and deletes all sub resources that have expired
 //
-// Returns an error if an execution lasts for longer than ExecutionTimeout
-func Reconcile(kubernetesClient kubernetes.Interface, dynamicClient dynamic.Interface, eventManager *kevent.EventManager) error {
+// Returns a DiscoveryError if the API resources could not be retrieved, a TimeoutError if an execution lasts for
+// longer than ExecutionTimeout, and a PartialListError if the execution completed, but some resources could not be
+// listed.
+//
+// An execution that times out is cancelled, and stops as soon as it is done with the page of items it is reconciling.
+func Reconcile(kubernetesClient kubernetes.Interface, dynamicClient dynamic.Interface, eventManager *kevent.EventManager) (ExecutionResult, error) {
+	defer func(start time.Time) { reconcileDurationSeconds.Observe(time.Since(start).Seconds()) }(time.Now())
 	// Use Kubernetes' discovery API to retrieve all resources
-	_, resources, err := kubernetesClient.Discovery().ServerGroupsAndResources()
+	groups, resources, err := kubernetesClient.Discovery().ServerGroupsAndResources()
 	if err != nil {
-		return err
+		return ExecutionResult{}, &DiscoveryError{Err: err}
 	}
+	if preferredVersionOnly {
+		resources = filterPreferredVersions(groups, resources)
+	}
+	resources = coalesceVersions(groups, resources)
 	logger.Debug(fmt.Sprintf("[Reconcile] Found %d API resources", len(resources)))
-	timeout := make(chan bool, 1)
-	result := make(chan bool, 1)
-	go func() {
-		time.Sleep(ExecutionTimeout)
-		timeout <- true
-	}()
+	ctx, cancel := context.WithTimeout(context.Background(), executionTimeout)
+	defer cancel()
+	result := make(chan ExecutionResult, 1)
 	go func() {
-		result <- DoReconcile(dynamicClient, eventManager, resources)
+		result <- DoReconcile(ctx, dynamicClient, eventManager, resources)
 	}()
 	select {
-	case <-timeout:
-		return ErrTimedOut
-	case <-result:
-		return nil
+	case <-ctx.Done():
+		return ExecutionResult{}, &TimeoutError{Timeout: executionTimeout}
+	case executionResult := <-result:
+		if len(executionResult.ListFailures) > 0 {
+			return executionResult, &PartialListError{Failures: executionResult.ListFailures}
+		}
+		lastSuccessfulReconcileTimestampSeconds.SetToCurrentTime()
+		return executionResult, nil
 	}
 }
 
+// getStartTime returns the time from which the TTL of an item is calculated, which depends on startTimeStrategy:
+//   - StartTimeStrategyPrecedence: the value of AnnotationLastUsedAt if the item has a valid one, otherwise the value of
+//     AnnotationRefreshedAt if the item has a valid one, and its creation timestamp otherwise
+//   - StartTimeStrategyLatest: the most recent of the item's creation timestamp and the valid values of
+//     AnnotationLastUsedAt and AnnotationRefreshedAt
+//
+// If the item is annotated with AnnotationStartTTLWhenReady and is ready, the time at which it became ready is used in
+// place of its creation timestamp.
 func getStartTime(item unstructured.Unstructured) metav1.Time {
-	refreshedAt, exists := item.GetAnnotations()[AnnotationRefreshedAt]
-	if exists {
-		t, err := time.Parse(time.RFC3339, refreshedAt)
-		if err == nil {
-			return metav1.NewTime(t)
+	startTime := item.GetCreationTimestamp()
+	if startsTTLWhenReady(item) {
+		if readyAt, _, ready := getReadyTime(item); ready {
+			startTime = metav1.NewTime(readyAt)
+		}
+	}
+	// The annotations are in order of precedence
+	for _, annotation := range []string{AnnotationLastUsedAt, AnnotationRefreshedAt} {
+		timestamp, exists, err := getTimestampAnnotation(item, annotation)
+		if !exists {
+			continue
+		}
+		if err != nil {
+			logger.Info(fmt.Sprintf("Failed to parse %s timestamp '%s' for %s/%s: %s", annotation, item.GetAnnotations()[annotation], item.GetKind(), item.GetName(), err))
+			continue
+		}
+		if startTimeStrategy != StartTimeStrategyLatest {
+			return metav1.NewTime(timestamp)
+		}
+		if timestamp.After(startTime.Time) {
+			startTime = metav1.NewTime(timestamp)
+		}
+	}
+	return startTime
+}
+
+// getRefreshedAt parses the value of the AnnotationRefreshedAt annotation of an item, if it has one
+func getRefreshedAt(item unstructured.Unstructured) (time.Time, bool, error) {
+	return getTimestampAnnotation(item, AnnotationRefreshedAt)
+}
+
+// getTimestampAnnotation parses the value of an annotation of an item as an RFC3339 timestamp, if the item has it
+func getTimestampAnnotation(item unstructured.Unstructured, annotation string) (time.Time, bool, error) {
+	value, exists := item.GetAnnotations()[annotation]
+	if !exists {
+		return time.Time{}, false, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, true, err
+}
+
+// getDeleteAt parses the value of the AnnotationDeleteAt annotation of an item, if it has one.
+//
+// An item with a delete-at timestamp expires at that exact time, regardless of its TTL, start time or deletion jitter.
+func getDeleteAt(item unstructured.Unstructured) (time.Time, bool, error) {
+	return getTimestampAnnotation(item, AnnotationDeleteAt)
+}
+
+// getDeleteAtTTL returns the TTL equivalent to deleting an item at deleteAt, which is used to report the TTL of items
+// with a delete-at timestamp in logs and events
+func getDeleteAtTTL(item unstructured.Unstructured, deleteAt time.Time) string {
+	return deleteAt.Sub(item.GetCreationTimestamp().Time).Round(time.Second).String()
+}
+
+// hasInvalidRefreshedAt checks whether an item must be skipped because its AnnotationRefreshedAt annotation is invalid,
+// which is only the case in strict mode. Otherwise, the creation timestamp of the item is used instead.
+func hasInvalidRefreshedAt(item unstructured.Unstructured) (bool, error) {
+	if !strictRefreshedAt {
+		return false, nil
+	}
+	_, exists, err := getRefreshedAt(item)
+	return exists && err != nil, err
+}
+
+// getTTL returns the TTL of an item along with where it was read from, which is either a TTL annotation or, if the item
+// has none, a label mapped to a TTL through LabelTTLMapEnv
+func getTTL(item unstructured.Unstructured) (string, string, bool) {
+	if ttlAnnotation, ttl, exists := getTTLAnnotation(item.GetAnnotations()); exists {
+		return "annotation " + ttlAnnotation, ttl, true
+	}
+	if labelTTL, exists := getTTLFromLabels(item.GetLabels()); exists {
+		return "label " + labelTTL.String(), labelTTL.TTL, true
+	}
+	return "", "", false
+}
+
+// getTTLAnnotation returns the first annotation from ttlAnnotations that is present in the given annotations, along
+// with its value
+func getTTLAnnotation(annotations map[string]string) (string, string, bool) {
+	for _, annotation := range ttlAnnotations {
+		if ttl, exists := annotations[annotation]; exists {
+			return annotation, ttl, true
+		}
+	}
+	return "", "", false
+}
+
+// filterPreferredVersions filters out the API resources that are not served under the preferred version of their
+// group, so that the resources of groups serving multiple versions (e.g. CRDs with both v1 and v1beta1) are only
+// reconciled once
+func filterPreferredVersions(groups []*metav1.APIGroup, resources []*metav1.APIResourceList) []*metav1.APIResourceList {
+	preferredGroupVersions := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		preferredGroupVersions[group.PreferredVersion.GroupVersion] = true
+	}
+	filtered := make([]*metav1.APIResourceList, 0, len(resources))
+	for _, resource := range resources {
+		if preferredGroupVersions[resource.GroupVersion] {
+			filtered = append(filtered, resource)
+		} else {
+			logger.Debug(fmt.Sprintf("Skipping %s, because it is not the preferred version of its group", resource.GroupVersion))
+		}
+	}
+	return filtered
+}
+
+// coalesceVersions filters out the API resources that are also served under another version of their group, so that
+// each object is only reconciled once per execution even if it can be reached through multiple versions (e.g.
+// deployments served as both apps/v1 and apps/v1beta1).
+//
+// The preferred version of the group is kept if it serves the resource, and the version the API server lists first
+// otherwise. Unlike filterPreferredVersions, resources that are only served under a version other than the preferred
+// one are kept.
+func coalesceVersions(groups []*metav1.APIGroup, resources []*metav1.APIResourceList) []*metav1.APIResourceList {
+	// Rank the versions of each group, starting with the preferred version, then in the order listed by the API server
+	ranks := make(map[string]map[string]int, len(groups))
+	for _, group := range groups {
+		ranks[group.Name] = map[string]int{group.PreferredVersion.Version: 0}
+		for i, version := range group.Versions {
+			if _, exists := ranks[group.Name][version.Version]; !exists {
+				ranks[group.Name][version.Version] = i + 1
+			}
+		}
+	}
+	rank := func(gv schema.GroupVersion) int {
+		if r, exists := ranks[gv.Group][gv.Version]; exists {
+			return r
+		}
+		return len(ranks[gv.Group]) + 1
+	}
+	// Pick the version through which each resource is reconciled. Subresources follow the version of their resource.
+	chosen := make(map[schema.GroupResource]schema.GroupVersion)
+	for _, resource := range resources {
+		gv, err := schema.ParseGroupVersion(resource.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, apiResource := range resource.APIResources {
+			groupResource := schema.GroupResource{Group: gv.Group, Resource: strings.SplitN(apiResource.Name, "/", 2)[0]}
+			if current, exists := chosen[groupResource]; !exists || rank(gv) < rank(current) {
+				chosen[groupResource] = gv
+			}
+		}
+	}
+	coalesced := make([]*metav1.APIResourceList, 0, len(resources))
+	for _, resource := range resources {
+		gv, err := schema.ParseGroupVersion(resource.GroupVersion)
+		if err != nil {
+			coalesced = append(coalesced, resource)
+			continue
+		}
+		resourceCopy := *resource
+		resourceCopy.APIResources = make([]metav1.APIResource, 0, len(resource.APIResources))
+		for _, apiResource := range resource.APIResources {
+			groupResource := schema.GroupResource{Group: gv.Group, Resource: strings.SplitN(apiResource.Name, "/", 2)[0]}
+			if chosen[groupResource] != gv {
+				logger.Debug(fmt.Sprintf("Skipping %s from %s, because it is also served under %s", apiResource.Name, resource.GroupVersion, chosen[groupResource]))
+				continue
+			}
+			resourceCopy.APIResources = append(resourceCopy.APIResources, apiResource)
+		}
+		if len(resourceCopy.APIResources) > 0 {
+			coalesced = append(coalesced, &resourceCopy)
+		}
+	}
+	return coalesced
+}
+
+// parseGroupVersion parses the GroupVersion of an APIResourceList, which is either "version" for the core group (e.g.
+// v1) or "group/version" for every other group (e.g. apps/v1).
+//
+// Returns an error for anything else, such as an empty GroupVersion or one with more than one slash.
+func parseGroupVersion(groupVersion string) (schema.GroupVersion, error) {
+	parts := strings.Split(groupVersion, "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return schema.GroupVersion{Version: parts[0]}, nil
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return schema.GroupVersion{Group: parts[0], Version: parts[1]}, nil
+	case groupVersion == "":
+		return schema.GroupVersion{}, errors.New("empty GroupVersion")
+	default:
+		return schema.GroupVersion{}, fmt.Errorf("unexpected GroupVersion format '%s'", groupVersion)
+	}
+}
+
+// sortAPIResources returns a copy of the API resources sorted by group, version and resource, so that resources are
+// always reconciled in the same order regardless of the order in which discovery returned them
+func sortAPIResources(resources []*metav1.APIResourceList) []*metav1.APIResourceList {
+	sorted := make([]*metav1.APIResourceList, 0, len(resources))
+	for _, resource := range resources {
+		resourceCopy := *resource
+		resourceCopy.APIResources = append([]metav1.APIResource(nil), resource.APIResources...)
+		sort.SliceStable(resourceCopy.APIResources, func(i, j int) bool {
+			return resourceCopy.APIResources[i].Name < resourceCopy.APIResources[j].Name
+		})
+		sorted = append(sorted, &resourceCopy)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		gvi, _ := schema.ParseGroupVersion(sorted[i].GroupVersion)
+		gvj, _ := schema.ParseGroupVersion(sorted[j].GroupVersion)
+		if gvi.Group != gvj.Group {
+			return gvi.Group < gvj.Group
 		}
-		logger.Info("Failed to parse refreshed-at timestamp '%s' for %s/%s: %s", refreshedAt, item.GetKind(), item.GetName(), err)
+		return gvi.Version < gvj.Version
+	})
+	return sorted
+}
+
+// isReconcilable checks whether an API resource passes the filters configured and supports the verbs required to
+// reconcile it
+func isReconcilable(gvr schema.GroupVersionResource, apiResource metav1.APIResource) bool {
+	live := currentLiveConfiguration()
+	// Skip groups that are not in the list of trackable groups
+	if len(live.APIGroupsToWatch) != 0 && !contains(live.APIGroupsToWatch, gvr.Group) {
+		return false
 	}
-	return item.GetCreationTimestamp()
+	// Skip resources that are not in the list of trackable resources
+	if len(live.APIResourcesToWatch) != 0 && !contains(live.APIResourcesToWatch, apiResource.Name) {
+		return false
+	}
+	// Make sure that we can list and delete the resource. If we can't, then there's no point querying it.
+	verbs := apiResource.Verbs.String()
+	return strings.Contains(verbs, "list") && strings.Contains(verbs, "delete")
+}
+
+// setAnnotation sets an annotation on an item, initializing its annotations first if it has none.
+//
+// Items retrieved through the dynamic client may not have a metadata.annotations field at all, in which case writing
+// to the map returned by GetAnnotations would panic.
+func setAnnotation(item *unstructured.Unstructured, key, value string) {
+	annotations := item.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[key] = value
+	item.SetAnnotations(annotations)
 }
 
 func contains(slice []string, item string) bool {
@@ -135,83 +1268,606 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-// DoReconcile goes over all API resources specified, retrieves all sub resources and deletes those who have expired
-func DoReconcile(dynamicClient dynamic.Interface, eventManager *kevent.EventManager, resources []*metav1.APIResourceList) bool {
-	for _, resource := range resources {
+// DoReconcile goes over all API resources specified, retrieves all sub resources and deletes those who have expired.
+//
+// If the context is cancelled, no more pages of items are listed, and the execution stops as soon as it is done with
+// the current page.
+func DoReconcile(ctx context.Context, dynamicClient dynamic.Interface, eventManager *kevent.EventManager, resources []*metav1.APIResourceList) ExecutionResult {
+	ownerResolver := NewOwnerResolver(dynamicClient, resources)
+	namespaces := NewNamespaceCache(dynamicClient)
+	listFailures := make(map[schema.GroupVersionResource]error)
+	inaccessibleNamespaces := make(map[string]bool)
+	resumedListing := false   // Whether the listing of at least one resource was resumed, in which case not every item was listed
+	skippedResources := false // Whether at least one resource was skipped because it wasn't due, in which case not every item was listed
+	scanned := 0
+	// Resources whose items were all listed, which is needed to tell whether a tracked orphan still exists
+	listedResources := make(map[schema.GroupVersionResource]bool)
+	deletionMutex.Lock()
+	remainingRetryBudget = retryBudgetPerExecution
+	deletedResourcesInExecution = 0
+	failedDeletionsInExecution = 0
+	deletedResourcesPerNamespaceInExecution = make(map[string]int)
+	deletedResourcesPerResourceInExecution = make(map[string]int)
+	if circuitBreaker != nil {
+		circuitBreaker.NewExecution(eventManager)
+	}
+	if deletionCeiling != nil {
+		deletionCeiling.NewExecution()
+	}
+	deletionMutex.Unlock()
+	if scheduler != nil {
+		scheduler.NewExecution()
+	}
+	if cascadeTracker != nil {
+		cascadeTracker.NewExecution()
+	}
+	if extension := currentLiveConfiguration().GlobalTTLExtension; extension > 0 {
+		logger.Info(fmt.Sprintf("The TTL of every resource is extended by %s, because %s is set", extension, GlobalTTLExtensionEnv))
+	}
+	// Forget the items that have since expired, as no more warnings will be created for them
+	warnedBeforeDeletion.Range(func(key, expiresAt any) bool {
+		if time.Now().After(expiresAt.(time.Time)) {
+			warnedBeforeDeletion.Delete(key)
+		}
+		return true
+	})
+	// Retry the deletions that failed in the previous execution before anything else
+	var retried map[string]bool
+	if requeue != nil {
+		retried = requeue.Retry(dynamicClient, eventManager, namespaces)
+	}
+	for _, resource := range sortAPIResources(resources) {
+		if ctx.Err() != nil {
+			break
+		}
 		if len(resource.APIResources) == 0 {
 			continue
 		}
-		gv := strings.Split(resource.GroupVersion, "/")
-		gvr := schema.GroupVersionResource{}
-		if len(gv) == 2 {
-			gvr.Group = gv[0]
-			gvr.Version = gv[1]
-		} else if len(gv) == 1 {
-			gvr.Version = gv[0]
-		} else {
+		gv, err := parseGroupVersion(resource.GroupVersion)
+		if err != nil {
+			logger.Debug(fmt.Sprintf("Skipping the %d API resources of %s: %s", len(resource.APIResources), resource.GroupVersion, err))
 			continue
 		}
+		gvr := gv.WithResource("")
 		for _, apiResource := range resource.APIResources {
-			// Skip resources that are not in the list of trackable resources
-			if len(apiResourcesToWatch) != 0 && !contains(apiResourcesToWatch, apiResource.Name) {
-				continue
+			if ctx.Err() != nil {
+				break
 			}
-			// Make sure that we can list and delete the resource. If we can't, then there's no point querying it.
-			verbs := apiResource.Verbs.String()
-			if !strings.Contains(verbs, "list") || !strings.Contains(verbs, "delete") {
+			gvr.Resource = apiResource.Name
+			if !isReconcilable(gvr, apiResource) {
 				continue
 			}
+			if resourceIntervals != nil {
+				if !resourceIntervals.IsDue(gvr) {
+					logger.Debug(fmt.Sprintf("Skipping %s from %s, because it is not due to be reconciled yet", gvr.Resource, gvr.GroupVersion()))
+					skippedResources = true
+					continue
+				}
+				resourceIntervals.Reconciled(gvr, time.Now())
+			}
 			// List all items under the resource
-			gvr.Resource = apiResource.Name
 			var list *unstructured.UnstructuredList
 			var continueToken string
-			var ttlInDuration time.Duration
 			var err error
-			for list == nil || continueToken != "" {
-				list, err = dynamicClient.Resource(gvr).List(context.TODO(), metav1.ListOptions{TimeoutSeconds: &listTimeoutSeconds, Continue: continueToken, Limit: ListLimit})
-				if err != nil {
-					logger.Info(fmt.Sprintf("Error checking %s from %s: %s", gvr.Resource, gvr.GroupVersion(), err))
-					continue
-				}
-				if list != nil {
-					continueToken = list.GetContinue()
+			keepLastGroups := NewKeepLastGroups(apiResource.Name)
+			summary := NewResourceSummary(apiResource.Name)
+			maxCount, hasMaxCountPerOwner := maxCountPerOwner[apiResource.Kind]
+			ownerGroups := NewOwnerGroups()
+			scannedCounter := scannedTotal.WithLabelValues(gvr.Group, gvr.Version, gvr.Resource)
+			var cachedItems []unstructured.Unstructured
+			var isCached bool
+			if watcher != nil {
+				cachedItems, isCached = watcher.List(gvr)
+			}
+			listedCompletely := true
+			for _, namespace := range getNamespacesToList(apiResource) {
+				list, continueToken = nil, ""
+				if listCheckpoints != nil && !isCached {
+					if continueToken = listCheckpoints.Get(gvr, namespace); continueToken != "" {
+						logger.Info(fmt.Sprintf("Resuming the listing of %s from %s where the previous execution left off", gvr.Resource, gvr.GroupVersion()))
+						resumedListing = true
+						listedCompletely = false
+					}
 				}
-				logger.Debug(fmt.Sprintf("Checking %d %s from %s", len(list.Items), gvr.Resource, gvr.GroupVersion()))
-				for _, item := range list.Items {
-					ttl, exists := item.GetAnnotations()[AnnotationTTL]
-					if !exists {
+				for (list == nil || continueToken != "") && ctx.Err() == nil {
+					if isCached {
+						// The resource is watched, so its items can be retrieved from the watcher's cache instead
+						list = &unstructured.UnstructuredList{Items: cachedItems}
+					} else {
+						listStart := time.Now()
+						list, err = listItems(ctx, dynamicClient, gvr, apiResource, namespace, metav1.ListOptions{TimeoutSeconds: &listTimeoutSeconds, Continue: continueToken, Limit: getListLimit()})
+						throttler.Observe(time.Since(listStart))
+					}
+					if err != nil && listCheckpoints != nil && continueToken != "" && apierrors.IsResourceExpired(err) {
+						// Continue tokens expire after a few minutes, in which case there's no choice but to start over
+						logger.Info(fmt.Sprintf("The continue token of %s from %s has expired, listing it from the start", gvr.Resource, gvr.GroupVersion()))
+						listCheckpoints.Set(gvr, namespace, "")
+						list, continueToken = nil, ""
 						continue
 					}
-					ttlInDuration, err = str2duration.ParseDuration(ttl)
+					if err != nil && ctx.Err() != nil {
+						// The execution was cancelled while the items were being listed
+						break
+					}
 					if err != nil {
-						logger.Info(fmt.Sprintf("[%s/%s] has an invalid TTL '%s': %s", apiResource.Name, item.GetName(), ttl, err))
-						continue
+						listedCompletely = false
+						if apierrors.IsMethodNotSupported(err) {
+							// Some resources advertise the list verb, but don't actually support listing collections
+							unlistableResourcesTotal.WithLabelValues(gvr.Group, gvr.Version, gvr.Resource).Inc()
+							if _, known := unlistableResources.LoadOrStore(gvr, true); !known {
+								logger.Info(fmt.Sprintf("Skipping %s from %s, because listing it is not supported by the API server", gvr.Resource, gvr.GroupVersion()))
+							} else {
+								logger.Debug(fmt.Sprintf("Skipping %s from %s, because listing it is not supported by the API server", gvr.Resource, gvr.GroupVersion()))
+							}
+						} else if namespace != "" && apierrors.IsForbidden(err) {
+							// Permissions may be granted in some namespaces but not others, which shouldn't prevent the
+							// controller from cleaning up the namespaces it has access to
+							logger.Debug(fmt.Sprintf("Skipping %s from %s in namespace %s, because listing it is forbidden: %s", gvr.Resource, gvr.GroupVersion(), namespace, err))
+							inaccessibleNamespaces[namespace] = true
+						} else {
+							logger.Info(fmt.Sprintf("Error checking %s from %s: %s", gvr.Resource, gvr.GroupVersion(), err))
+							listFailures[gvr] = err
+						}
+						// Move on rather than retrying the same list request over and over
+						break
+					}
+					if list != nil {
+						continueToken = list.GetContinue()
 					}
-					ttlExpired := time.Now().After(getStartTime(item).Add(ttlInDuration))
-					if ttlExpired {
-						durationSinceExpired := time.Since(getStartTime(item).Add(ttlInDuration)).Round(time.Second)
-						logger.Info(fmt.Sprintf("[%s/%s] is configured with a TTL of %s, which means it has expired %s ago", apiResource.Name, item.GetName(), ttl, durationSinceExpired))
-						err = dynamicClient.Resource(gvr).Namespace(item.GetNamespace()).Delete(context.TODO(), item.GetName(), metav1.DeleteOptions{})
+					logger.Debug(fmt.Sprintf("Checking %d %s from %s", len(list.Items), gvr.Resource, gvr.GroupVersion()))
+					for _, item := range list.Items {
+						summary.Checked()
+						scanned++
+						scannedCounter.Inc()
+						if deletionCeiling != nil {
+							deletionCeiling.Scanned()
+						}
+						if cascadeTracker != nil {
+							cascadeTracker.Observe(gvr, item)
+						}
+						if reportUnannotatedResources && len(item.GetAnnotations()) == 0 {
+							logger.Debug(fmt.Sprintf("[%s/%s] has no annotations", apiResource.Name, item.GetName()))
+							unannotatedResourcesTotal.WithLabelValues(gvr.Group, gvr.Version, gvr.Resource).Inc()
+						}
+						if hasMaxCountPerOwner && item.GetDeletionTimestamp() == nil {
+							ownerGroups.Add(retainItem(item))
+						}
+						// The watcher deletes the items it handles as soon as they expire
+						handledByWatcher := isCached && watcher.Handles(gvr, item)
+						var hasStatus bool
+						if item, hasStatus = withStatus(ctx, dynamicClient, gvr, item); !hasStatus {
+							continue
+						}
+						if !hasTTL(item) && !isWaitingToBecomeReady(item) {
+							if reapOrphans && reapOrphan(dynamicClient, eventManager, namespaces, ownerResolver, gvr, item) {
+								continue
+							}
+							if purgeUnannotatedResource(dynamicClient, eventManager, namespaces, gvr, item) {
+								continue
+							}
+						}
+						expiration, err := resolveExpiration(ownerResolver, item)
 						if err != nil {
-							logger.Info(fmt.Sprintf("[%s/%s] failed to delete: %s", apiResource.Name, item.GetName(), err))
-							eventManager.Create(item.GetNamespace(), item.GetKind(), item.GetName(), "FailedToDeleteExpiredTTL", "Unable to delete expired resource:"+err.Error(), true)
-							// XXX: Should we retry with GracePeriodSeconds set to &0 to force immediate deletion after the first attempt failed?
+							var expirationErr *ExpirationError
+							if errors.As(err, &expirationErr) {
+								if expirationErr.Notable {
+									logger.Info(fmt.Sprintf("[%s/%s] %s", apiResource.Name, item.GetName(), expirationErr.Reason))
+								} else {
+									logger.Debug(fmt.Sprintf("[%s/%s] %s", apiResource.Name, item.GetName(), expirationErr.Reason))
+								}
+								if expirationErr.EventReason != "" {
+									eventManager.Create(item.GetNamespace(), item.GetKind(), item.GetName(), expirationErr.EventReason, expirationErr.EventMessage, true)
+								}
+							}
+							continue
+						}
+						logger.Debug(fmt.Sprintf("[%s/%s] TTL read from %s", apiResource.Name, item.GetName(), expiration.Source))
+						if expiration.RelativeToOwnerErr != nil {
+							logger.Info(fmt.Sprintf("[%s/%s] failed to resolve TTL relative to owner, falling back to its own TTL: %s", apiResource.Name, item.GetName(), expiration.RelativeToOwnerErr))
+						}
+						if expiration.UncappedTTL != "" {
+							logPerItem(fmt.Sprintf("[%s/%s] has a TTL of %s, which exceeds the maximum TTL of %s for %s; capping it", apiResource.Name, item.GetName(), expiration.UncappedTTL, expiration.TTL, item.GetKind()))
+						}
+						ttl, expiresAt := expiration.TTL, expiration.ExpiresAt
+						if isExpired(expiresAt) {
+							summary.Expired()
+							if handledByWatcher || retried[timerKey(gvr, item.GetNamespace()+"/"+item.GetName())] {
+								continue
+							}
+							if deletionTimestamp := item.GetDeletionTimestamp(); deletionTimestamp != nil {
+								// The resource is already being deleted (e.g. foreground deletion waiting on its dependents
+								// or finalizers), so there's no point in issuing another delete request
+								logger.Debug(fmt.Sprintf("[%s/%s] has expired, but its deletion has been in progress since %s", apiResource.Name, item.GetName(), deletionTimestamp.Format(time.RFC3339)))
+								continue
+							}
+							if keepLast, exists := item.GetAnnotations()[AnnotationKeepLast]; exists {
+								// The decision of whether to delete the item or not can only be made once all items have been listed
+								keepLastGroups.Add(retainItem(item), keepLast, ttl, expiresAt)
+								continue
+							}
+							if deleteExpiredResource(dynamicClient, eventManager, namespaces, gvr, item, ttl, expiresAt) {
+								ownerGroups.MarkDeleted(item)
+							}
 						} else {
-							logger.Info(fmt.Sprintf("[%s/%s] deleted", apiResource.Name, item.GetName()))
-							eventManager.Create(item.GetNamespace(), item.GetKind(), item.GetName(), "DeletedExpiredTTL", "Deleted resource because "+ttl+" or more has elapsed", false)
+							summary.Pending(expiresAt)
+							markEvaluated(ctx, dynamicClient, gvr, item)
+							if preDeletionWarning > 0 && time.Until(expiresAt) <= preDeletionWarning {
+								warnBeforeDeletion(eventManager, gvr, item, ttl, expiresAt)
+							}
+							if !handledByWatcher && scheduler != nil && !runOnce && time.Until(expiresAt) < ExecutionInterval && scheduler.Schedule(dynamicClient, eventManager, gvr, item) {
+								logPerItem(fmt.Sprintf("[%s/%s] is configured with a TTL of %s, which means it will expire in %s, before the next execution; its deletion has been scheduled", apiResource.Name, item.GetName(), ttl, time.Until(expiresAt).Round(time.Second)))
+							} else {
+								logPerItem(fmt.Sprintf("[%s/%s] is configured with a TTL of %s, which means it will expire in %s", apiResource.Name, item.GetName(), ttl, time.Until(expiresAt).Round(time.Second)))
+							}
 						}
-						// Cool off a tiny bit to avoid hitting the API too often
-						time.Sleep(ThrottleDuration)
-					} else {
-						logger.Info(fmt.Sprintf("[%s/%s] is configured with a TTL of %s, which means it will expire in %s", apiResource.Name, item.GetName(), ttl, time.Until(getStartTime(item).Add(ttlInDuration)).Round(time.Second)))
 					}
+					if listCheckpoints != nil && !isCached {
+						listCheckpoints.Set(gvr, namespace, continueToken)
+					}
+					// Cool off a tiny bit to avoid hitting the API too often
+					throttler.Sleep()
+				}
+			}
+			if listedCompletely && ctx.Err() == nil {
+				listedResources[gvr] = true
+			}
+			if logAggregate && summary.checked > 0 {
+				logger.Info(fmt.Sprintf("[%s] %s", gvr.GroupResource(), summary))
+			}
+			// Now that all items have been listed, delete the expired items that are not among the most recent ones
+			// of their keep-last group
+			for _, expired := range keepLastGroups.ResourcesToDelete() {
+				if deleteExpiredResource(dynamicClient, eventManager, namespaces, gvr, expired.item, expired.ttl, expired.expiresAt) {
+					ownerGroups.MarkDeleted(expired.item)
+				}
+			}
+			// Delete the oldest resources of owners that own more resources than allowed
+			if hasMaxCountPerOwner {
+				for _, item := range ownerGroups.ResourcesExceeding(maxCount) {
+					deleteResourceExceedingMaxCountPerOwner(dynamicClient, eventManager, namespaces, gvr, item, maxCount)
 				}
-				// Cool off a tiny bit to avoid hitting the API too often
-				time.Sleep(ThrottleDuration)
 			}
 			// Cool off a tiny bit to avoid hitting the API too often
-			time.Sleep(ThrottleDuration)
+			throttler.Sleep()
+		}
+	}
+	if ctx.Err() != nil {
+		logger.Info(fmt.Sprintf("Execution cancelled before every resource was reconciled: %s", ctx.Err()))
+	}
+	// Now that all resources have been listed, delete the children of the parents that no longer exist
+	if cascadeTracker != nil {
+		for _, child := range cascadeTracker.Finish(len(listFailures) == 0 && len(inaccessibleNamespaces) == 0 && !resumedListing && !skippedResources && ctx.Err() == nil, listedResources) {
+			deleteCascadedResource(dynamicClient, eventManager, namespaces, child.gvr, child.item, child.parent)
+		}
+	}
+	// Forget the orphans that no longer exist
+	orphanTracker.Finish(listedResources)
+	result := ExecutionResult{Scanned: scanned, ListFailures: listFailures}
+	if len(inaccessibleNamespaces) > 0 {
+		for namespace := range inaccessibleNamespaces {
+			result.InaccessibleNamespaces = append(result.InaccessibleNamespaces, namespace)
+		}
+		sort.Strings(result.InaccessibleNamespaces)
+		logger.Info(fmt.Sprintf("Some resources could not be listed due to insufficient permissions in %d namespaces: %s", len(result.InaccessibleNamespaces), strings.Join(result.InaccessibleNamespaces, ",")))
+	}
+	deletionMutex.Lock()
+	defer deletionMutex.Unlock()
+	result.Deleted, result.FailedDeletions = deletedResourcesInExecution, failedDeletionsInExecution
+	result.DeletedPerResource = make(map[string]int, len(deletedResourcesPerResourceInExecution))
+	for resource, deleted := range deletedResourcesPerResourceInExecution {
+		result.DeletedPerResource[resource] = deleted
+	}
+	return result
+}
+
+// getNamespacesToList returns the namespaces in which the items of a resource should be listed, where an empty
+// namespace means all namespaces.
+//
+// If the controller is restricted to some namespaces, namespaced resources are listed in each of them, while
+// cluster-scoped resources are not listed at all.
+func getNamespacesToList(apiResource metav1.APIResource) []string {
+	if len(watchNamespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	if !apiResource.Namespaced {
+		return nil
+	}
+	return watchNamespaces
+}
+
+// isDeletionAllowed checks whether an item may be deleted at this moment, logging the reason if it may not
+func isDeletionAllowed(namespaces *NamespaceCache, gvr schema.GroupVersionResource, item unstructured.Unstructured) bool {
+	if reason, level, excluded := getExclusionReason(gvr, item); excluded {
+		logger.Log(context.Background(), level, fmt.Sprintf("[%s/%s] will not be deleted, because %s", gvr.Resource, item.GetName(), reason))
+		return false
+	}
+	if currentLiveConfiguration().Paused {
+		logger.Info(fmt.Sprintf("[%s/%s] will not be deleted yet, because deletions are paused (see %s)", gvr.Resource, item.GetName(), PausedEnv))
+		return false
+	}
+	if circuitBreaker != nil && !circuitBreaker.Allow() {
+		logger.Debug(fmt.Sprintf("[%s/%s] will not be deleted yet, because deletions were halted after too many failures", gvr.Resource, item.GetName()))
+		return false
+	}
+	if deletionCeiling != nil && !deletionCeiling.Allow() {
+		logger.Debug(fmt.Sprintf("[%s/%s] will not be deleted yet, because the maximum percentage of resources that may be deleted per execution has been reached", gvr.Resource, item.GetName()))
+		return false
+	}
+	if remainingGracePeriod := startupGracePeriod - time.Since(startedAt); remainingGracePeriod > 0 {
+		logger.Info(fmt.Sprintf("[%s/%s] will not be deleted yet, because the controller is still within its startup grace period (%s remaining)", gvr.Resource, item.GetName(), remainingGracePeriod.Round(time.Second)))
+		return false
+	}
+	if window := getDeletionWindow(namespaces, item); window != nil && !window.Contains(time.Now()) {
+		logger.Info(fmt.Sprintf("[%s/%s] will not be deleted yet, because it is outside of the deletion window %s (UTC)", gvr.Resource, item.GetName(), window))
+		return false
+	}
+	if condition, exists := deleteConditions[item.GetKind()]; exists {
+		met, err := condition.IsMet(item)
+		if err != nil {
+			logger.Info(fmt.Sprintf("[%s/%s] will not be deleted, because its delete condition %s could not be evaluated: %s", gvr.Resource, item.GetName(), condition, err))
+			return false
+		}
+		if !met {
+			logger.Info(fmt.Sprintf("[%s/%s] will not be deleted yet, because its delete condition %s is not met", gvr.Resource, item.GetName(), condition))
+			return false
+		}
+	}
+	if reasons, exists := deleteConditionReasons[item.GetKind()]; exists {
+		if _, matched := getMatchingConditionReason(item, reasons); !matched {
+			logger.Info(fmt.Sprintf("[%s/%s] will not be deleted yet, because none of its status conditions has one of the reasons %s", gvr.Resource, item.GetName(), strings.Join(reasons, "|")))
+			return false
+		}
+	}
+	if conditionDuration, exists := deleteConditionDurations[item.GetKind()]; exists && !conditionDuration.IsMet(item) {
+		if since, inStatus := conditionDuration.Since(item); inStatus {
+			logger.Info(fmt.Sprintf("[%s/%s] will not be deleted yet, because its status condition %s has only held for %s out of %s", gvr.Resource, item.GetName(), conditionDuration.Condition(), since.Round(time.Second), conditionDuration.minimum))
+		} else {
+			logger.Info(fmt.Sprintf("[%s/%s] will not be deleted yet, because its status condition %s does not hold", gvr.Resource, item.GetName(), conditionDuration.Condition()))
+		}
+		return false
+	}
+	if skipTerminatingNamespaces && isNamespaceTerminating(namespaces, item.GetNamespace()) {
+		logger.Debug(fmt.Sprintf("[%s/%s] will not be deleted, because its namespace is terminating", gvr.Resource, item.GetName()))
+		return false
+	}
+	if locked, until := isNamespaceLocked(namespaces, item.GetNamespace()); locked {
+		if until.IsZero() {
+			logger.Info(fmt.Sprintf("[%s/%s] will not be deleted yet, because its namespace is locked", gvr.Resource, item.GetName()))
+		} else {
+			logger.Info(fmt.Sprintf("[%s/%s] will not be deleted yet, because its namespace is locked until %s", gvr.Resource, item.GetName(), until.Format(time.RFC3339)))
+		}
+		return false
+	}
+	if maxDeletionsPerNamespace > 0 && deletedResourcesPerNamespaceInExecution[item.GetNamespace()] >= maxDeletionsPerNamespace {
+		logger.Info(fmt.Sprintf("[%s/%s] will not be deleted yet, because %d resources have already been deleted from its namespace during this execution", gvr.Resource, item.GetName(), maxDeletionsPerNamespace))
+		return false
+	}
+	if maxNamespaceDeletionsPerExecution > 0 && isNamespace(gvr) && deletedResourcesPerResourceInExecution[gvr.GroupResource().String()] >= maxNamespaceDeletionsPerExecution {
+		logger.Info(fmt.Sprintf("[%s/%s] will not be deleted yet, because %d namespaces have already been deleted during this execution", gvr.Resource, item.GetName(), maxNamespaceDeletionsPerExecution))
+		return false
+	}
+	if externalCheck != nil {
+		if err := externalCheck.Check(gvr, item); err != nil {
+			logger.Info(fmt.Sprintf("[%s/%s] will not be deleted yet, because %s", gvr.Resource, item.GetName(), err))
+			return false
 		}
 	}
 	return true
 }
+
+// recordDeletion keeps track of a deleted item, both for the current execution and for the metrics
+func recordDeletion(gvr schema.GroupVersionResource, item unstructured.Unstructured) {
+	deletedResourcesInExecution++
+	deletedResourcesPerNamespaceInExecution[item.GetNamespace()]++
+	deletedResourcesPerResourceInExecution[gvr.GroupResource().String()]++
+	deletedTotal.WithLabelValues(item.GetNamespace()).Inc()
+	if namespaceDeletionThrottle > 0 && isNamespace(gvr) {
+		// Give the control plane time to delete the resources in the namespace before deleting anything else
+		logger.Debug(fmt.Sprintf("[%s/%s] Pausing for %s after deleting a namespace", gvr.Resource, item.GetName(), namespaceDeletionThrottle))
+		pauseDeletions(namespaceDeletionThrottle)
+	}
+	checkpointDeletionBatch()
+}
+
+// getExclusionReason returns the reason why an item is never deleted by the controller, regardless of when it expires,
+// if there is one, as well as the level at which said reason should be logged.
+//
+// Unlike the other checks made by isDeletionAllowed, these only depend on the item and the configuration, which is why
+// they are also used to report the status of resources.
+func getExclusionReason(gvr schema.GroupVersionResource, item unstructured.Unstructured) (string, slog.Level, bool) {
+	if reason, own := getOwnResourceReason(gvr, item); own {
+		return reason, slog.LevelInfo, true
+	}
+	if _, ttl, exists := getTTLAnnotation(item.GetAnnotations()); exists && isTTLDisabled(ttl) {
+		return fmt.Sprintf("its TTL is explicitly disabled with '%s'", ttl), slog.LevelDebug, true
+	}
+	if contains(protectedKinds, item.GetKind()) {
+		return fmt.Sprintf("%s is a protected kind (see %s)", item.GetKind(), AllowDangerousKindsEnv), slog.LevelInfo, true
+	}
+	if ownerKind, excluded := getExcludedOwnerKind(item); excluded {
+		return fmt.Sprintf("it is owned by a %s", ownerKind), slog.LevelDebug, true
+	}
+	if label, protected := getProtectingLabel(item); protected {
+		return fmt.Sprintf("it has the protected label %s", label), slog.LevelInfo, true
+	}
+	if namespace, excluded := getExcludedNamespace(gvr, item); excluded {
+		return fmt.Sprintf("the namespace %s is excluded (see %s)", namespace, NamespacesToExcludeEnv), slog.LevelDebug, true
+	}
+	if allowlist != nil && !allowlist.Allows(gvr.Resource, item) {
+		return fmt.Sprintf("it does not match any entry of the allowlist (see %s)", DeletionAllowlistFileEnv), slog.LevelDebug, true
+	}
+	return "", 0, false
+}
+
+// pauseDeletions pauses deletions for a given duration, unless they are already paused for longer.
+//
+// Rather than sleeping while holding the deletionMutex, which would also block the watcher, the pause is only waited
+// for by the next deletion through waitForDeletionPause.
+//
+// Must be called with the deletionMutex held.
+func pauseDeletions(duration time.Duration) {
+	if pausedUntil := time.Now().Add(duration); pausedUntil.After(deletionsPausedUntil) {
+		deletionsPausedUntil = pausedUntil
+	}
+}
+
+// waitForDeletionPause sleeps until deletions are no longer paused, if they are.
+//
+// Must be called before acquiring the deletionMutex.
+func waitForDeletionPause() {
+	deletionMutex.Lock()
+	pause := time.Until(deletionsPausedUntil)
+	deletionMutex.Unlock()
+	if pause > 0 {
+		time.Sleep(pause)
+	}
+}
+
+// throttleAfterDeletion cools off a tiny bit after a deletion was attempted to avoid hitting the API too often.
+//
+// It is meant to be deferred before acquiring the deletionMutex, so that it only sleeps once the mutex is released,
+// rather than blocking the watcher and every other deletion for the duration of the throttle.
+func throttleAfterDeletion(attempted *bool) {
+	if *attempted {
+		throttler.Sleep()
+	}
+}
+
+// recordFailedDeletion keeps track of an item that failed to be deleted, both for the current execution and for the
+// metrics
+func recordFailedDeletion(item unstructured.Unstructured) {
+	failedDeletionsInExecution++
+	deletionFailuresTotal.WithLabelValues(item.GetNamespace()).Inc()
+}
+
+// checkpointDeletionBatch logs a checkpoint every deletionBatchSize deletions, and pauses deletions for
+// deletionBatchPause afterward if configured, which provides progress markers and pacing for mass cleanups.
+//
+// Must be called with the deletionMutex held.
+func checkpointDeletionBatch() {
+	if deletionBatchSize <= 0 || deletedResourcesInExecution%deletionBatchSize != 0 {
+		return
+	}
+	logger.Info(fmt.Sprintf("[Checkpoint] Batch #%d completed, %d resources deleted so far during this execution", deletedResourcesInExecution/deletionBatchSize, deletedResourcesInExecution))
+	if deletionBatchPause > 0 {
+		logger.Debug(fmt.Sprintf("[Checkpoint] Pausing for %s before the next batch", deletionBatchPause))
+		pauseDeletions(deletionBatchPause)
+	}
+}
+
+// warnBeforeDeletion creates a warning event for an item that is about to expire, unless one was already created for it
+func warnBeforeDeletion(eventManager *kevent.EventManager, gvr schema.GroupVersionResource, item unstructured.Unstructured, ttl string, expiresAt time.Time) {
+	key := gvr.String() + "|" + item.GetNamespace() + "/" + item.GetName()
+	if _, warned := warnedBeforeDeletion.LoadOrStore(key, expiresAt); warned {
+		return
+	}
+	logger.Info(fmt.Sprintf("[%s/%s] will expire in %s, creating a warning event", gvr.Resource, item.GetName(), time.Until(expiresAt).Round(time.Second)))
+	eventManager.Create(item.GetNamespace(), item.GetKind(), item.GetName(), "TTLExpiringSoon", fmt.Sprintf("Resource will be deleted in %s, because it is configured with a TTL of %s", time.Until(expiresAt).Round(time.Second), ttl), true)
+}
+
+// deleteExpiredResource deletes an item whose TTL has expired and creates an event reflecting the outcome
+//
+// Returns whether the item was deleted, or would have been if dry run is enabled
+func deleteExpiredResource(dynamicClient dynamic.Interface, eventManager *kevent.EventManager, namespaces *NamespaceCache, gvr schema.GroupVersionResource, item unstructured.Unstructured, ttl string, expiresAt time.Time) bool {
+	waitForDeletionPause()
+	var attempted, deletePersistentVolumeClaims bool
+	defer func() {
+		// The persistent volume claims are deleted once the deletionMutex is released, since they're deleted one by one
+		if deletePersistentVolumeClaims {
+			deleteStatefulSetPersistentVolumeClaims(dynamicClient, eventManager, namespaces, item)
+		}
+	}()
+	defer throttleAfterDeletion(&attempted)
+	deletionMutex.Lock()
+	defer deletionMutex.Unlock()
+	logger.Info(fmt.Sprintf("[%s/%s] is configured with a TTL of %s, which means it has expired %s ago", gvr.Resource, item.GetName(), ttl, time.Since(expiresAt).Round(time.Second)))
+	if !isDeletionAllowed(namespaces, gvr, item) {
+		return false
+	}
+	deleted, err := NewDeleter(dynamicClient).Delete(gvr, item, fmt.Sprintf("TTL of %s expired %s ago", ttl, time.Since(expiresAt).Round(time.Second)))
+	if err == nil && !deleted {
+		// The deletion was only simulated (e.g. dry run)
+		return true
+	}
+	if denial, denied := getAdmissionDenial(err); denied {
+		logger.Info(fmt.Sprintf("[%s/%s] failed to delete, because the deletion was denied by admission control: %s", gvr.Resource, item.GetName(), denial))
+		recordFailedDeletion(item)
+		createDeletionEvent(eventManager, gvr, item, "DeletionDeniedByAdmission", "Deletion of expired resource denied by admission control: "+denial, true, ttl, expiresAt)
+		if requeue != nil && isRetryableDeletionError(err) {
+			requeue.Add(gvr, item)
+		}
+	} else if err != nil {
+		logger.Info(fmt.Sprintf("[%s/%s] failed to delete: %s", gvr.Resource, item.GetName(), err))
+		recordFailedDeletion(item)
+		createDeletionEvent(eventManager, gvr, item, "FailedToDeleteExpiredTTL", "Unable to delete expired resource:"+err.Error(), true, ttl, expiresAt)
+		if requeue != nil {
+			requeue.Add(gvr, item)
+		}
+		// XXX: Should we retry with GracePeriodSeconds set to &0 to force immediate deletion after the first attempt failed?
+	} else {
+		message := getDeletionMessage(gvr.Resource, item, ttl, expiresAt)
+		if deletionMessageTemplate != nil {
+			logger.Info(fmt.Sprintf("[%s/%s] deleted (uid=%s, resourceVersion=%s): %s", gvr.Resource, item.GetName(), item.GetUID(), item.GetResourceVersion(), message))
+		} else {
+			logger.Info(fmt.Sprintf("[%s/%s] deleted (uid=%s, resourceVersion=%s)", gvr.Resource, item.GetName(), item.GetUID(), item.GetResourceVersion()))
+		}
+		createDeletionEvent(eventManager, gvr, item, "DeletedExpiredTTL", message, false, ttl, expiresAt)
+		recordDeletion(gvr, item)
+		publishDeletion(gvr, item, ttl)
+		deletePersistentVolumeClaims = deleteStatefulSetPVCs && isStatefulSet(gvr)
+	}
+	attempted = true
+	return err == nil
+}
+
+// deleteResourceExceedingMaxCountPerOwner deletes an item whose owner owns more resources of the same kind than
+// allowed by maxCountPerOwner and creates an event reflecting the outcome
+//
+// Returns whether the item was deleted, or would have been if dry run is enabled
+func deleteResourceExceedingMaxCountPerOwner(dynamicClient dynamic.Interface, eventManager *kevent.EventManager, namespaces *NamespaceCache, gvr schema.GroupVersionResource, item unstructured.Unstructured, maxCount int) bool {
+	waitForDeletionPause()
+	var attempted bool
+	defer throttleAfterDeletion(&attempted)
+	deletionMutex.Lock()
+	defer deletionMutex.Unlock()
+	logger.Info(fmt.Sprintf("[%s/%s] is among the oldest resources of an owner that has more than %d %s", gvr.Resource, item.GetName(), maxCount, gvr.Resource))
+	if !isDeletionAllowed(namespaces, gvr, item) {
+		return false
+	}
+	deleted, err := NewDeleter(dynamicClient).Delete(gvr, item, fmt.Sprintf("owner has more than %d %s", maxCount, gvr.Resource))
+	if err == nil && !deleted {
+		// The deletion was only simulated (e.g. dry run)
+		return true
+	}
+	if err != nil {
+		logger.Info(fmt.Sprintf("[%s/%s] failed to delete: %s", gvr.Resource, item.GetName(), err))
+		recordFailedDeletion(item)
+		createDeletionEvent(eventManager, gvr, item, "FailedToDeleteExceedingMaxCountPerOwner", "Unable to delete resource exceeding the maximum count per owner:"+err.Error(), true, "", time.Time{})
+	} else {
+		logger.Info(fmt.Sprintf("[%s/%s] deleted (uid=%s, resourceVersion=%s)", gvr.Resource, item.GetName(), item.GetUID(), item.GetResourceVersion()))
+		createDeletionEvent(eventManager, gvr, item, "DeletedExceedingMaxCountPerOwner", fmt.Sprintf("Deleted resource because its owner has more than %d %s (uid=%s, resourceVersion=%s)", maxCount, gvr.Resource, item.GetUID(), item.GetResourceVersion()), false, "", time.Time{})
+		recordDeletion(gvr, item)
+		publishDeletion(gvr, item, "")
+	}
+	attempted = true
+	return err == nil
+}
+
+// parsePropagationPolicy parses a deletion propagation policy, which must be one of Foreground, Background or Orphan
+func parsePropagationPolicy(value string) (metav1.DeletionPropagation, error) {
+	switch policy := metav1.DeletionPropagation(value); policy {
+	case metav1.DeletePropagationForeground, metav1.DeletePropagationBackground, metav1.DeletePropagationOrphan:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("'%s' is not one of %s, %s or %s", value, metav1.DeletePropagationForeground, metav1.DeletePropagationBackground, metav1.DeletePropagationOrphan)
+	}
+}
+
+// getPropagationPolicy returns the propagation policy to use when deleting a resource of the given kind, or nil if the
+// API server's default should be used
+func getPropagationPolicy(kind string) *metav1.DeletionPropagation {
+	if policy, exists := propagationPolicyByKind[kind]; exists {
+		return &policy
+	}
+	return propagationPolicy
+}
@@ -1,20 +1,37 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	goruntime "runtime"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/TwiN/kevent"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/dynamic"
 	fakedynamic "k8s.io/client-go/dynamic/fake"
 	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/metadata"
+	fakemetadata "k8s.io/client-go/metadata/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestReconcile(t *testing.T) {
@@ -41,6 +58,17 @@ func TestReconcile(t *testing.T) {
 			},
 			expectedResourcesLeftAfterReconciliation: 0,
 		},
+		{
+			name: "pod-with-disabled-ttl-is-not-deleted",
+			podsToCreate: []*unstructured.Unstructured{
+				newUnstructuredWithAnnotations("v1", "Pod", "default", "never-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "never"}),
+				newUnstructuredWithAnnotations("v1", "Pod", "default", "disabled-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "Disabled"}),
+				newUnstructuredWithAnnotations("v1", "Pod", "default", "zero-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "0"}),
+				newUnstructuredWithAnnotations("v1", "Pod", "default", "zero-seconds-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "0s"}),
+				newUnstructuredWithAnnotations("v1", "Pod", "default", "zero-iso8601-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "PT0S"}),
+			},
+			expectedResourcesLeftAfterReconciliation: 5,
+		},
 		{
 			name: "not-expired-pod-is-not-deleted",
 			podsToCreate: []*unstructured.Unstructured{
@@ -128,6 +156,31 @@ func TestReconcile(t *testing.T) {
 			},
 			expectedResourcesLeftAfterReconciliation: 2,
 		},
+		{
+			name: "expired-pod-already-being-deleted-is-not-deleted-again",
+			podsToCreate: []*unstructured.Unstructured{
+				withDeletionTimestamp(newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}), time.Now().Add(-time.Minute)),
+			},
+			expectedResourcesLeftAfterReconciliation: 1,
+		},
+		{
+			name: "keep-last-exempts-most-recent-expired-pods",
+			podsToCreate: []*unstructured.Unstructured{
+				newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name-1", time.Now().Add(-3*time.Hour), map[string]interface{}{AnnotationTTL: "5m", AnnotationKeepLast: "2"}),
+				newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name-2", time.Now().Add(-2*time.Hour), map[string]interface{}{AnnotationTTL: "5m", AnnotationKeepLast: "2"}),
+				newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name-3", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m", AnnotationKeepLast: "2"}),
+				newUnstructuredWithAnnotations("v1", "Pod", "default", "not-expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "3d", AnnotationKeepLast: "2"}),
+			},
+			expectedResourcesLeftAfterReconciliation: 3,
+		},
+		{
+			name: "keep-last-of-zero-deletes-all-expired-pods",
+			podsToCreate: []*unstructured.Unstructured{
+				newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name-1", time.Now().Add(-2*time.Hour), map[string]interface{}{AnnotationTTL: "5m", AnnotationKeepLast: "0"}),
+				newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name-2", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m", AnnotationKeepLast: "0"}),
+			},
+			expectedResourcesLeftAfterReconciliation: 0,
+		},
 	}
 
 	// Run scenarios
@@ -168,7 +221,7 @@ func TestReconcile(t *testing.T) {
 				t.Errorf("expected 3 resources, got %d", len(list.Items))
 			}
 			// Reconcile once
-			if err = Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+			if _, err = Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
 				t.Errorf("unexpected error: %v", err)
 			}
 			// Make sure that the expired resources have been deleted
@@ -179,6 +232,9 @@ func TestReconcile(t *testing.T) {
 			if len(list.Items) != scenario.expectedResourcesLeftAfterReconciliation {
 				t.Errorf("expected 3 resources, got %d", len(list.Items))
 			}
+			if expectedDeletions := len(scenario.podsToCreate) - scenario.expectedResourcesLeftAfterReconciliation; deletedResourcesInExecution != expectedDeletions {
+				t.Errorf("expected %d deletions to have been counted, got %d", expectedDeletions, deletedResourcesInExecution)
+			}
 		})
 	}
 }
@@ -197,3 +253,2198 @@ func newUnstructuredWithAnnotations(apiVersion, kind, namespace, name string, cr
 		},
 	}
 }
+
+func TestReconcile_withRefreshedAt(t *testing.T) {
+	scenarios := []struct {
+		name            string
+		createdAgo      time.Duration
+		refreshedAgo    time.Duration
+		ttl             string
+		expectedDeleted bool
+	}{
+		{name: "old-but-recently-refreshed", createdAgo: 72 * time.Hour, refreshedAgo: 10 * time.Minute, ttl: "1h", expectedDeleted: false},
+		{name: "old-and-stale-refresh", createdAgo: 72 * time.Hour, refreshedAgo: 2 * time.Hour, ttl: "1h", expectedDeleted: true},
+		{name: "refreshed-before-creation", createdAgo: 10 * time.Minute, refreshedAgo: 72 * time.Hour, ttl: "1h", expectedDeleted: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+			// Timestamps are hours away from the TTL boundary, so the outcome doesn't depend on how long the test takes
+			pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now().Add(-scenario.createdAgo), map[string]interface{}{
+				AnnotationTTL:         scenario.ttl,
+				AnnotationRefreshedAt: time.Now().Add(-scenario.refreshedAgo).UTC().Format(time.RFC3339),
+			})
+			if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if deleted := !listNames(t, dynamicClient, podsGVR, "default")["pod-name"]; deleted != scenario.expectedDeleted {
+				t.Errorf("expected deleted=%t, got %t", scenario.expectedDeleted, deleted)
+			}
+		})
+	}
+}
+
+func TestReconcile_withDeleteAt(t *testing.T) {
+	scenarios := []struct {
+		name            string
+		createdAgo      time.Duration
+		annotations     map[string]interface{}
+		expectedDeleted bool
+	}{
+		{
+			name:            "past",
+			createdAgo:      10 * time.Minute,
+			annotations:     map[string]interface{}{AnnotationDeleteAt: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)},
+			expectedDeleted: true,
+		},
+		{
+			name:            "future",
+			createdAgo:      72 * time.Hour,
+			annotations:     map[string]interface{}{AnnotationDeleteAt: time.Now().Add(time.Hour).UTC().Format(time.RFC3339)},
+			expectedDeleted: false,
+		},
+		{
+			name:            "future-with-expired-ttl",
+			createdAgo:      72 * time.Hour,
+			annotations:     map[string]interface{}{AnnotationTTL: "1h", AnnotationDeleteAt: time.Now().Add(time.Hour).UTC().Format(time.RFC3339)},
+			expectedDeleted: false,
+		},
+		{
+			name:            "past-with-pending-ttl",
+			createdAgo:      10 * time.Minute,
+			annotations:     map[string]interface{}{AnnotationTTL: "1d", AnnotationDeleteAt: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)},
+			expectedDeleted: true,
+		},
+		{
+			name:       "past-with-recent-refresh",
+			createdAgo: 72 * time.Hour,
+			annotations: map[string]interface{}{
+				AnnotationTTL:         "1h",
+				AnnotationRefreshedAt: time.Now().UTC().Format(time.RFC3339),
+				AnnotationDeleteAt:    time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+			},
+			expectedDeleted: true,
+		},
+		{
+			name:            "invalid",
+			createdAgo:      72 * time.Hour,
+			annotations:     map[string]interface{}{AnnotationTTL: "1h", AnnotationDeleteAt: "tomorrow"},
+			expectedDeleted: false,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+			pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now().Add(-scenario.createdAgo), scenario.annotations)
+			if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if deleted := !listNames(t, dynamicClient, podsGVR, "default")["pod-name"]; deleted != scenario.expectedDeleted {
+				t.Errorf("expected deleted=%t, got %t", scenario.expectedDeleted, deleted)
+			}
+		})
+	}
+}
+
+func TestGetStartTime(t *testing.T) {
+	defer func(previous string) { startTimeStrategy = previous }(startTimeStrategy)
+	creationTimestamp := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	scenarios := []struct {
+		name              string
+		strategy          string
+		refreshedAt       string
+		lastUsedAt        string
+		expectedStartTime time.Time
+	}{
+		{name: "not-refreshed", strategy: StartTimeStrategyPrecedence, expectedStartTime: creationTimestamp},
+		{name: "refreshed", strategy: StartTimeStrategyPrecedence, refreshedAt: "2026-01-05T12:00:00Z", expectedStartTime: time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)},
+		{name: "invalid-refreshed-at", strategy: StartTimeStrategyPrecedence, refreshedAt: "yesterday", expectedStartTime: creationTimestamp},
+		{name: "last-used-before-refreshed", strategy: StartTimeStrategyPrecedence, refreshedAt: "2026-01-05T12:00:00Z", lastUsedAt: "2026-01-03T12:00:00Z", expectedStartTime: time.Date(2026, 1, 3, 12, 0, 0, 0, time.UTC)},
+		{name: "invalid-last-used-at", strategy: StartTimeStrategyPrecedence, refreshedAt: "2026-01-05T12:00:00Z", lastUsedAt: "yesterday", expectedStartTime: time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)},
+		{name: "refreshed-before-creation", strategy: StartTimeStrategyPrecedence, refreshedAt: "2025-12-25T00:00:00Z", expectedStartTime: time.Date(2025, 12, 25, 0, 0, 0, 0, time.UTC)},
+		{name: "latest-not-refreshed", strategy: StartTimeStrategyLatest, expectedStartTime: creationTimestamp},
+		{name: "latest-last-used-before-refreshed", strategy: StartTimeStrategyLatest, refreshedAt: "2026-01-05T12:00:00Z", lastUsedAt: "2026-01-03T12:00:00Z", expectedStartTime: time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)},
+		{name: "latest-last-used-after-refreshed", strategy: StartTimeStrategyLatest, refreshedAt: "2026-01-05T12:00:00Z", lastUsedAt: "2026-01-07T12:00:00Z", expectedStartTime: time.Date(2026, 1, 7, 12, 0, 0, 0, time.UTC)},
+		{name: "latest-refreshed-before-creation", strategy: StartTimeStrategyLatest, refreshedAt: "2025-12-25T00:00:00Z", expectedStartTime: creationTimestamp},
+		{name: "latest-invalid-last-used-at", strategy: StartTimeStrategyLatest, lastUsedAt: "yesterday", expectedStartTime: creationTimestamp},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			startTimeStrategy = scenario.strategy
+			annotations := map[string]interface{}{AnnotationTTL: "1d"}
+			if scenario.refreshedAt != "" {
+				annotations[AnnotationRefreshedAt] = scenario.refreshedAt
+			}
+			if scenario.lastUsedAt != "" {
+				annotations[AnnotationLastUsedAt] = scenario.lastUsedAt
+			}
+			item := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", creationTimestamp, annotations)
+			if startTime := getStartTime(*item); !startTime.Time.Equal(scenario.expectedStartTime) {
+				t.Errorf("expected start time %s, got %s", scenario.expectedStartTime, startTime.Time)
+			}
+		})
+	}
+}
+
+func TestReconcile_withoutAnnotations(t *testing.T) {
+	defer func(previous bool) { inheritTTLFromOwner = previous }(inheritTTLFromOwner)
+	inheritTTLFromOwner = true
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pod := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata": map[string]interface{}{
+				"namespace":         "default",
+				"name":              "pod-without-annotations",
+				"creationTimestamp": time.Now().Add(-time.Hour).Format(time.RFC3339),
+			},
+		},
+	}
+	if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 1 {
+		t.Errorf("expected the pod without annotations to have been left alone, got %v", remaining)
+	}
+	setAnnotation(pod, AnnotationTTL, "5m")
+	if _, ttl, exists := getTTLAnnotation(pod.GetAnnotations()); !exists || ttl != "5m" {
+		t.Errorf("expected the annotation to have been set on the pod without annotations, got %v", pod.GetAnnotations())
+	}
+}
+
+func TestReconcile_withTTLRelativeToOwner(t *testing.T) {
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList, deploymentsAPIResourceList)
+	owners := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("apps/v1", "Deployment", "default", "owner", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "2h"}),
+		newUnstructuredWithAnnotations("apps/v1", "Deployment", "default", "zero-owner", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "0"}),
+		newUnstructuredWithAnnotations("apps/v1", "Deployment", "default", "never-owner", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "never"}),
+	}
+	for _, owner := range owners {
+		if _, err := dynamicClient.Resource(deploymentsGVR).Namespace("default").Create(context.TODO(), owner, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	pods := []*unstructured.Unstructured{
+		// 50% of the owner's 2h TTL is 1h, so this pod should be deleted
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-90*time.Minute), map[string]interface{}{AnnotationTTLRelativeToOwner: "50%"}),
+		// 90% of the owner's 2h TTL is 108m, so this pod should not be deleted
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "not-expired-pod-name", time.Now().Add(-90*time.Minute), map[string]interface{}{AnnotationTTLRelativeToOwner: "90%"}),
+		// The owner doesn't exist, so this pod should fall back to its own TTL
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "orphan-with-fallback-pod-name", time.Now().Add(-90*time.Minute), map[string]interface{}{AnnotationTTLRelativeToOwner: "50%", AnnotationTTL: "1h"}),
+		// The owner doesn't exist and there's no fallback, so this pod should not be deleted
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "orphan-pod-name", time.Now().Add(-90*time.Minute), map[string]interface{}{AnnotationTTLRelativeToOwner: "50%"}),
+		// The TTL of these pods' owners is explicitly disabled, so these pods should not be deleted either
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "zero-owner-pod-name", time.Now().Add(-90*time.Minute), map[string]interface{}{AnnotationTTLRelativeToOwner: "50%"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "never-owner-pod-name", time.Now().Add(-90*time.Minute), map[string]interface{}{AnnotationTTLRelativeToOwner: "50%", AnnotationTTL: "1h"}),
+	}
+	for _, pod := range pods {
+		if pod.GetName() == "orphan-with-fallback-pod-name" || pod.GetName() == "orphan-pod-name" {
+			pod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "deleted-owner"}})
+		} else if pod.GetName() == "zero-owner-pod-name" {
+			pod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "zero-owner"}})
+		} else if pod.GetName() == "never-owner-pod-name" {
+			pod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "never-owner"}})
+		} else {
+			pod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "owner"}})
+		}
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	remaining := listNames(t, dynamicClient, podsGVR, "default")
+	if len(remaining) != 4 || !remaining["not-expired-pod-name"] || !remaining["orphan-pod-name"] || !remaining["zero-owner-pod-name"] || !remaining["never-owner-pod-name"] {
+		t.Errorf("expected only not-expired-pod-name, orphan-pod-name, zero-owner-pod-name and never-owner-pod-name to remain, got %v", remaining)
+	}
+}
+
+func TestReconcile_withRetryBudget(t *testing.T) {
+	defer func(previous int) { retryBudgetPerExecution = previous }(retryBudgetPerExecution)
+	scenarios := []struct {
+		name                                     string
+		retryBudget                              int
+		failedDeletions                          int
+		expectedResourcesLeftAfterReconciliation int
+		expectedFailedDeletions                  int
+	}{
+		{
+			name:                                     "no-retry-budget",
+			retryBudget:                              0,
+			failedDeletions:                          1,
+			expectedResourcesLeftAfterReconciliation: 1,
+			expectedFailedDeletions:                  1,
+		},
+		{
+			name:                                     "retry-budget-large-enough",
+			retryBudget:                              2,
+			failedDeletions:                          2,
+			expectedResourcesLeftAfterReconciliation: 0,
+		},
+		{
+			name:                                     "retry-budget-exhausted",
+			retryBudget:                              2,
+			failedDeletions:                          3,
+			expectedResourcesLeftAfterReconciliation: 1,
+			expectedFailedDeletions:                  1,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			retryBudgetPerExecution = scenario.retryBudget
+			kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+			pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+			if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			failedDeletions := 0
+			dynamicClient.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if failedDeletions < scenario.failedDeletions {
+					failedDeletions++
+					return true, nil, errors.New("simulated failure")
+				}
+				return false, nil, nil
+			})
+			result, err := Reconcile(kubernetesClient, dynamicClient, eventManager)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if result.FailedDeletions != scenario.expectedFailedDeletions {
+				t.Errorf("expected %d failed deletions, got %d", scenario.expectedFailedDeletions, result.FailedDeletions)
+			}
+			if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != scenario.expectedResourcesLeftAfterReconciliation {
+				t.Errorf("expected %d resources, got %d", scenario.expectedResourcesLeftAfterReconciliation, len(remaining))
+			}
+		})
+	}
+}
+
+func TestReconcile_withInheritTTLFromOwner(t *testing.T) {
+	defer func(previous bool) { inheritTTLFromOwner = previous }(inheritTTLFromOwner)
+	scenarios := []struct {
+		name                                     string
+		inheritTTLFromOwner                      bool
+		deploymentTTL                            string
+		expectedResourcesLeftAfterReconciliation int
+	}{
+		{
+			name:                                     "disabled",
+			inheritTTLFromOwner:                      false,
+			deploymentTTL:                            "1h",
+			expectedResourcesLeftAfterReconciliation: 1,
+		},
+		{
+			name:                                     "enabled-with-expired-owner",
+			inheritTTLFromOwner:                      true,
+			deploymentTTL:                            "1h",
+			expectedResourcesLeftAfterReconciliation: 0,
+		},
+		{
+			name:                                     "enabled-with-non-expired-owner",
+			inheritTTLFromOwner:                      true,
+			deploymentTTL:                            "3h",
+			expectedResourcesLeftAfterReconciliation: 1,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			inheritTTLFromOwner = scenario.inheritTTLFromOwner
+			kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList, &metav1.APIResourceList{
+				GroupVersion: "apps/v1",
+				APIResources: []metav1.APIResource{
+					{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: []string{"get"}},
+					{Name: "replicasets", Kind: "ReplicaSet", Namespaced: true, Verbs: []string{"get"}},
+				},
+			})
+			deployment := newUnstructuredWithAnnotations("apps/v1", "Deployment", "default", "deployment-name", time.Now().Add(-2*time.Hour), map[string]interface{}{AnnotationTTL: scenario.deploymentTTL})
+			replicaSet := newUnstructuredWithAnnotations("apps/v1", "ReplicaSet", "default", "replicaset-name", time.Now().Add(-2*time.Hour), map[string]interface{}{})
+			replicaSet.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "deployment-name"}})
+			pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now().Add(-time.Minute), map[string]interface{}{})
+			pod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "replicaset-name"}})
+			for gvr, object := range map[schema.GroupVersionResource]*unstructured.Unstructured{deploymentsGVR: deployment, {Group: "apps", Version: "v1", Resource: "replicasets"}: replicaSet, podsGVR: pod} {
+				if _, err := dynamicClient.Resource(gvr).Namespace("default").Create(context.TODO(), object, metav1.CreateOptions{}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != scenario.expectedResourcesLeftAfterReconciliation {
+				t.Errorf("expected %d resources, got %d", scenario.expectedResourcesLeftAfterReconciliation, len(remaining))
+			}
+		})
+	}
+}
+
+func TestReconcile_withRequeuedDeletions(t *testing.T) {
+	defer func(previous *DeletionRequeue) { requeue = previous }(requeue)
+	requeue = NewDeletionRequeue(10)
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deletionAttempts := 0
+	dynamicClient.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deletionAttempts++
+		if deletionAttempts == 1 {
+			return true, nil, errors.New("simulated failure")
+		}
+		return false, nil, nil
+	})
+	if result, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil || result.FailedDeletions != 1 {
+		t.Fatalf("expected the first deletion to fail, got result=%+v and err=%v", result, err)
+	}
+	if len(requeue.deletions) != 1 {
+		t.Fatalf("expected the failed deletion to have been requeued, got %d requeued deletions", len(requeue.deletions))
+	}
+	dynamicClient.ClearActions()
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, action := range dynamicClient.Actions() {
+		if action.GetVerb() == "list" {
+			t.Error("expected the requeued deletion to have been retried before listing resources")
+		}
+		if action.GetVerb() == "delete" {
+			break
+		}
+	}
+	if deletionAttempts != 2 {
+		t.Errorf("expected the requeued deletion to have been attempted once in the second execution, got %d attempts in total", deletionAttempts)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 0 {
+		t.Errorf("expected the expired pod to have been deleted, got %v", remaining)
+	}
+	if len(requeue.deletions) != 0 {
+		t.Errorf("expected no deletions to be left in the requeue, got %d", len(requeue.deletions))
+	}
+}
+
+func TestReconcile_withPublisher(t *testing.T) {
+	defer func(previous Publisher) { publisher = previous }(publisher)
+	testPublisher := &memoryPublisher{}
+	publisher = testPublisher
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	for _, pod := range []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "not-expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "3d"}),
+	} {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(testPublisher.messages) != 1 {
+		t.Fatalf("expected 1 message to have been published, got %d", len(testPublisher.messages))
+	}
+	if message := testPublisher.messages[0]; message.Name != "expired-pod-name" || message.Namespace != "default" || message.Resource != "pods" || message.TTL != "5m" {
+		t.Errorf("unexpected message: %+v", message)
+	}
+}
+
+func TestReconcile_withArchiveWebhook(t *testing.T) {
+	defer func(previous *WebhookArchiver) { archiver = previous }(archiver)
+	scenarios := []struct {
+		name                      string
+		webhookStatusCode         int
+		failureBlocksDeletion     bool
+		expectedPodsLeft          int
+		expectedArchivedManifests int
+	}{
+		{name: "archived", webhookStatusCode: http.StatusOK, failureBlocksDeletion: true, expectedPodsLeft: 0, expectedArchivedManifests: 1},
+		{name: "archive-failure-blocks-deletion", webhookStatusCode: http.StatusInternalServerError, failureBlocksDeletion: true, expectedPodsLeft: 1},
+		{name: "archive-failure-does-not-block-deletion", webhookStatusCode: http.StatusInternalServerError, failureBlocksDeletion: false, expectedPodsLeft: 0},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			var archivedManifests []map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(scenario.webhookStatusCode)
+				if scenario.webhookStatusCode != http.StatusOK {
+					return
+				}
+				var manifest map[string]interface{}
+				if err := json.NewDecoder(r.Body).Decode(&manifest); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				archivedManifests = append(archivedManifests, manifest)
+			}))
+			defer server.Close()
+			archiver = NewWebhookArchiver(server.URL, scenario.failureBlocksDeletion)
+			kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+			pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+			if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != scenario.expectedPodsLeft {
+				t.Errorf("expected %d pods to be left, got %v", scenario.expectedPodsLeft, remaining)
+			}
+			if len(archivedManifests) != scenario.expectedArchivedManifests {
+				t.Fatalf("expected %d archived manifests, got %d", scenario.expectedArchivedManifests, len(archivedManifests))
+			}
+			for _, manifest := range archivedManifests {
+				if manifest["kind"] != "Pod" || manifest["metadata"].(map[string]interface{})["name"] != "expired-pod-name" {
+					t.Errorf("expected the manifest of the expired pod to have been archived, got %v", manifest)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcile_withAPIGroupsToWatch(t *testing.T) {
+	defer func(previous []string) { apiGroupsToWatch = previous }(apiGroupsToWatch)
+	apiGroupsToWatch = []string{"apps"}
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList, deploymentsAPIResourceList)
+	pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deployment := newUnstructuredWithAnnotations("apps/v1", "Deployment", "default", "expired-deployment-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	if _, err := dynamicClient.Resource(deploymentsGVR).Namespace("default").Create(context.TODO(), deployment, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 1 {
+		t.Errorf("expected pod to not have been deleted, because its group is not watched")
+	}
+	if remaining := listNames(t, dynamicClient, deploymentsGVR, "default"); len(remaining) != 0 {
+		t.Errorf("expected deployment to have been deleted, because its group is watched")
+	}
+}
+
+func TestReconcile_withCoreAPIGroupToWatch(t *testing.T) {
+	defer func(previous []string) { apiGroupsToWatch = previous }(apiGroupsToWatch)
+	widgetsGVR := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	kubernetesClient, _, eventManager := newTestClients(
+		&metav1.APIResourceList{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "pods", Kind: "Pod", Namespaced: true, Verbs: []string{"delete", "get", "list"}},
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: []string{"delete", "get", "list"}},
+			},
+		},
+		deploymentsAPIResourceList,
+		&metav1.APIResourceList{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{{Name: "widgets", Kind: "Widget", Namespaced: true, Verbs: []string{"delete", "get", "list"}}},
+		},
+	)
+	listKinds := map[schema.GroupVersionResource]string{podsGVR: "PodList", configMapsGVR: "ConfigMapList", deploymentsGVR: "DeploymentList", widgetsGVR: "WidgetList"}
+	scenarios := []struct {
+		name             string
+		apiGroupsToWatch string
+		expectedDeleted  map[schema.GroupVersionResource]bool
+	}{
+		{
+			name:             "core-token",
+			apiGroupsToWatch: "core,example.com",
+			expectedDeleted:  map[schema.GroupVersionResource]bool{podsGVR: true, configMapsGVR: true, deploymentsGVR: false, widgetsGVR: true},
+		},
+		{
+			name:             "empty-entry",
+			apiGroupsToWatch: ",apps",
+			expectedDeleted:  map[schema.GroupVersionResource]bool{podsGVR: true, configMapsGVR: true, deploymentsGVR: true, widgetsGVR: false},
+		},
+		{
+			name:             "without-core",
+			apiGroupsToWatch: "apps, example.com",
+			expectedDeleted:  map[schema.GroupVersionResource]bool{podsGVR: false, configMapsGVR: false, deploymentsGVR: true, widgetsGVR: true},
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			apiGroupsToWatch = parseAPIGroups(scenario.apiGroupsToWatch)
+			dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+			for gvr := range scenario.expectedDeleted {
+				kind := strings.TrimSuffix(listKinds[gvr], "List")
+				item := newUnstructuredWithAnnotations(gvr.GroupVersion().String(), kind, "default", "expired-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+				if _, err := dynamicClient.Resource(gvr).Namespace("default").Create(context.TODO(), item, metav1.CreateOptions{}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			for gvr, expectedDeleted := range scenario.expectedDeleted {
+				if deleted := len(listNames(t, dynamicClient, gvr, "default")) == 0; deleted != expectedDeleted {
+					t.Errorf("expected %s to have been deleted=%t, got %t", gvr.Resource, expectedDeleted, deleted)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcile_withPreferredVersionOnly(t *testing.T) {
+	defer func(previous bool) { preferredVersionOnly = previous }(preferredVersionOnly)
+	preferredVersionOnly = true
+	deploymentsV1beta1APIResourceList := &metav1.APIResourceList{
+		GroupVersion: "apps/v1beta1",
+		APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: []string{"delete", "get", "list"}}},
+	}
+	// The first version of a group is the preferred one
+	kubernetesClient, dynamicClient, eventManager := newTestClients(deploymentsAPIResourceList, deploymentsV1beta1APIResourceList)
+	deployment := newUnstructuredWithAnnotations("apps/v1", "Deployment", "default", "expired-deployment-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	if _, err := dynamicClient.Resource(deploymentsGVR).Namespace("default").Create(context.TODO(), deployment, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, action := range dynamicClient.Actions() {
+		if action.GetResource().Version == "v1beta1" {
+			t.Errorf("expected deployments not to be reconciled through apps/v1beta1, got %s %s", action.GetVerb(), action.GetResource())
+		}
+	}
+	if remaining := listNames(t, dynamicClient, deploymentsGVR, "default"); len(remaining) != 0 {
+		t.Errorf("expected the expired deployment to have been deleted through apps/v1, got %v", remaining)
+	}
+}
+
+func TestReconcile_withResourceServedUnderMultipleVersions(t *testing.T) {
+	deploymentsV1beta1GVR := schema.GroupVersionResource{Group: "apps", Version: "v1beta1", Resource: "deployments"}
+	deploymentsV1beta1APIResourceList := &metav1.APIResourceList{
+		GroupVersion: "apps/v1beta1",
+		APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: []string{"delete", "get", "list"}}},
+	}
+	// The first version of a group is the preferred one
+	kubernetesClient, _, eventManager := newTestClients(deploymentsAPIResourceList, deploymentsV1beta1APIResourceList)
+	dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{deploymentsGVR: "DeploymentList", deploymentsV1beta1GVR: "DeploymentList"})
+	// The fake client doesn't share storage between versions, so the same deployment is created under both of them
+	for _, gvr := range []schema.GroupVersionResource{deploymentsGVR, deploymentsV1beta1GVR} {
+		deployment := newUnstructuredWithAnnotations(gvr.GroupVersion().String(), "Deployment", "default", "expired-deployment-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+		if _, err := dynamicClient.Resource(gvr).Namespace("default").Create(context.TODO(), deployment, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	dynamicClient.ClearActions()
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, action := range dynamicClient.Actions() {
+		if action.GetResource() == deploymentsV1beta1GVR {
+			t.Errorf("expected deployments not to be reconciled through apps/v1beta1, got %s %s", action.GetVerb(), action.GetResource())
+		}
+	}
+	if remaining := listNames(t, dynamicClient, deploymentsGVR, "default"); len(remaining) != 0 {
+		t.Errorf("expected the expired deployment to have been deleted through apps/v1, got %v", remaining)
+	}
+	events, err := kubernetesClient.CoreV1().Events("default").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events.Items) != 1 {
+		t.Errorf("expected a single event for the deleted deployment, got %d", len(events.Items))
+	}
+}
+
+func TestCoalesceVersions(t *testing.T) {
+	groups := []*metav1.APIGroup{
+		{
+			Name:             "example.com",
+			Versions:         []metav1.GroupVersionForDiscovery{{GroupVersion: "example.com/v1", Version: "v1"}, {GroupVersion: "example.com/v1beta2", Version: "v1beta2"}, {GroupVersion: "example.com/v1beta1", Version: "v1beta1"}},
+			PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "example.com/v1", Version: "v1"},
+		},
+	}
+	resources := []*metav1.APIResourceList{
+		{GroupVersion: "example.com/v1beta1", APIResources: []metav1.APIResource{{Name: "widgets"}, {Name: "gadgets"}, {Name: "legacies"}}},
+		{GroupVersion: "example.com/v1beta2", APIResources: []metav1.APIResource{{Name: "gadgets"}, {Name: "gadgets/status"}}},
+		{GroupVersion: "example.com/v1", APIResources: []metav1.APIResource{{Name: "widgets"}, {Name: "widgets/status"}}},
+	}
+	var reconciled []string
+	for _, resource := range coalesceVersions(groups, resources) {
+		for _, apiResource := range resource.APIResources {
+			reconciled = append(reconciled, resource.GroupVersion+"/"+apiResource.Name)
+		}
+	}
+	// widgets are served under the preferred version, gadgets under the highest ranked version that serves them, and
+	// legacies are only served under a single version
+	expected := []string{"example.com/v1beta1/legacies", "example.com/v1beta2/gadgets", "example.com/v1beta2/gadgets/status", "example.com/v1/widgets", "example.com/v1/widgets/status"}
+	if strings.Join(reconciled, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, reconciled)
+	}
+}
+
+func TestParseGroupVersion(t *testing.T) {
+	scenarios := []struct {
+		groupVersion    string
+		expectedGroup   string
+		expectedVersion string
+		expectedErr     bool
+	}{
+		{groupVersion: "v1", expectedVersion: "v1"},
+		{groupVersion: "apps/v1", expectedGroup: "apps", expectedVersion: "v1"},
+		{groupVersion: "", expectedErr: true},
+		{groupVersion: "/v1", expectedErr: true},
+		{groupVersion: "apps/", expectedErr: true},
+		{groupVersion: "example.com/apps/v1", expectedErr: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.groupVersion, func(t *testing.T) {
+			gv, err := parseGroupVersion(scenario.groupVersion)
+			if scenario.expectedErr != (err != nil) {
+				t.Fatalf("expected error=%t, got %v", scenario.expectedErr, err)
+			}
+			if gv.Group != scenario.expectedGroup || gv.Version != scenario.expectedVersion {
+				t.Errorf("expected group '%s' and version '%s', got group '%s' and version '%s'", scenario.expectedGroup, scenario.expectedVersion, gv.Group, gv.Version)
+			}
+		})
+	}
+}
+
+func TestSortAPIResources(t *testing.T) {
+	resources := []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods"}, {Name: "configmaps"}}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "replicasets"}, {Name: "deployments"}}},
+		{GroupVersion: "batch/v1", APIResources: []metav1.APIResource{{Name: "jobs"}}},
+		{GroupVersion: "apps/v1beta1", APIResources: []metav1.APIResource{{Name: "deployments"}}},
+	}
+	var order []string
+	for _, resource := range sortAPIResources(resources) {
+		for _, apiResource := range resource.APIResources {
+			order = append(order, resource.GroupVersion+"/"+apiResource.Name)
+		}
+	}
+	expected := []string{"v1/configmaps", "v1/pods", "apps/v1/deployments", "apps/v1/replicasets", "apps/v1beta1/deployments", "batch/v1/jobs"}
+	if strings.Join(order, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, order)
+	}
+	// The resources passed must be left untouched
+	if resources[0].GroupVersion != "v1" || resources[0].APIResources[0].Name != "pods" {
+		t.Error("expected the original resources not to have been sorted in place")
+	}
+}
+
+func TestReconcile_withStartupGracePeriod(t *testing.T) {
+	defer func(previous time.Duration) { startupGracePeriod = previous }(startupGracePeriod)
+	scenarios := []struct {
+		name                                     string
+		startupGracePeriod                       time.Duration
+		expectedResourcesLeftAfterReconciliation int
+	}{
+		{
+			name:                                     "within-grace-period",
+			startupGracePeriod:                       time.Hour,
+			expectedResourcesLeftAfterReconciliation: 1,
+		},
+		{
+			name:                                     "no-grace-period",
+			startupGracePeriod:                       0,
+			expectedResourcesLeftAfterReconciliation: 0,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			startupGracePeriod = scenario.startupGracePeriod
+			kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+			pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+			if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != scenario.expectedResourcesLeftAfterReconciliation {
+				t.Errorf("expected %d resources, got %d", scenario.expectedResourcesLeftAfterReconciliation, len(remaining))
+			}
+		})
+	}
+}
+
+func TestReconcile_withClockSkewTolerance(t *testing.T) {
+	defer func(previous time.Duration) { clockSkewTolerance = previous }(clockSkewTolerance)
+	clockSkewTolerance = time.Hour
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pods := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-within-tolerance-pod-name", time.Now().Add(-35*time.Minute), map[string]interface{}{AnnotationTTL: "5m"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-beyond-tolerance-pod-name", time.Now().Add(-2*time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+	}
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 1 || !remaining["expired-within-tolerance-pod-name"] {
+		t.Errorf("expected only the pod that expired within the clock skew tolerance to be left, got %v", remaining)
+	}
+}
+
+func TestReconcile_withTTLAnnotations(t *testing.T) {
+	defer func(previous []string) { ttlAnnotations = previous }(ttlAnnotations)
+	ttlAnnotations = []string{"example.com/ttl", AnnotationTTL}
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	for _, pod := range []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-with-new-annotation", time.Now().Add(-time.Hour), map[string]interface{}{"example.com/ttl": "5m"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-with-old-annotation", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+		// The first annotation takes precedence, so this pod should not be deleted
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "not-expired-pod-with-both-annotations", time.Now().Add(-time.Hour), map[string]interface{}{"example.com/ttl": "3d", AnnotationTTL: "5m"}),
+	} {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 1 || !remaining["not-expired-pod-with-both-annotations"] {
+		t.Errorf("expected only not-expired-pod-with-both-annotations to remain, got %v", remaining)
+	}
+}
+
+func TestReconcile_withDeletionWindow(t *testing.T) {
+	defer func(previous *DeletionWindow) { deletionWindow = previous }(deletionWindow)
+	now := time.Now().UTC()
+	windowIncludingNow := now.Add(-time.Hour).Format("15:04") + "-" + now.Add(time.Hour).Format("15:04")
+	windowExcludingNow := now.Add(2*time.Hour).Format("15:04") + "-" + now.Add(3*time.Hour).Format("15:04")
+	scenarios := []struct {
+		name                                     string
+		globalWindow                             string
+		namespaceWindow                          string
+		expectedResourcesLeftAfterReconciliation int
+	}{
+		{
+			name:                                     "no-window",
+			expectedResourcesLeftAfterReconciliation: 0,
+		},
+		{
+			name:                                     "within-global-window",
+			globalWindow:                             windowIncludingNow,
+			expectedResourcesLeftAfterReconciliation: 0,
+		},
+		{
+			name:                                     "outside-global-window",
+			globalWindow:                             windowExcludingNow,
+			expectedResourcesLeftAfterReconciliation: 1,
+		},
+		{
+			name:                                     "outside-namespace-window",
+			namespaceWindow:                          windowExcludingNow,
+			expectedResourcesLeftAfterReconciliation: 1,
+		},
+		{
+			name:                                     "namespace-window-takes-precedence-over-global-window",
+			globalWindow:                             windowExcludingNow,
+			namespaceWindow:                          windowIncludingNow,
+			expectedResourcesLeftAfterReconciliation: 0,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			deletionWindow = nil
+			if scenario.globalWindow != "" {
+				deletionWindow, _ = ParseDeletionWindow(scenario.globalWindow)
+			}
+			kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+			if scenario.namespaceWindow != "" {
+				namespace := newUnstructuredWithAnnotations("v1", "Namespace", "", "default", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationDeletionWindow: scenario.namespaceWindow})
+				if _, err := dynamicClient.Resource(namespacesGVR).Create(context.TODO(), namespace, metav1.CreateOptions{}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+			if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != scenario.expectedResourcesLeftAfterReconciliation {
+				t.Errorf("expected %d resources, got %d", scenario.expectedResourcesLeftAfterReconciliation, len(remaining))
+			}
+		})
+	}
+}
+
+func TestReconcile_withNamespaceLock(t *testing.T) {
+	scenarios := []struct {
+		name                                     string
+		lock                                     string
+		expectedResourcesLeftAfterReconciliation int
+	}{
+		{
+			name:                                     "locked",
+			lock:                                     "true",
+			expectedResourcesLeftAfterReconciliation: 1,
+		},
+		{
+			name:                                     "locked-until-future",
+			lock:                                     time.Now().Add(time.Hour).Format(time.RFC3339),
+			expectedResourcesLeftAfterReconciliation: 1,
+		},
+		{
+			name:                                     "locked-until-past",
+			lock:                                     time.Now().Add(-time.Hour).Format(time.RFC3339),
+			expectedResourcesLeftAfterReconciliation: 0,
+		},
+		{
+			name:                                     "unlocked",
+			lock:                                     "false",
+			expectedResourcesLeftAfterReconciliation: 0,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+			namespace := newUnstructuredWithAnnotations("v1", "Namespace", "", "default", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationLock: scenario.lock})
+			if _, err := dynamicClient.Resource(namespacesGVR).Create(context.TODO(), namespace, metav1.CreateOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+			if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != scenario.expectedResourcesLeftAfterReconciliation {
+				t.Errorf("expected %d resources, got %d", scenario.expectedResourcesLeftAfterReconciliation, len(remaining))
+			}
+		})
+	}
+}
+
+func TestReconcile_withReportUnannotatedResources(t *testing.T) {
+	defer func(previous bool) { reportUnannotatedResources = previous }(reportUnannotatedResources)
+	reportUnannotatedResources = true
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pods := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "unannotated-pod-name", time.Now(), map[string]interface{}{}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "annotated-pod-name", time.Now(), map[string]interface{}{"app": "example"}),
+	}
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	before := testutil.ToFloat64(unannotatedResourcesTotal.WithLabelValues(podsGVR.Group, podsGVR.Version, podsGVR.Resource))
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if reported := testutil.ToFloat64(unannotatedResourcesTotal.WithLabelValues(podsGVR.Group, podsGVR.Version, podsGVR.Resource)) - before; reported != 1 {
+		t.Errorf("expected 1 unannotated resource to be reported, got %v", reported)
+	}
+}
+
+func TestReconcile_withDeletionBatches(t *testing.T) {
+	defer func(previousSize int, previousPause time.Duration) {
+		deletionBatchSize, deletionBatchPause = previousSize, previousPause
+	}(deletionBatchSize, deletionBatchPause)
+	deletionBatchSize, deletionBatchPause = 2, 100*time.Millisecond
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	for _, name := range []string{"expired-pod-name-1", "expired-pod-name-2", "expired-pod-name-3", "expired-pod-name-4", "expired-pod-name-5"} {
+		pod := newUnstructuredWithAnnotations("v1", "Pod", "default", name, time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	start := time.Now()
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// 5 deletions make up 2 full batches, each of which is followed by a pause
+	if elapsed := time.Since(start); elapsed < 2*deletionBatchPause {
+		t.Errorf("expected the execution to pause for at least %s, took %s", 2*deletionBatchPause, elapsed)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 0 {
+		t.Errorf("expected 0 resources, got %d", len(remaining))
+	}
+}
+
+func TestRecordDeletion_withDeletionBatchPause(t *testing.T) {
+	defer func(previousSize int, previousPause time.Duration) {
+		deletionBatchSize, deletionBatchPause = previousSize, previousPause
+	}(deletionBatchSize, deletionBatchPause)
+	defer func(previous time.Time) { deletionsPausedUntil = previous }(deletionsPausedUntil)
+	defer func(previous int) { deletedResourcesInExecution = previous }(deletedResourcesInExecution)
+	deletionBatchSize, deletionBatchPause = 1, 100*time.Millisecond
+	pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now(), map[string]interface{}{})
+	start := time.Now()
+	deletionMutex.Lock()
+	recordDeletion(podsGVR, *pod)
+	deletionMutex.Unlock()
+	if elapsed := time.Since(start); elapsed >= deletionBatchPause {
+		t.Errorf("expected the pause not to be taken while holding the deletion mutex, took %s", elapsed)
+	}
+	waitForDeletionPause()
+	if elapsed := time.Since(start); elapsed < deletionBatchPause {
+		t.Errorf("expected the next deletion to wait for %s, only waited %s", deletionBatchPause, elapsed)
+	}
+}
+
+func TestDeleteExpiredResource_throttlesWithoutHoldingDeletionMutex(t *testing.T) {
+	defer func(previous *Throttler) { throttler = previous }(throttler)
+	throttler = NewThrottler(false, 200*time.Millisecond, 200*time.Millisecond, DefaultAdaptiveThrottleLatencyThreshold, 0)
+	_, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan bool)
+	go func() {
+		done <- deleteExpiredResource(dynamicClient, eventManager, NewNamespaceCache(dynamicClient), podsGVR, *pod, "5m", time.Now().Add(-time.Minute))
+	}()
+	deadline := time.Now().Add(100 * time.Millisecond)
+	for {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Get(context.TODO(), "pod-name", metav1.GetOptions{}); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the resource to have been deleted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for !deletionMutex.TryLock() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the deletion mutex to be released while throttling")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	deletionMutex.Unlock()
+	select {
+	case <-done:
+		t.Fatal("expected the deletion to still be throttled")
+	default:
+	}
+	if !<-done {
+		t.Error("expected the resource to have been deleted")
+	}
+}
+
+func TestReconcile_withTerminatingNamespace(t *testing.T) {
+	defer func(previous bool) { skipTerminatingNamespaces = previous }(skipTerminatingNamespaces)
+	scenarios := []struct {
+		name                                     string
+		skipTerminatingNamespaces                bool
+		expectedResourcesLeftAfterReconciliation int
+	}{
+		{
+			name:                                     "skip-terminating-namespaces",
+			skipTerminatingNamespaces:                true,
+			expectedResourcesLeftAfterReconciliation: 2,
+		},
+		{
+			name:                                     "do-not-skip-terminating-namespaces",
+			skipTerminatingNamespaces:                false,
+			expectedResourcesLeftAfterReconciliation: 0,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			skipTerminatingNamespaces = scenario.skipTerminatingNamespaces
+			kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+			namespace := newUnstructuredWithAnnotations("v1", "Namespace", "", "default", time.Now().Add(-time.Hour), map[string]interface{}{})
+			namespace.Object["status"] = map[string]interface{}{"phase": "Terminating"}
+			if _, err := dynamicClient.Resource(namespacesGVR).Create(context.TODO(), namespace, metav1.CreateOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, name := range []string{"expired-pod-name-1", "expired-pod-name-2"} {
+				pod := newUnstructuredWithAnnotations("v1", "Pod", "default", name, time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+				if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != scenario.expectedResourcesLeftAfterReconciliation {
+				t.Errorf("expected %d resources, got %d", scenario.expectedResourcesLeftAfterReconciliation, len(remaining))
+			}
+		})
+	}
+}
+
+func TestReconcile_withMaxCountPerOwner(t *testing.T) {
+	defer func(previous map[string]int) { maxCountPerOwner = previous }(maxCountPerOwner)
+	maxCountPerOwner = map[string]int{"Pod": 2}
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	isController := true
+	pods := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "oldest-pod-name", time.Now().Add(-4*time.Hour), map[string]interface{}{}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-3*time.Hour), map[string]interface{}{AnnotationTTL: "1h"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "recent-pod-name-1", time.Now().Add(-2*time.Hour), map[string]interface{}{}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "recent-pod-name-2", time.Now().Add(-time.Hour), map[string]interface{}{}),
+	}
+	for _, pod := range pods {
+		pod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "replicaset-name", UID: "replicaset-uid", Controller: &isController}})
+	}
+	otherOwnerPod := newUnstructuredWithAnnotations("v1", "Pod", "default", "other-owner-pod-name", time.Now().Add(-5*time.Hour), map[string]interface{}{})
+	otherOwnerPod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "other-replicaset-name", UID: "other-replicaset-uid", Controller: &isController}})
+	pods = append(pods, otherOwnerPod, newUnstructuredWithAnnotations("v1", "Pod", "default", "unowned-pod-name", time.Now().Add(-5*time.Hour), map[string]interface{}{}))
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	remaining := listNames(t, dynamicClient, podsGVR, "default")
+	if len(remaining) != 4 || !remaining["recent-pod-name-1"] || !remaining["recent-pod-name-2"] || !remaining["other-owner-pod-name"] || !remaining["unowned-pod-name"] {
+		t.Errorf("expected the expired pod and the oldest pod of the owner exceeding the maximum count to have been deleted, got %v", remaining)
+	}
+}
+
+func TestReconcile_withListMethodNotAllowed(t *testing.T) {
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList, deploymentsAPIResourceList)
+	deployment := newUnstructuredWithAnnotations("apps/v1", "Deployment", "default", "expired-deployment-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	if _, err := dynamicClient.Resource(deploymentsGVR).Namespace("default").Create(context.TODO(), deployment, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	listAttempts := 0
+	dynamicClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		listAttempts++
+		return true, nil, apierrors.NewMethodNotSupported(schema.GroupResource{Resource: "pods"}, "list")
+	})
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if listAttempts != 1 {
+		t.Errorf("expected pods to have been listed once, got %d", listAttempts)
+	}
+	if _, unlistable := unlistableResources.Load(podsGVR); !unlistable {
+		t.Error("expected pods to have been recorded as unlistable")
+	}
+	if remaining := listNames(t, dynamicClient, deploymentsGVR, "default"); len(remaining) != 0 {
+		t.Error("expected the reconciliation to have moved on to deployments and deleted the expired deployment")
+	}
+}
+
+func TestReconcile_withMetadataOnlyList(t *testing.T) {
+	defer func(previous metadata.Interface) { metadataClient = previous }(metadataClient)
+	scenarios := []struct {
+		name                        string
+		metadataListErr             error
+		expectedDynamicListAttempts int
+	}{
+		{name: "metadata-only", expectedDynamicListAttempts: 0},
+		{name: "fallback-to-full-objects", metadataListErr: apierrors.NewGenericServerResponse(406, "list", schema.GroupResource{Resource: "pods"}, "", "", 0, false), expectedDynamicListAttempts: 1},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			metadataListUnsupportedResources.Delete(podsGVR)
+			kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+			scheme := fakemetadata.NewTestScheme()
+			_ = metav1.AddMetaToScheme(scheme)
+			var pods []runtime.Object
+			for _, pod := range []*unstructured.Unstructured{
+				newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+				newUnstructuredWithAnnotations("v1", "Pod", "default", "not-expired-pod-name", time.Now(), map[string]interface{}{AnnotationTTL: "3d"}),
+			} {
+				if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				pods = append(pods, &metav1.PartialObjectMetadata{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+					ObjectMeta: metav1.ObjectMeta{Name: pod.GetName(), Namespace: pod.GetNamespace(), CreationTimestamp: pod.GetCreationTimestamp(), Annotations: pod.GetAnnotations()},
+				})
+			}
+			fakeMetadataClient := fakemetadata.NewSimpleMetadataClient(scheme, pods...)
+			if scenario.metadataListErr != nil {
+				fakeMetadataClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, scenario.metadataListErr
+				})
+			}
+			metadataClient = fakeMetadataClient
+			dynamicListAttempts := 0
+			dynamicClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetNamespace() == "" {
+					dynamicListAttempts++
+				}
+				return false, nil, nil
+			})
+			if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if dynamicListAttempts != scenario.expectedDynamicListAttempts {
+				t.Errorf("expected pods to have been listed as full objects %d times, got %d", scenario.expectedDynamicListAttempts, dynamicListAttempts)
+			}
+			if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 1 || !remaining["not-expired-pod-name"] {
+				t.Errorf("expected only the pod that has not expired to be left, got %v", remaining)
+			}
+		})
+	}
+}
+
+func TestReconcile_withStartTTLWhenReady(t *testing.T) {
+	defer func(previous metadata.Interface) { metadataClient = previous }(metadataClient)
+	readyCondition := func(status string, transitionedAgo time.Duration) []interface{} {
+		return []interface{}{map[string]interface{}{"type": "Ready", "status": status, "lastTransitionTime": time.Now().Add(-transitionedAgo).UTC().Format(time.RFC3339)}}
+	}
+	scenarios := []struct {
+		name            string
+		annotated       bool
+		conditions      []interface{}
+		metadataOnly    bool
+		getErr          error
+		expectedDeleted bool
+	}{
+		{name: "not-annotated", annotated: false, conditions: readyCondition("True", 10*time.Minute), expectedDeleted: true},
+		{name: "recently-ready", annotated: true, conditions: readyCondition("True", 10*time.Minute), expectedDeleted: false},
+		{name: "ready-for-long", annotated: true, conditions: readyCondition("True", 2*time.Hour), expectedDeleted: true},
+		{name: "never-ready", annotated: true, conditions: readyCondition("False", 3*time.Hour), expectedDeleted: false},
+		{name: "without-ready-condition", annotated: true, conditions: nil, expectedDeleted: true},
+		{name: "recently-ready-with-metadata-only-list", annotated: true, conditions: readyCondition("True", 10*time.Minute), metadataOnly: true, expectedDeleted: false},
+		{name: "never-ready-with-metadata-only-list-and-get-failure", annotated: true, conditions: readyCondition("False", 3*time.Hour), metadataOnly: true, getErr: apierrors.NewServiceUnavailable("unavailable"), expectedDeleted: false},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			metadataClient = nil
+			kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+			annotations := map[string]interface{}{AnnotationTTL: "1h"}
+			if scenario.annotated {
+				annotations[AnnotationStartTTLWhenReady] = "true"
+			}
+			pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now().Add(-3*time.Hour), annotations)
+			if scenario.conditions != nil {
+				if err := unstructured.SetNestedSlice(pod.Object, scenario.conditions, "status", "conditions"); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if scenario.metadataOnly {
+				scheme := fakemetadata.NewTestScheme()
+				_ = metav1.AddMetaToScheme(scheme)
+				metadataClient = fakemetadata.NewSimpleMetadataClient(scheme, &metav1.PartialObjectMetadata{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+					ObjectMeta: metav1.ObjectMeta{Name: pod.GetName(), Namespace: pod.GetNamespace(), CreationTimestamp: pod.GetCreationTimestamp(), Annotations: pod.GetAnnotations()},
+				})
+			}
+			if scenario.getErr != nil {
+				dynamicClient.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, scenario.getErr
+				})
+			}
+			if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if deleted := !listNames(t, dynamicClient, podsGVR, "default")["pod-name"]; deleted != scenario.expectedDeleted {
+				t.Errorf("expected deleted=%t, got %t", scenario.expectedDeleted, deleted)
+			}
+		})
+	}
+}
+
+func TestReconcile_withListFailure(t *testing.T) {
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList, deploymentsAPIResourceList)
+	deployment := newUnstructuredWithAnnotations("apps/v1", "Deployment", "default", "expired-deployment-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	if _, err := dynamicClient.Resource(deploymentsGVR).Namespace("default").Create(context.TODO(), deployment, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dynamicClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewServiceUnavailable("unavailable")
+	})
+	result, err := Reconcile(kubernetesClient, dynamicClient, eventManager)
+	var partialListError *PartialListError
+	if !errors.As(err, &partialListError) {
+		t.Fatalf("expected a PartialListError, got %v", err)
+	}
+	if _, exists := partialListError.Failures[podsGVR]; !exists || len(partialListError.Failures) != 1 {
+		t.Errorf("expected only pods to have failed to be listed, got %v", partialListError.Failures)
+	}
+	if !isTransientError(err) {
+		t.Error("expected a partial list failure to be transient")
+	}
+	if result.Deleted != 1 {
+		t.Errorf("expected the execution to have completed and deleted the expired deployment, got %+v", result)
+	}
+}
+
+func TestReconcile_withMaxTTLByKind(t *testing.T) {
+	defer func(previous map[string]time.Duration) { maxTTLByKind = previous }(maxTTLByKind)
+	maxTTLByKind = map[string]time.Duration{"Pod": 2 * time.Hour}
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pods := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "capped-pod-name", time.Now().Add(-3*time.Hour), map[string]interface{}{AnnotationTTL: "365d"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "capped-but-not-expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "365d"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "uncapped-pod-name", time.Now().Add(-3*time.Hour), map[string]interface{}{AnnotationTTL: "90m"}),
+	}
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 1 || !remaining["capped-but-not-expired-pod-name"] {
+		t.Errorf("expected only the pod that has not reached the maximum TTL to be left, got %v", remaining)
+	}
+}
+
+func TestReconcile_withInvalidRefreshedAt(t *testing.T) {
+	defer func(previous bool) { strictRefreshedAt = previous }(strictRefreshedAt)
+	scenarios := []struct {
+		name                                     string
+		strictRefreshedAt                        bool
+		expectedResourcesLeftAfterReconciliation int
+	}{
+		{
+			name:                                     "lenient",
+			strictRefreshedAt:                        false,
+			expectedResourcesLeftAfterReconciliation: 0,
+		},
+		{
+			name:                                     "strict",
+			strictRefreshedAt:                        true,
+			expectedResourcesLeftAfterReconciliation: 1,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			strictRefreshedAt = scenario.strictRefreshedAt
+			kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+			pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m", AnnotationRefreshedAt: "yesterday"})
+			if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != scenario.expectedResourcesLeftAfterReconciliation {
+				t.Errorf("expected %d resources, got %d", scenario.expectedResourcesLeftAfterReconciliation, len(remaining))
+			}
+		})
+	}
+}
+
+func TestReconcile_withPaused(t *testing.T) {
+	defer func(previous bool) { paused = previous }(paused)
+	paused = true
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 1 {
+		t.Errorf("expected the expired pod not to be deleted while deletions are paused, got %v", remaining)
+	}
+	paused = false
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 0 {
+		t.Errorf("expected the expired pod to be deleted once deletions are resumed, got %v", remaining)
+	}
+}
+
+func TestReconcile_withDeleteConditionReason(t *testing.T) {
+	defer func(previous map[string][]string) { deleteConditionReasons = previous }(deleteConditionReasons)
+	deleteConditionReasons = map[string][]string{"Pod": {"Evicted", "DeadlineExceeded"}}
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	evictedPod := newUnstructuredWithAnnotations("v1", "Pod", "default", "evicted-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	_ = unstructured.SetNestedSlice(evictedPod.Object, []interface{}{map[string]interface{}{"type": "Ready", "status": "False", "reason": "Evicted"}}, "status", "conditions")
+	runningPod := newUnstructuredWithAnnotations("v1", "Pod", "default", "running-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	_ = unstructured.SetNestedSlice(runningPod.Object, []interface{}{map[string]interface{}{"type": "Ready", "status": "True", "reason": "PodCompleted"}}, "status", "conditions")
+	for _, pod := range []*unstructured.Unstructured{evictedPod, runningPod} {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 1 || !remaining["running-pod-name"] {
+		t.Errorf("expected only the expired pod without a matching condition reason to be left, got %v", remaining)
+	}
+}
+
+func TestReconcile_withGlobalTTLExtension(t *testing.T) {
+	defer func(previous time.Duration) { globalTTLExtension = previous }(globalTTLExtension)
+	globalTTLExtension = 2 * time.Hour
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pods := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "recently-expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "long-expired-pod-name", time.Now().Add(-5*time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+	}
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 1 || !remaining["recently-expired-pod-name"] {
+		t.Errorf("expected only the pod that expired less than 2h ago to be left, got %v", remaining)
+	}
+}
+
+func TestReconcile_withLastEvaluatedInterval(t *testing.T) {
+	defer func(previous time.Duration) { lastEvaluatedInterval = previous }(lastEvaluatedInterval)
+	lastEvaluatedInterval = time.Hour
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	// The fake object tracker can't apply patches to unstructured objects, so the annotations of apply patches are
+	// merged into the pods directly
+	dynamicClient.PrependReactor("patch", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patchAction := action.(k8stesting.PatchActionImpl)
+		if patchAction.GetPatchType() != types.ApplyPatchType {
+			t.Errorf("expected an apply patch, got %s", patchAction.GetPatchType())
+		}
+		patch := &unstructured.Unstructured{}
+		if err := json.Unmarshal(patchAction.GetPatch(), &patch.Object); err != nil {
+			return true, nil, err
+		}
+		obj, err := dynamicClient.Tracker().Get(podsGVR, patchAction.GetNamespace(), patchAction.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		pod := obj.(*unstructured.Unstructured)
+		annotations := pod.GetAnnotations()
+		for key, value := range patch.GetAnnotations() {
+			annotations[key] = value
+		}
+		pod.SetAnnotations(annotations)
+		return true, pod, dynamicClient.Tracker().Update(podsGVR, pod, patchAction.GetNamespace())
+	})
+	recentlyEvaluatedAt := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	pods := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "never-evaluated-pod-name", time.Now(), map[string]interface{}{AnnotationTTL: "1d"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "recently-evaluated-pod-name", time.Now(), map[string]interface{}{AnnotationTTL: "1d", AnnotationLastEvaluatedAt: recentlyEvaluatedAt}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "long-evaluated-pod-name", time.Now(), map[string]interface{}{AnnotationTTL: "1d", AnnotationLastEvaluatedAt: time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-without-ttl-name", time.Now(), map[string]interface{}{}),
+	}
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	getLastEvaluatedAt := func(name string) string {
+		pod, err := dynamicClient.Resource(podsGVR).Namespace("default").Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return pod.GetAnnotations()[AnnotationLastEvaluatedAt]
+	}
+	for _, name := range []string{"never-evaluated-pod-name", "long-evaluated-pod-name"} {
+		if lastEvaluatedAt, err := time.Parse(time.RFC3339, getLastEvaluatedAt(name)); err != nil || time.Since(lastEvaluatedAt) > time.Minute {
+			t.Errorf("expected %s to have been marked as evaluated just now, got '%s'", name, getLastEvaluatedAt(name))
+		}
+	}
+	if lastEvaluatedAt := getLastEvaluatedAt("recently-evaluated-pod-name"); lastEvaluatedAt != recentlyEvaluatedAt {
+		t.Errorf("expected the annotation of recently-evaluated-pod-name to have been left untouched, got '%s'", lastEvaluatedAt)
+	}
+	if lastEvaluatedAt := getLastEvaluatedAt("pod-without-ttl-name"); lastEvaluatedAt != "" {
+		t.Errorf("expected pod-without-ttl-name not to have been marked as evaluated, got '%s'", lastEvaluatedAt)
+	}
+}
+
+func TestGetAlignmentDelay(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		now      time.Time
+		align    time.Duration
+		expected time.Duration
+	}{
+		{
+			name:     "disabled",
+			now:      time.Date(2024, 12, 8, 10, 3, 27, 0, time.UTC),
+			align:    0,
+			expected: 0,
+		},
+		{
+			name:     "5m",
+			now:      time.Date(2024, 12, 8, 10, 3, 27, 0, time.UTC),
+			align:    5 * time.Minute,
+			expected: time.Minute + 33*time.Second,
+		},
+		{
+			name:     "30m",
+			now:      time.Date(2024, 12, 8, 10, 3, 27, 0, time.UTC),
+			align:    30 * time.Minute,
+			expected: 26*time.Minute + 33*time.Second,
+		},
+		{
+			name:     "on-boundary",
+			now:      time.Date(2024, 12, 8, 10, 5, 0, 0, time.UTC),
+			align:    5 * time.Minute,
+			expected: 0,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			if delay := getAlignmentDelay(scenario.now, scenario.align); delay != scenario.expected {
+				t.Errorf("expected %s, got %s", scenario.expected, delay)
+			}
+		})
+	}
+}
+
+func TestReconcile_withWatchNamespace(t *testing.T) {
+	defer func(previous []string) { watchNamespaces = previous }(watchNamespaces)
+	watchNamespaces = []string{"default", "restricted"}
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pods := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "restricted", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "unwatched", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+	}
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace(pod.GetNamespace()).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	dynamicClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() != "restricted" {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewForbidden(podsGVR.GroupResource(), "", errors.New("no permissions"))
+	})
+	result, err := Reconcile(kubernetesClient, dynamicClient, eventManager)
+	if err != nil {
+		t.Errorf("expected forbidden namespaces not to fail the execution, got %v", err)
+	}
+	if len(result.InaccessibleNamespaces) != 1 || result.InaccessibleNamespaces[0] != "restricted" {
+		t.Errorf("expected only the restricted namespace to be reported as inaccessible, got %v", result.InaccessibleNamespaces)
+	}
+	for namespace, expectedToExist := range map[string]bool{"default": false, "restricted": true, "unwatched": true} {
+		_, err := dynamicClient.Resource(podsGVR).Namespace(namespace).Get(context.TODO(), "expired-pod-name", metav1.GetOptions{})
+		if exists := err == nil; exists != expectedToExist {
+			t.Errorf("expected pod in namespace %s to exist to be %t, got error %v", namespace, expectedToExist, err)
+		}
+	}
+}
+
+func TestReconcile_withMaxDeletionsPerNamespace(t *testing.T) {
+	defer func(previous int) { maxDeletionsPerNamespace = previous }(maxDeletionsPerNamespace)
+	maxDeletionsPerNamespace = 2
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pods := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name-1", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name-2", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name-3", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "other", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+	}
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace(pod.GetNamespace()).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 1 {
+		t.Errorf("expected 1 pod to be left in the default namespace, got %v", remaining)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "other"); len(remaining) != 0 {
+		t.Errorf("expected no pods to be left in the other namespace, got %v", remaining)
+	}
+	if deletedResourcesPerNamespaceInExecution["default"] != 2 || deletedResourcesPerNamespaceInExecution["other"] != 1 {
+		t.Errorf("expected 2 deletions in the default namespace and 1 in the other namespace, got %v", deletedResourcesPerNamespaceInExecution)
+	}
+}
+
+func TestReconcile_withMaxNamespaceDeletionsPerExecution(t *testing.T) {
+	defer func(previous int) { maxNamespaceDeletionsPerExecution = previous }(maxNamespaceDeletionsPerExecution)
+	defer func(previous time.Duration) { namespaceDeletionThrottle = previous }(namespaceDeletionThrottle)
+	defer func(previous []string) { protectedKinds = previous }(protectedKinds)
+	maxNamespaceDeletionsPerExecution = 2
+	namespaceDeletionThrottle = 50 * time.Millisecond
+	protectedKinds = nil
+	namespacesAPIResourceList := &metav1.APIResourceList{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "namespaces", Kind: "Namespace", Namespaced: false, Verbs: []string{"delete", "get", "list"}}},
+	}
+	kubernetesClient, dynamicClient, eventManager := newTestClients(namespacesAPIResourceList, podsAPIResourceList)
+	for i := 1; i <= 3; i++ {
+		namespace := newUnstructuredWithAnnotations("v1", "Namespace", "", fmt.Sprintf("expired-namespace-name-%d", i), time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+		if _, err := dynamicClient.Resource(namespacesGVR).Create(context.TODO(), namespace, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	for i := 1; i <= 3; i++ {
+		pod := newUnstructuredWithAnnotations("v1", "Pod", "default", fmt.Sprintf("expired-pod-name-%d", i), time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	start := time.Now()
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 2*namespaceDeletionThrottle {
+		t.Errorf("expected the execution to pause for %s after each namespace deletion, took %s", namespaceDeletionThrottle, elapsed)
+	}
+	if remaining := listNames(t, dynamicClient, namespacesGVR, ""); len(remaining) != 1 {
+		t.Errorf("expected 1 namespace to be left, got %v", remaining)
+	}
+	// Other resources are not affected by the limit
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 0 {
+		t.Errorf("expected no pods to be left, got %v", remaining)
+	}
+}
+
+func TestRecordDeletion_withNamespaceDeletionThrottle(t *testing.T) {
+	defer func(previous time.Duration) { namespaceDeletionThrottle = previous }(namespaceDeletionThrottle)
+	defer func(previous time.Time) { deletionsPausedUntil = previous }(deletionsPausedUntil)
+	namespaceDeletionThrottle = 100 * time.Millisecond
+	namespace := newUnstructuredWithAnnotations("v1", "Namespace", "", "namespace-name", time.Now(), map[string]interface{}{})
+	start := time.Now()
+	deletionMutex.Lock()
+	recordDeletion(namespacesGVR, *namespace)
+	deletionMutex.Unlock()
+	if elapsed := time.Since(start); elapsed >= namespaceDeletionThrottle {
+		t.Errorf("expected the pause not to be taken while holding the deletion mutex, took %s", elapsed)
+	}
+	waitForDeletionPause()
+	if elapsed := time.Since(start); elapsed < namespaceDeletionThrottle {
+		t.Errorf("expected the next deletion to wait for %s, only waited %s", namespaceDeletionThrottle, elapsed)
+	}
+}
+
+func TestReconcile_withPreDeletionWarning(t *testing.T) {
+	defer func(previous time.Duration) { preDeletionWarning = previous }(preDeletionWarning)
+	preDeletionWarning = 10 * time.Minute
+	warnedBeforeDeletion.Range(func(key, _ any) bool {
+		warnedBeforeDeletion.Delete(key)
+		return true
+	})
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pods := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expiring-soon-pod-name", time.Now().Add(-55*time.Minute), map[string]interface{}{AnnotationTTL: "1h"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "not-expiring-soon-pod-name", time.Now(), map[string]interface{}{AnnotationTTL: "1h"}),
+	}
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// Reconcile twice to make sure that the warning is only created once
+	for i := 0; i < 2; i++ {
+		if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if warned := captureState().WarnedBeforeDeletion; len(warned) != 1 {
+		t.Errorf("expected a warning to have been created for 1 pod, got %d", len(warned))
+	}
+	if _, warned := warnedBeforeDeletion.Load(podsGVR.String() + "|default/expiring-soon-pod-name"); !warned {
+		t.Error("expected a warning to have been created for the pod expiring soon")
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 2 {
+		t.Errorf("expected no pods to have been deleted, got %v", remaining)
+	}
+}
+
+func TestReconcile_withDryRun(t *testing.T) {
+	defer func(previous bool) { dryRun = previous }(dryRun)
+	dryRun = true
+	deletionCandidates = nil
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pods := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "not-expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "3d"}),
+	}
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 2 {
+		t.Errorf("expected no pods to have been deleted, got %v", remaining)
+	}
+	if deletedResourcesInExecution != 0 {
+		t.Errorf("expected no deletions to have been recorded, got %d", deletedResourcesInExecution)
+	}
+	if len(deletionCandidates) != 1 || deletionCandidates[0].name != "expired-pod-name" {
+		t.Fatalf("expected the expired pod to be the only deletion candidate, got %v", deletionCandidates)
+	}
+	report := &bytes.Buffer{}
+	printObservationReport(report)
+	if !strings.Contains(report.String(), "expired-pod-name") || !strings.Contains(report.String(), "1 resources would have been deleted") {
+		t.Errorf("expected the report to contain the expired pod, got:\n%s", report.String())
+	}
+}
+
+func TestPrintObservationReport_withKubectlFormat(t *testing.T) {
+	defer func(previous []deletionCandidate) { deletionCandidates = previous }(deletionCandidates)
+	defer func(previous string) { observeFormat = previous }(observeFormat)
+	observeFormat = ObserveFormatKubectl
+	deletionCandidates = []deletionCandidate{
+		{gvr: podsGVR, namespace: "default", name: "expired-pod-name", reason: "TTL of 5m expired 55m0s ago"},
+		{gvr: deploymentsGVR, namespace: "ci", name: "expired-deployment-name", reason: "TTL of 1h expired 3m12s ago"},
+		{gvr: namespacesGVR, name: "expired-namespace-name", reason: "multi-line\nreason"},
+	}
+	report := &bytes.Buffer{}
+	printObservationReport(report)
+	expected := `# 3 resources would have been deleted
+kubectl delete pods expired-pod-name -n default # TTL of 5m expired 55m0s ago
+kubectl delete deployments.apps expired-deployment-name -n ci # TTL of 1h expired 3m12s ago
+kubectl delete namespaces expired-namespace-name # multi-line reason
+`
+	if report.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, report.String())
+	}
+}
+
+func TestReconcile_withExcludedOwnerKinds(t *testing.T) {
+	defer func(previous []string) { excludedOwnerKinds = previous }(excludedOwnerKinds)
+	excludedOwnerKinds = []string{"Application", "HelmRelease"}
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	excludedPod := newUnstructuredWithAnnotations("v1", "Pod", "default", "excluded-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	excludedPod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "argoproj.io/v1alpha1", Kind: "Application", Name: "application-name"}})
+	ownedPod := newUnstructuredWithAnnotations("v1", "Pod", "default", "owned-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	ownedPod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "replicaset-name"}})
+	for _, pod := range []*unstructured.Unstructured{excludedPod, ownedPod} {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 1 || !remaining["excluded-pod-name"] {
+		t.Errorf("expected only the pod owned by an excluded kind to be left, got %v", remaining)
+	}
+}
+
+func TestReconcile_withLabelTTLs(t *testing.T) {
+	defer func(previous []LabelTTL) { labelTTLs = previous }(labelTTLs)
+	var err error
+	if labelTTLs, err = parseLabelTTLs("tier=ephemeral:30m,tekton.dev/pipelineRun:2h"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pods := map[string]*unstructured.Unstructured{
+		"ephemeral-pod-name":           newUnstructuredWithAnnotations("v1", "Pod", "default", "ephemeral-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{}),
+		"stable-pod-name":              newUnstructuredWithAnnotations("v1", "Pod", "default", "stable-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{}),
+		"pipelinerun-pod-name":         newUnstructuredWithAnnotations("v1", "Pod", "default", "pipelinerun-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{}),
+		"annotated-ephemeral-pod-name": newUnstructuredWithAnnotations("v1", "Pod", "default", "annotated-ephemeral-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "2h"}),
+	}
+	pods["ephemeral-pod-name"].SetLabels(map[string]string{"tier": "ephemeral"})
+	pods["stable-pod-name"].SetLabels(map[string]string{"tier": "stable"})
+	pods["pipelinerun-pod-name"].SetLabels(map[string]string{"tekton.dev/pipelineRun": "build-1234"})
+	pods["annotated-ephemeral-pod-name"].SetLabels(map[string]string{"tier": "ephemeral"})
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// The annotation takes precedence over the label, so only the pod with a label mapped to an expired TTL is deleted
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 3 || remaining["ephemeral-pod-name"] {
+		t.Errorf("expected only the ephemeral pod without a TTL annotation to be deleted, got %v", remaining)
+	}
+}
+
+func TestReconcile_withTTLTooLong(t *testing.T) {
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	// Without bounds checking, this TTL would overflow into a negative duration, which would make the pod expire immediately
+	pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "P1000000D"})
+	if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 1 {
+		t.Errorf("expected the pod with a TTL that is too long not to be deleted, got %v", remaining)
+	}
+	events, err := kubernetesClient.CoreV1().Events("default").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events.Items) != 1 || events.Items[0].Reason != "InvalidTTL" {
+		t.Errorf("expected an InvalidTTL event, got %v", events.Items)
+	}
+}
+
+func TestParseLabelTTLs(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		value         string
+		expected      []LabelTTL
+		expectedError bool
+	}{
+		{
+			name:     "empty",
+			value:    "",
+			expected: nil,
+		},
+		{
+			name:     "key-and-value",
+			value:    "tier=ephemeral:1d",
+			expected: []LabelTTL{{Key: "tier", Value: "ephemeral", TTL: "1d"}},
+		},
+		{
+			name:     "multiple",
+			value:    "tekton.dev/pipelineRun:2h, tier=ephemeral:PT30M",
+			expected: []LabelTTL{{Key: "tekton.dev/pipelineRun", TTL: "2h"}, {Key: "tier", Value: "ephemeral", TTL: "PT30M"}},
+		},
+		{
+			name:          "missing-ttl",
+			value:         "tier=ephemeral",
+			expectedError: true,
+		},
+		{
+			name:          "invalid-ttl",
+			value:         "tier=ephemeral:soon",
+			expectedError: true,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			labelTTLs, err := parseLabelTTLs(scenario.value)
+			if scenario.expectedError != (err != nil) {
+				t.Fatalf("expected error to be %t, got %v", scenario.expectedError, err)
+			}
+			if len(labelTTLs) != len(scenario.expected) {
+				t.Fatalf("expected %v, got %v", scenario.expected, labelTTLs)
+			}
+			for i := range labelTTLs {
+				if labelTTLs[i] != scenario.expected[i] {
+					t.Errorf("expected %v, got %v", scenario.expected[i], labelTTLs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReconcile_withProtectedLabels(t *testing.T) {
+	defer func(previous map[string]string) { protectedLabels = previous }(protectedLabels)
+	protectedLabels = parseProtectedLabels("app.kubernetes.io/managed-by=Helm,do-not-delete")
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	labelsByPodName := map[string]map[string]string{
+		"helm-pod-name":          {"app.kubernetes.io/managed-by": "Helm"},
+		"do-not-delete-pod-name": {"do-not-delete": "anything"},
+		"kustomize-pod-name":     {"app.kubernetes.io/managed-by": "Kustomize"},
+		"unlabeled-pod-name":     nil,
+	}
+	for name, labels := range labelsByPodName {
+		pod := newUnstructuredWithAnnotations("v1", "Pod", "default", name, time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+		pod.SetLabels(labels)
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 2 || !remaining["helm-pod-name"] || !remaining["do-not-delete-pod-name"] {
+		t.Errorf("expected only the pods with a protected label to be left, got %v", remaining)
+	}
+}
+
+func TestReconcile_withNamespacesToExclude(t *testing.T) {
+	defer func(previous []string) { namespacesToExclude = previous }(namespacesToExclude)
+	scenarios := []struct {
+		name                string
+		namespacesToExclude []string
+		expectedRemaining   []string
+	}{
+		{
+			name:                "default",
+			namespacesToExclude: DefaultNamespacesToExclude,
+			expectedRemaining:   []string{"kube-system", "kube-public", "kube-node-lease"},
+		},
+		{
+			name:                "custom",
+			namespacesToExclude: parseNamespacesToExclude("team-a, kube-system"),
+			expectedRemaining:   []string{"kube-system", "team-a"},
+		},
+		{
+			name:                "disabled",
+			namespacesToExclude: parseNamespacesToExclude(""),
+			expectedRemaining:   nil,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			namespacesToExclude = scenario.namespacesToExclude
+			kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+			namespaces := []string{"kube-system", "kube-public", "kube-node-lease", "team-a", "default"}
+			for _, namespace := range namespaces {
+				pod := newUnstructuredWithAnnotations("v1", "Pod", namespace, "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+				if _, err := dynamicClient.Resource(podsGVR).Namespace(namespace).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			for _, namespace := range namespaces {
+				expectedRemaining := contains(scenario.expectedRemaining, namespace)
+				if remaining := listNames(t, dynamicClient, podsGVR, namespace)["expired-pod-name"]; remaining != expectedRemaining {
+					t.Errorf("expected the pod in namespace %s to remain=%t, got %t", namespace, expectedRemaining, remaining)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcile_withReapOrphans(t *testing.T) {
+	defer func(previous bool, previousGracePeriod time.Duration) {
+		reapOrphans, orphanGracePeriod = previous, previousGracePeriod
+	}(reapOrphans, orphanGracePeriod)
+	reapOrphans, orphanGracePeriod = true, time.Hour
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList, deploymentsAPIResourceList)
+	deployment := newUnstructuredWithAnnotations("apps/v1", "Deployment", "default", "deployment-name", time.Now(), nil)
+	deployment.SetUID("deployment-uid")
+	if _, err := dynamicClient.Resource(deploymentsGVR).Namespace("default").Create(context.TODO(), deployment, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	orphanedPod := newUnstructuredWithAnnotations("v1", "Pod", "default", "orphaned-pod-name", time.Now(), nil)
+	orphanedPod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "deleted-deployment-name", UID: "deleted-deployment-uid"}})
+	ownerRecreatedPod := newUnstructuredWithAnnotations("v1", "Pod", "default", "owner-recreated-pod-name", time.Now(), nil)
+	ownerRecreatedPod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "deployment-name", UID: "previous-deployment-uid"}})
+	ownedPod := newUnstructuredWithAnnotations("v1", "Pod", "default", "owned-pod-name", time.Now(), nil)
+	ownedPod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "deployment-name", UID: "deployment-uid"}})
+	unownedPod := newUnstructuredWithAnnotations("v1", "Pod", "default", "unowned-pod-name", time.Now(), nil)
+	for _, pod := range []*unstructured.Unstructured{orphanedPod, ownerRecreatedPod, ownedPod, unownedPod} {
+		pod.SetUID(types.UID(pod.GetName() + "-uid"))
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 4 {
+		t.Errorf("expected no pods to be deleted before the end of the orphan grace period, got %v", remaining)
+	}
+	// Pretend that the pods were found to be orphaned before the beginning of the grace period
+	for _, orphan := range orphanTracker.Snapshot() {
+		orphanTracker.orphans[orphan.uid].since = time.Now().Add(-2 * time.Hour)
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 2 || !remaining["owned-pod-name"] || !remaining["unowned-pod-name"] {
+		t.Errorf("expected only the pods that aren't orphaned to be left, got %v", remaining)
+	}
+	if orphans := orphanTracker.Snapshot(); len(orphans) != 0 {
+		t.Errorf("expected the deleted orphans to have been forgotten, got %v", orphans)
+	}
+}
+
+func TestReconcile_withReapCascade(t *testing.T) {
+	defer func(previous *CascadeTracker) { cascadeTracker = previous }(cascadeTracker)
+	cascadeTracker = NewCascadeTracker()
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList, deploymentsAPIResourceList)
+	parent := newUnstructuredWithAnnotations("apps/v1", "Deployment", "default", "parent-name", time.Now(), nil)
+	parent.SetLabels(map[string]string{LabelCascadeID: "project-foo"})
+	if _, err := dynamicClient.Resource(deploymentsGVR).Namespace("default").Create(context.TODO(), parent, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	child := newUnstructuredWithAnnotations("v1", "Pod", "default", "child-pod-name", time.Now(), nil)
+	child.SetLabels(map[string]string{LabelCascadeFrom: "project-foo"})
+	childOfUnknownParent := newUnstructuredWithAnnotations("v1", "Pod", "default", "child-of-unknown-parent-pod-name", time.Now(), nil)
+	childOfUnknownParent.SetLabels(map[string]string{LabelCascadeFrom: "project-bar"})
+	unrelatedPod := newUnstructuredWithAnnotations("v1", "Pod", "default", "unrelated-pod-name", time.Now(), nil)
+	for _, pod := range []*unstructured.Unstructured{child, childOfUnknownParent, unrelatedPod} {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 3 {
+		t.Errorf("expected no pods to be deleted while the parent exists, got %v", remaining)
+	}
+	if err := dynamicClient.Resource(deploymentsGVR).Namespace("default").Delete(context.TODO(), "parent-name", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 2 || remaining["child-pod-name"] {
+		t.Errorf("expected only the child of the deleted parent to be deleted, got %v", remaining)
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if known, deleted := cascadeTracker.Snapshot(); len(known) != 0 || len(deleted) != 0 {
+		t.Errorf("expected the deleted parent to have been forgotten once it no longer had children, got known=%v and deleted=%v", known, deleted)
+	}
+}
+
+func TestReconcile_withReapCascadeAndNarrowedResourcesToWatch(t *testing.T) {
+	defer func(previous *CascadeTracker) { cascadeTracker = previous }(cascadeTracker)
+	defer func(previous []string) { apiResourcesToWatch = previous }(apiResourcesToWatch)
+	cascadeTracker = NewCascadeTracker()
+	apiResourcesToWatch = nil
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList, deploymentsAPIResourceList)
+	parent := newUnstructuredWithAnnotations("apps/v1", "Deployment", "default", "parent-name", time.Now(), nil)
+	parent.SetLabels(map[string]string{LabelCascadeID: "project-foo"})
+	if _, err := dynamicClient.Resource(deploymentsGVR).Namespace("default").Create(context.TODO(), parent, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	child := newUnstructuredWithAnnotations("v1", "Pod", "default", "child-pod-name", time.Now(), nil)
+	child.SetLabels(map[string]string{LabelCascadeFrom: "project-foo"})
+	if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), child, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// Deployments are no longer listed, e.g. after the configuration was reloaded, which doesn't mean the parent is gone
+	apiResourcesToWatch = []string{"pods"}
+	for i := 0; i < 2; i++ {
+		if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 1 {
+		t.Errorf("expected the child to be left alone while its parent isn't listed, got %v", remaining)
+	}
+	if known, deleted := cascadeTracker.Snapshot(); len(known) != 1 || len(deleted) != 0 {
+		t.Errorf("expected the parent to still be known and not deleted, got known=%v and deleted=%v", known, deleted)
+	}
+	// Once deployments are listed again, the deletion of the parent is detected
+	apiResourcesToWatch = nil
+	if err := dynamicClient.Resource(deploymentsGVR).Namespace("default").Delete(context.TODO(), "parent-name", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 0 {
+		t.Errorf("expected the child of the deleted parent to be deleted, got %v", remaining)
+	}
+}
+
+func TestGetPropagationPolicy(t *testing.T) {
+	defer func(previous *metav1.DeletionPropagation, previousByKind map[string]metav1.DeletionPropagation) {
+		propagationPolicy, propagationPolicyByKind = previous, previousByKind
+	}(propagationPolicy, propagationPolicyByKind)
+	propagationPolicy, propagationPolicyByKind = nil, map[string]metav1.DeletionPropagation{"Deployment": metav1.DeletePropagationForeground}
+	if policy := getPropagationPolicy("Pod"); policy != nil {
+		t.Errorf("expected the API server's default to be used when no default policy is configured, got %s", *policy)
+	}
+	background := metav1.DeletePropagationBackground
+	propagationPolicy = &background
+	if policy := getPropagationPolicy("Pod"); policy == nil || *policy != metav1.DeletePropagationBackground {
+		t.Errorf("expected the default policy to be used for kinds without a policy of their own, got %v", policy)
+	}
+	if policy := getPropagationPolicy("Deployment"); policy == nil || *policy != metav1.DeletePropagationForeground {
+		t.Errorf("expected the policy of the kind to take precedence over the default policy, got %v", policy)
+	}
+	for _, value := range []string{"Foreground", "Background", "Orphan"} {
+		if _, err := parsePropagationPolicy(value); err != nil {
+			t.Errorf("expected %s to be a valid propagation policy, got %v", value, err)
+		}
+	}
+	if _, err := parsePropagationPolicy("foreground"); err == nil {
+		t.Error("expected propagation policies to be case-sensitive")
+	}
+}
+
+func TestReconcile_doesNotLeakGoroutines(t *testing.T) {
+	defer func(previous time.Duration) { executionTimeout = previous }(executionTimeout)
+	executionTimeout = time.Hour
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	before := goruntime.NumGoroutine()
+	for i := 0; i < 5; i++ {
+		if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// Give the goroutines a moment to exit, as they may still be returning
+	for deadline := time.Now().Add(time.Second); goruntime.NumGoroutine() > before && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := goruntime.NumGoroutine(); after > before {
+		t.Errorf("expected executions that finished before timing out not to leave goroutines behind, went from %d to %d goroutines", before, after)
+	}
+}
+
+func TestReconcile_withTimeout(t *testing.T) {
+	defer func(previous time.Duration) { executionTimeout = previous }(executionTimeout)
+	executionTimeout = 100 * time.Millisecond
+	configMapsAPIResourceList := &metav1.APIResourceList{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: []string{"delete", "get", "list"}}},
+	}
+	cancelled := make(chan struct{})
+	var podsListed atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/configmaps":
+			// Hang until the client gives up, like an overloaded API server would
+			select {
+			case <-r.Context().Done():
+				close(cancelled)
+			case <-time.After(10 * time.Second):
+			}
+		case "/api/v1/pods":
+			podsListed.Store(true)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"apiVersion":"v1","kind":"List","items":[]}`))
+	}))
+	defer server.Close()
+	dynamicClient, err := dynamic.NewForConfig(&rest.Config{Host: server.URL, QPS: -1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Resources are reconciled in alphabetical order, so configmaps are listed before pods
+	kubernetesClient, _, eventManager := newTestClients(configMapsAPIResourceList, podsAPIResourceList)
+	start := time.Now()
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); !errors.Is(err, ErrTimedOut) {
+		t.Errorf("expected %v, got %v", ErrTimedOut, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the execution to time out after %s, took %s", executionTimeout, elapsed)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("expected the list request in progress to have been cancelled")
+	}
+	// Give the cancelled execution a moment to move on, which it must not do by listing the next resource
+	time.Sleep(100 * time.Millisecond)
+	if podsListed.Load() {
+		t.Error("expected the execution to stop after being cancelled, but it listed the next resource")
+	}
+}
+
+func TestGetExitCode(t *testing.T) {
+	defer func(previous bool) { failIfNothingDeleted = previous }(failIfNothingDeleted)
+	scenarios := []struct {
+		name                 string
+		result               ExecutionResult
+		err                  error
+		failIfNothingDeleted bool
+		expectedExitCode     int
+	}{
+		{name: "success", result: ExecutionResult{Deleted: 1}, expectedExitCode: ExitCodeSuccess},
+		{name: "success-without-deletions", result: ExecutionResult{}, expectedExitCode: ExitCodeSuccess},
+		{name: "discovery-failed", err: &DiscoveryError{Err: errors.New("unreachable")}, expectedExitCode: ExitCodeDiscoveryFailed},
+		{name: "timed-out", err: &TimeoutError{Timeout: ExecutionTimeout}, expectedExitCode: ExitCodeTimedOut},
+		{name: "partial-list-failure", result: ExecutionResult{Deleted: 1}, err: &PartialListError{Failures: map[schema.GroupVersionResource]error{podsGVR: errors.New("unavailable")}}, expectedExitCode: ExitCodeSuccess},
+		{name: "unknown-error", err: errors.New("unknown"), expectedExitCode: ExitCodeFailure},
+		{name: "deletions-failed", result: ExecutionResult{Deleted: 1, FailedDeletions: 1}, expectedExitCode: ExitCodeDeletionsFailed},
+		{name: "nothing-deleted", result: ExecutionResult{}, failIfNothingDeleted: true, expectedExitCode: ExitCodeNothingDeleted},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			failIfNothingDeleted = scenario.failIfNothingDeleted
+			if exitCode := getExitCode(scenario.result, scenario.err); exitCode != scenario.expectedExitCode {
+				t.Errorf("expected exit code %d, got %d", scenario.expectedExitCode, exitCode)
+			}
+		})
+	}
+}
+
+func TestReconcile_withScheduledDeletions(t *testing.T) {
+	defer func(previous *DeletionScheduler) { scheduler = previous }(scheduler)
+	scheduler = NewDeletionScheduler(1)
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pods := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expiring-pod-name-1", time.Now(), map[string]interface{}{AnnotationTTL: "2s"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expiring-pod-name-2", time.Now(), map[string]interface{}{AnnotationTTL: "2s"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "not-expired-pod-name", time.Now(), map[string]interface{}{AnnotationTTL: "3d"}),
+	}
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// Only one deletion may be scheduled per execution, so only one of the two expiring pods should be deleted
+	deadline := time.Now().Add(10 * time.Second)
+	for len(listNames(t, dynamicClient, podsGVR, "default")) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected one of the expiring pods to have been deleted by the scheduler, got %v", listNames(t, dynamicClient, podsGVR, "default"))
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); !remaining["not-expired-pod-name"] {
+		t.Errorf("expected the pod that has not expired to have been left alone, got %v", remaining)
+	}
+}
+
+var (
+	podsGVR        = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
+	deploymentsGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+	podsAPIResourceList = &metav1.APIResourceList{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "pods", Kind: "Pod", Namespaced: true, Verbs: []string{"delete", "get", "list"}}},
+	}
+	deploymentsAPIResourceList = &metav1.APIResourceList{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: []string{"delete", "get", "list"}}},
+	}
+)
+
+// newTestClients creates fake clients whose discovery API returns the given API resources
+func newTestClients(apiResourceLists ...*metav1.APIResourceList) (*fakekubernetes.Clientset, *fakedynamic.FakeDynamicClient, *kevent.EventManager) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = v1.AddToScheme(scheme)
+	_ = metav1.AddMetaToScheme(scheme)
+	kubernetesClient := fakekubernetes.NewSimpleClientset()
+	dynamicClient := fakedynamic.NewSimpleDynamicClient(scheme)
+	eventManager := kevent.NewEventManager(kubernetesClient, "k8s-ttl-controller")
+	fakeDiscovery, _ := kubernetesClient.Discovery().(*fakediscovery.FakeDiscovery)
+	fakeDiscovery.Fake.Resources = apiResourceLists
+	return kubernetesClient, dynamicClient, eventManager
+}
+
+// listNames returns the names of all resources of a given GVR in a namespace
+func listNames(t *testing.T, dynamicClient *fakedynamic.FakeDynamicClient, gvr schema.GroupVersionResource, namespace string) map[string]bool {
+	list, err := dynamicClient.Resource(gvr).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, item := range list.Items {
+		names[item.GetName()] = true
+	}
+	return names
+}
+
+func withDeletionTimestamp(u *unstructured.Unstructured, deletionTimestamp time.Time) *unstructured.Unstructured {
+	timestamp := metav1.NewTime(deletionTimestamp)
+	u.SetDeletionTimestamp(&timestamp)
+	u.SetFinalizers([]string{"foregroundDeletion"})
+	return u
+}
+
+type memoryPublisher struct {
+	messages []DeletionMessage
+}
+
+func (p *memoryPublisher) Publish(message DeletionMessage) error {
+	p.messages = append(p.messages, message)
+	return nil
+}
+
+func (p *memoryPublisher) Close() {}
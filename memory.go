@@ -0,0 +1,59 @@
+package main
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// LowMemoryListLimit is the maximum number of items to list at once in low memory mode
+const LowMemoryListLimit = 100
+
+// getListLimit returns the maximum number of items to list at once, which is lower in low memory mode so that fewer
+// items are held in memory at any given time
+func getListLimit() int64 {
+	if lowMemoryMode {
+		return LowMemoryListLimit
+	}
+	return ListLimit
+}
+
+// retainItem returns the copy of an item that should be retained until every item of its resource has been listed,
+// which is the case for items in keep-last groups and for items counted toward the maximum count per owner.
+//
+// In low memory mode, the managed fields of the item are left out, as they are often larger than the rest of the item.
+// The only exception is the entry of CoordinationFieldManager, which getOwnResourceReason relies on to recognize the
+// controller's own objects. The item itself is left untouched.
+func retainItem(item unstructured.Unstructured) unstructured.Unstructured {
+	if !lowMemoryMode {
+		return item
+	}
+	metadata, ok := item.Object["metadata"].(map[string]interface{})
+	if !ok {
+		return item
+	}
+	if _, exists := metadata["managedFields"]; !exists {
+		return item
+	}
+	retained := make(map[string]interface{}, len(item.Object))
+	for key, value := range item.Object {
+		retained[key] = value
+	}
+	retainedMetadata := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		if key != "managedFields" {
+			retainedMetadata[key] = value
+		}
+	}
+	if managedFields, ok := metadata["managedFields"].([]interface{}); ok {
+		var retainedManagedFields []interface{}
+		for _, entry := range managedFields {
+			if fields, ok := entry.(map[string]interface{}); ok && fields["manager"] == CoordinationFieldManager {
+				retainedManagedFields = append(retainedManagedFields, entry)
+			}
+		}
+		if len(retainedManagedFields) > 0 {
+			retainedMetadata["managedFields"] = retainedManagedFields
+		}
+	}
+	retained["metadata"] = retainedMetadata
+	return unstructured.Unstructured{Object: retained}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRetainItem(t *testing.T) {
+	defer func(previous bool) { lowMemoryMode = previous }(lowMemoryMode)
+	item := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now(), map[string]interface{}{AnnotationTTL: "5m"})
+	item.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationApply}})
+	lowMemoryMode = false
+	if retained := retainItem(*item); len(retained.GetManagedFields()) != 1 {
+		t.Error("expected managed fields to be retained outside of low memory mode")
+	}
+	lowMemoryMode = true
+	retained := retainItem(*item)
+	if len(retained.GetManagedFields()) != 0 {
+		t.Error("expected managed fields not to be retained in low memory mode")
+	}
+	if retained.GetName() != "pod-name" || retained.GetAnnotations()[AnnotationTTL] != "5m" {
+		t.Errorf("expected the rest of the metadata to be retained, got %v", retained.Object["metadata"])
+	}
+	if len(item.GetManagedFields()) != 1 {
+		t.Error("expected the original item to be left untouched")
+	}
+	item.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationApply}, {Manager: CoordinationFieldManager, Operation: metav1.ManagedFieldsOperationApply}})
+	retained = retainItem(*item)
+	if managedFields := retained.GetManagedFields(); len(managedFields) != 1 || managedFields[0].Manager != CoordinationFieldManager {
+		t.Errorf("expected only the managed fields of %s to be retained in low memory mode, got %v", CoordinationFieldManager, managedFields)
+	}
+	if _, own := getOwnResourceReason(podsGVR, retained); !own {
+		t.Error("expected the retained item to still be recognized as the controller's own")
+	}
+}
+
+func TestReconcile_withLowMemoryModeAndKeepLast(t *testing.T) {
+	defer func(previous bool) { lowMemoryMode = previous }(lowMemoryMode)
+	lowMemoryMode = true
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pods := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "own-pod-name", time.Now().Add(-3*time.Hour), map[string]interface{}{AnnotationTTL: "5m", AnnotationKeepLast: "1"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name-1", time.Now().Add(-2*time.Hour), map[string]interface{}{AnnotationTTL: "5m", AnnotationKeepLast: "1"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name-2", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m", AnnotationKeepLast: "1"}),
+	}
+	pods[0].SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationApply}, {Manager: CoordinationFieldManager, Operation: metav1.ManagedFieldsOperationApply}})
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, podsGVR, "default"); len(remaining) != 2 || !remaining["own-pod-name"] || !remaining["expired-pod-name-2"] {
+		t.Errorf("expected the pod managed by the controller and the most recent pod to be left, got %v", remaining)
+	}
+}
+
+func TestGetListLimit(t *testing.T) {
+	defer func(previous bool) { lowMemoryMode = previous }(lowMemoryMode)
+	lowMemoryMode = false
+	if limit := getListLimit(); limit != ListLimit {
+		t.Errorf("expected %d, got %d", ListLimit, limit)
+	}
+	lowMemoryMode = true
+	if limit := getListLimit(); limit != LowMemoryListLimit {
+		t.Errorf("expected %d, got %d", LowMemoryListLimit, limit)
+	}
+}
+
+func TestReconcile_withLowMemoryModeAndDryRun(t *testing.T) {
+	defer func(previousDryRun, previousLowMemoryMode bool) {
+		dryRun, lowMemoryMode = previousDryRun, previousLowMemoryMode
+		deletionCandidates, deletionCandidatesTotal = nil, 0
+	}(dryRun, lowMemoryMode)
+	dryRun, lowMemoryMode = true, true
+	deletionCandidates, deletionCandidatesTotal = nil, 0
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pods := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "not-expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "3d"}),
+	}
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(deletionCandidates) != 0 {
+		t.Errorf("expected deletion candidates not to be kept in low memory mode, got %v", deletionCandidates)
+	}
+	report := &bytes.Buffer{}
+	printObservationReport(report)
+	if strings.Contains(report.String(), "expired-pod-name") || !strings.Contains(report.String(), "1 resources would have been deleted") {
+		t.Errorf("expected the report to only contain the number of deletion candidates, got:\n%s", report.String())
+	}
+}
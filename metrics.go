@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	MetricsNamespace = "k8s_ttl_controller"
+
+	MetricsServerShutdownTimeout = 5 * time.Second // Maximum time to wait for in-flight scrapes to complete on shutdown
+)
+
+var (
+	reconcileGapSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "reconcile_gap_seconds",
+		Help:      "Wall-clock time between the start of the two most recent reconciliations",
+	})
+	consecutiveFailures = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "consecutive_failures",
+		Help:      "Number of consecutive failed executions, the controller panics once it exceeds the maximum allowed",
+	})
+	unlistableResourcesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "unlistable_resources_total",
+		Help:      "Number of times a resource was skipped because listing it returned 405 Method Not Allowed",
+	}, []string{"group", "version", "resource"})
+	executionFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "execution_failures_total",
+		Help:      "Number of failed executions, by reason",
+	}, []string{"reason"})
+	unannotatedResourcesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "unannotated_resources_total",
+		Help:      "Number of times a resource without any annotations was checked, only tracked if REPORT_UNANNOTATED_RESOURCES is true",
+	}, []string{"group", "version", "resource"})
+	deletedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "deleted_total",
+		Help:      "Number of resources deleted, by namespace",
+	}, []string{"namespace"})
+	deletionFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "deletion_failures_total",
+		Help:      "Number of resources that failed to be deleted, by namespace",
+	}, []string{"namespace"})
+	scannedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "scanned_total",
+		Help:      "Number of resources scanned, by group, version and resource",
+	}, []string{"group", "version", "resource"})
+	lastSuccessfulReconcileTimestampSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "last_successful_reconcile_timestamp_seconds",
+		Help:      "Unix timestamp at which the most recent successful reconciliation ended",
+	})
+	reconcileDurationSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: MetricsNamespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Duration of reconciliations, whether they succeeded or not",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200},
+	})
+	throttleSecondsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "throttle_seconds_total",
+		Help:      "Total time spent sleeping to throttle calls to the API server",
+	})
+	circuitBreakerTrippedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "circuit_breaker_tripped_total",
+		Help:      "Number of executions during which deletions were halted because too many of them failed",
+	})
+)
+
+// startMetricsServer starts an HTTP server exposing the Prometheus metrics on /metrics, as well as the status of
+// resources on /status, in the background.
+//
+// Returns an error if the port cannot be listened on (e.g. because it is already in use).
+func startMetricsServer(port int) (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/status", NewStatusHandler(CreateClients))
+	server := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: mux}
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		logger.Info(fmt.Sprintf("Starting metrics server on port %d", port))
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(fmt.Sprintf("Metrics server failed: %s", err))
+		}
+	}()
+	return server, nil
+}
+
+// shutdownMetricsServer gracefully shuts down the metrics server, waiting for in-flight scrapes to complete
+func shutdownMetricsServer(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), MetricsServerShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error(fmt.Sprintf("Failed to gracefully shut down metrics server: %s", err))
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestMetricsServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+	server, err := startMetricsServer(port)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	url := fmt.Sprintf("http://127.0.0.1:%d/metrics", port)
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The same server must keep serving metrics across executions
+	for i := 0; i < 3; i++ {
+		if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if err := waitForMetrics(url); err != nil {
+			t.Fatalf("expected metrics to be served after execution #%d, got %v", i+1, err)
+		}
+	}
+	shutdownMetricsServer(server)
+	if response, err := http.Get(url); err == nil {
+		_ = response.Body.Close()
+		t.Error("expected metrics to no longer be served after the server was shut down")
+	}
+}
+
+func TestMetricsServer_withPortInUse(t *testing.T) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer listener.Close()
+	if server, err := startMetricsServer(listener.Addr().(*net.TCPAddr).Port); err == nil {
+		shutdownMetricsServer(server)
+		t.Error("expected an error, because the port is already in use")
+	}
+}
+
+func TestReconcile_updatesMetrics(t *testing.T) {
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	pods := []*metav1.ObjectMeta{
+		{Namespace: "metrics", Name: "expired-pod-name"},
+		{Namespace: "metrics", Name: "undeletable-pod-name"},
+		{Namespace: "metrics", Name: "pod-name"},
+	}
+	for _, meta := range pods {
+		ttl := "5m"
+		if meta.Name == "pod-name" {
+			ttl = "1d"
+		}
+		pod := newUnstructuredWithAnnotations("v1", "Pod", meta.Namespace, meta.Name, time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: ttl})
+		if _, err := dynamicClient.Resource(podsGVR).Namespace(meta.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	dynamicClient.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.(k8stesting.DeleteAction).GetName() == "undeletable-pod-name" {
+			return true, nil, apierrors.NewInternalError(fmt.Errorf("simulated failure"))
+		}
+		return false, nil, nil
+	})
+	scanned := testutil.ToFloat64(scannedTotal.WithLabelValues(podsGVR.Group, podsGVR.Version, podsGVR.Resource))
+	deleted := testutil.ToFloat64(deletedTotal.WithLabelValues("metrics"))
+	failed := testutil.ToFloat64(deletionFailuresTotal.WithLabelValues("metrics"))
+	reconciliations := getHistogramSampleCount(t, reconcileDurationSeconds)
+	start := time.Now()
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if delta := testutil.ToFloat64(scannedTotal.WithLabelValues(podsGVR.Group, podsGVR.Version, podsGVR.Resource)) - scanned; delta != 3 {
+		t.Errorf("expected 3 pods to have been scanned, got %v", delta)
+	}
+	if delta := testutil.ToFloat64(deletedTotal.WithLabelValues("metrics")) - deleted; delta != 1 {
+		t.Errorf("expected 1 pod to have been deleted, got %v", delta)
+	}
+	if delta := testutil.ToFloat64(deletionFailuresTotal.WithLabelValues("metrics")) - failed; delta != 1 {
+		t.Errorf("expected 1 pod to have failed to be deleted, got %v", delta)
+	}
+	if delta := getHistogramSampleCount(t, reconcileDurationSeconds) - reconciliations; delta != 1 {
+		t.Errorf("expected the duration of 1 reconciliation to have been observed, got %d", delta)
+	}
+	if timestamp := testutil.ToFloat64(lastSuccessfulReconcileTimestampSeconds); timestamp < float64(start.Unix()) {
+		t.Errorf("expected the last successful reconciliation timestamp to be after %d, got %v", start.Unix(), timestamp)
+	}
+}
+
+// getHistogramSampleCount returns the number of observations made by a histogram
+func getHistogramSampleCount(t *testing.T, histogram prometheus.Histogram) uint64 {
+	metric := &dto.Metric{}
+	if err := histogram.Write(metric); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
+
+// waitForMetrics waits for the metrics server to respond successfully, since it is started in the background
+func waitForMetrics(url string) error {
+	var err error
+	for attempt := 0; attempt < 50; attempt++ {
+		var response *http.Response
+		if response, err = http.Get(url); err == nil {
+			_ = response.Body.Close()
+			if response.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = fmt.Errorf("unexpected status code %d", response.StatusCode)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return err
+}
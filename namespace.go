@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var namespacesGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+// DefaultNamespacesToExclude are the namespaces in which nothing is deleted unless NamespacesToExcludeEnv is set, because
+// they contain the components that keep the cluster running
+var DefaultNamespacesToExclude = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// NamespaceCache retrieves namespaces, caching every lookup so that each namespace is only retrieved once per execution
+type NamespaceCache struct {
+	dynamicClient dynamic.Interface
+	cache         map[string]namespaceLookupResult
+
+	terminating map[string]bool // Namespaces found to be terminating, which are only logged once per execution
+}
+
+type namespaceLookupResult struct {
+	namespace *unstructured.Unstructured
+	err       error
+}
+
+// NewNamespaceCache creates a new NamespaceCache
+func NewNamespaceCache(dynamicClient dynamic.Interface) *NamespaceCache {
+	return &NamespaceCache{dynamicClient: dynamicClient, cache: make(map[string]namespaceLookupResult), terminating: make(map[string]bool)}
+}
+
+// Get retrieves a namespace by name
+func (c *NamespaceCache) Get(name string) (*unstructured.Unstructured, error) {
+	if result, cached := c.cache[name]; cached {
+		return result.namespace, result.err
+	}
+	namespace, err := c.dynamicClient.Resource(namespacesGVR).Get(context.TODO(), name, metav1.GetOptions{})
+	c.cache[name] = namespaceLookupResult{namespace: namespace, err: err}
+	return namespace, err
+}
+
+// GetAnnotation retrieves the value of an annotation on a namespace.
+//
+// Returns false if the namespace or the annotation does not exist.
+func (c *NamespaceCache) GetAnnotation(name, annotation string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+	namespace, err := c.Get(name)
+	if err != nil {
+		logger.Debug(fmt.Sprintf("Failed to retrieve namespace %s: %s", name, err))
+		return "", false
+	}
+	value, exists := namespace.GetAnnotations()[annotation]
+	return value, exists
+}
+
+// isNamespace checks whether a resource is the namespaces resource, regardless of its version
+func isNamespace(gvr schema.GroupVersionResource) bool {
+	return gvr.GroupResource() == namespacesGVR.GroupResource()
+}
+
+// parseNamespacesToExclude parses a comma-separated list of namespaces, ignoring surrounding spaces and empty entries
+func parseNamespacesToExclude(value string) []string {
+	var namespaces []string
+	for _, namespace := range strings.Split(value, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// getExcludedNamespace returns the namespace from namespacesToExclude that an item is in, or that the item is if it is
+// a namespace itself
+func getExcludedNamespace(gvr schema.GroupVersionResource, item unstructured.Unstructured) (string, bool) {
+	namespace := item.GetNamespace()
+	if isNamespace(gvr) {
+		namespace = item.GetName()
+	}
+	return namespace, namespace != "" && contains(namespacesToExclude, namespace)
+}
+
+// isNamespaceLocked checks whether a namespace is annotated with AnnotationLock, which pauses deletions in said
+// namespace either until the annotation is removed (if its value is "true"), or until the RFC3339 timestamp it is set to.
+//
+// Returns the time until which the namespace is locked, which is zero if the namespace is locked until further notice.
+func isNamespaceLocked(namespaces *NamespaceCache, name string) (bool, time.Time) {
+	value, exists := namespaces.GetAnnotation(name, AnnotationLock)
+	if !exists {
+		return false, time.Time{}
+	}
+	if value == "true" {
+		return true, time.Time{}
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if value != "false" {
+			logger.Debug(fmt.Sprintf("Namespace %s has an invalid lock '%s', ignoring", name, value))
+		}
+		return false, time.Time{}
+	}
+	return time.Now().Before(until), until
+}
+
+// isNamespaceTerminating checks whether a namespace is being deleted, in which case deleting the resources it contains
+// is both pointless and likely to fail.
+//
+// Each terminating namespace is only logged once per execution.
+func isNamespaceTerminating(namespaces *NamespaceCache, name string) bool {
+	if name == "" {
+		return false
+	}
+	if namespaces.terminating[name] {
+		return true
+	}
+	namespace, err := namespaces.Get(name)
+	if err != nil {
+		return false
+	}
+	phase, _, _ := unstructured.NestedString(namespace.Object, "status", "phase")
+	if phase != "Terminating" && namespace.GetDeletionTimestamp() == nil {
+		return false
+	}
+	logger.Info(fmt.Sprintf("Namespace %s is terminating, skipping the deletion of its resources", name))
+	namespaces.terminating[name] = true
+	return true
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ObserveCommand is the command that runs a single execution in dry run and reports what would have been deleted
+const ObserveCommand = "observe"
+
+const (
+	ObserveFormatTable   = "table"   // Report every deletion candidate as a row of a table
+	ObserveFormatKubectl = "kubectl" // Report every deletion candidate as a kubectl command that deletes it
+)
+
+// deletionCandidate is a resource that would have been deleted if dry run had not been enabled
+type deletionCandidate struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+	reason    string
+}
+
+var (
+	deletionCandidates      []deletionCandidate // Resources that would have been deleted if dry run had not been enabled
+	deletionCandidatesTotal int                 // Number of resources that would have been deleted, which is all that's kept of them in low memory mode
+)
+
+// recordDeletionCandidate keeps track of an item that would have been deleted if dry run had not been enabled
+func recordDeletionCandidate(gvr schema.GroupVersionResource, item unstructured.Unstructured, reason string) {
+	logger.Info(fmt.Sprintf("[%s/%s] would have been deleted, but dry run is enabled: %s", gvr.Resource, item.GetName(), reason))
+	deletionCandidatesTotal++
+	if lowMemoryMode {
+		// The log line above is all that's kept of the candidate
+		return
+	}
+	deletionCandidates = append(deletionCandidates, deletionCandidate{
+		gvr:       gvr,
+		namespace: item.GetNamespace(),
+		name:      item.GetName(),
+		reason:    reason,
+	})
+}
+
+// printObservationReport prints every resource that would have been deleted if dry run had not been enabled, in the
+// format configured through ObserveFormatEnv.
+//
+// In low memory mode, only the number of such resources is printed.
+func printObservationReport(writer io.Writer) {
+	if lowMemoryMode {
+		if observeFormat == ObserveFormatKubectl {
+			// Keep the output safe to paste into a shell
+			_, _ = fmt.Fprint(writer, "# ")
+		}
+		_, _ = fmt.Fprintf(writer, "%d resources would have been deleted (see the logs for details, as they are not kept in %s)\n", deletionCandidatesTotal, LowMemoryModeEnv)
+		return
+	}
+	if observeFormat == ObserveFormatKubectl {
+		printObservationReportAsKubectlCommands(writer)
+		return
+	}
+	tw := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "RESOURCE\tNAMESPACE\tNAME\tREASON")
+	for _, candidate := range deletionCandidates {
+		namespace := candidate.namespace
+		if namespace == "" {
+			namespace = "<cluster>"
+		}
+		resource := candidate.gvr.GroupResource().String()
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", resource, namespace, candidate.name, candidate.reason)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintf(writer, "\n%d resources would have been deleted\n", len(deletionCandidates))
+}
+
+// printObservationReportAsKubectlCommands prints a kubectl command deleting each resource that would have been deleted,
+// along with the reason as a comment, so that operators can review them and run those they approve of
+func printObservationReportAsKubectlCommands(writer io.Writer) {
+	_, _ = fmt.Fprintf(writer, "# %d resources would have been deleted\n", len(deletionCandidates))
+	for _, candidate := range deletionCandidates {
+		command := fmt.Sprintf("kubectl delete %s %s", candidate.gvr.GroupResource().String(), candidate.name)
+		if candidate.namespace != "" {
+			command += " -n " + candidate.namespace
+		}
+		_, _ = fmt.Fprintf(writer, "%s # %s\n", command, strings.ReplaceAll(candidate.reason, "\n", " "))
+	}
+}
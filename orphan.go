@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TwiN/kevent"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// DefaultOrphanGracePeriod is the default duration for which a resource must have been orphaned before being deleted
+const DefaultOrphanGracePeriod = time.Hour
+
+var orphanTracker = NewOrphanTracker()
+
+// OrphanTracker keeps track of the time at which resources were first found to be orphaned.
+//
+// Resources are tracked by UID, so that a resource recreated with the same name starts a new grace period. Resources
+// that are no longer found once their resource has been listed in full (e.g. because they were deleted by someone else)
+// are forgotten by Finish.
+//
+// Since an execution that timed out may still be running when the next one starts, OrphanTracker is safe for
+// concurrent use.
+type OrphanTracker struct {
+	orphans map[types.UID]*trackedOrphan
+	mutex   sync.Mutex
+}
+
+type trackedOrphan struct {
+	gvr   schema.GroupVersionResource
+	uid   types.UID
+	since time.Time
+	seen  bool // Whether the orphan was found during the current execution
+}
+
+// NewOrphanTracker creates a new OrphanTracker
+func NewOrphanTracker() *OrphanTracker {
+	return &OrphanTracker{orphans: make(map[types.UID]*trackedOrphan)}
+}
+
+// Track returns the time since which an item has been orphaned, which is now if the item wasn't tracked yet, along with
+// whether the item was already tracked
+func (t *OrphanTracker) Track(gvr schema.GroupVersionResource, item unstructured.Unstructured) (time.Time, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	orphan, tracked := t.orphans[item.GetUID()]
+	if !tracked {
+		orphan = &trackedOrphan{gvr: gvr, uid: item.GetUID(), since: time.Now()}
+		t.orphans[item.GetUID()] = orphan
+	}
+	orphan.seen = true
+	return orphan.since, tracked
+}
+
+// Keep prevents an item from being forgotten by Finish without tracking it, which is used when whether the item is
+// still orphaned could not be determined
+func (t *OrphanTracker) Keep(item unstructured.Unstructured) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if orphan, tracked := t.orphans[item.GetUID()]; tracked {
+		orphan.seen = true
+	}
+}
+
+// Forget stops tracking an item
+func (t *OrphanTracker) Forget(item unstructured.Unstructured) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.orphans, item.GetUID())
+}
+
+// Finish forgets the orphans of the resources that were listed in full during the current execution, but that were not
+// found.
+//
+// The orphans of resources that weren't listed in full (e.g. because they could not be listed) are kept as is.
+func (t *OrphanTracker) Finish(listed map[schema.GroupVersionResource]bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for uid, orphan := range t.orphans {
+		if !orphan.seen && listed[orphan.gvr] {
+			delete(t.orphans, uid)
+		}
+		orphan.seen = false
+	}
+}
+
+// Snapshot returns the tracked orphans, from the one that has been orphaned for the longest to the most recent one
+func (t *OrphanTracker) Snapshot() []trackedOrphan {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	orphans := make([]trackedOrphan, 0, len(t.orphans))
+	for _, orphan := range t.orphans {
+		orphans = append(orphans, *orphan)
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].since.Before(orphans[j].since) })
+	return orphans
+}
+
+// Restore tracks an orphan returned by Snapshot, unless it is already tracked
+func (t *OrphanTracker) Restore(orphan trackedOrphan) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if _, tracked := t.orphans[orphan.uid]; !tracked {
+		orphan.seen = false
+		t.orphans[orphan.uid] = &orphan
+	}
+}
+
+// reapOrphan deletes an item without a TTL whose owners no longer exist, once it has been orphaned for longer than
+// orphanGracePeriod.
+//
+// Since the time at which the owners were deleted is unknown, the grace period starts when the controller first finds
+// the item to be orphaned.
+//
+// Returns whether the item is orphaned, regardless of whether it was deleted.
+func reapOrphan(dynamicClient dynamic.Interface, eventManager *kevent.EventManager, namespaces *NamespaceCache, ownerResolver *OwnerResolver, gvr schema.GroupVersionResource, item unstructured.Unstructured) bool {
+	orphaned, err := ownerResolver.IsOrphaned(item)
+	if err != nil {
+		logger.Debug(fmt.Sprintf("[%s/%s] could not be checked for missing owners: %s", gvr.Resource, item.GetName(), err))
+		orphanTracker.Keep(item)
+		return false
+	}
+	if !orphaned {
+		orphanTracker.Forget(item)
+		return false
+	}
+	since, tracked := orphanTracker.Track(gvr, item)
+	if !tracked {
+		logger.Info(fmt.Sprintf("[%s/%s] is orphaned, because none of its owners exist anymore; it will be deleted in %s", gvr.Resource, item.GetName(), orphanGracePeriod))
+	}
+	if item.GetDeletionTimestamp() != nil || time.Since(since) < orphanGracePeriod {
+		return true
+	}
+	if deleteOrphanedResource(dynamicClient, eventManager, namespaces, gvr, item, since) {
+		orphanTracker.Forget(item)
+	}
+	return true
+}
+
+// deleteOrphanedResource deletes an item whose owners no longer exist and creates an event reflecting the outcome
+//
+// Returns whether the item was deleted, or would have been if dry run is enabled
+func deleteOrphanedResource(dynamicClient dynamic.Interface, eventManager *kevent.EventManager, namespaces *NamespaceCache, gvr schema.GroupVersionResource, item unstructured.Unstructured, orphanedSince time.Time) bool {
+	waitForDeletionPause()
+	var attempted bool
+	defer throttleAfterDeletion(&attempted)
+	deletionMutex.Lock()
+	defer deletionMutex.Unlock()
+	logger.Info(fmt.Sprintf("[%s/%s] has been orphaned for %s", gvr.Resource, item.GetName(), time.Since(orphanedSince).Round(time.Second)))
+	if !isDeletionAllowed(namespaces, gvr, item) {
+		return false
+	}
+	deleted, err := NewDeleter(dynamicClient).Delete(gvr, item, fmt.Sprintf("orphaned for %s", time.Since(orphanedSince).Round(time.Second)))
+	if err == nil && !deleted {
+		// The deletion was only simulated (e.g. dry run)
+		return true
+	}
+	if err != nil {
+		logger.Info(fmt.Sprintf("[%s/%s] failed to delete: %s", gvr.Resource, item.GetName(), err))
+		recordFailedDeletion(item)
+		createDeletionEvent(eventManager, gvr, item, "FailedToDeleteOrphan", "Unable to delete orphaned resource:"+err.Error(), true, "", time.Time{})
+	} else {
+		logger.Info(fmt.Sprintf("[%s/%s] deleted (uid=%s, resourceVersion=%s)", gvr.Resource, item.GetName(), item.GetUID(), item.GetResourceVersion()))
+		createDeletionEvent(eventManager, gvr, item, "DeletedOrphan", fmt.Sprintf("Deleted resource because none of its owners exist anymore (uid=%s, resourceVersion=%s)", item.GetUID(), item.GetResourceVersion()), false, "", time.Time{})
+		recordDeletion(gvr, item)
+		publishDeletion(gvr, item, "")
+	}
+	attempted = true
+	return err == nil
+}
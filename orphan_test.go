@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestOrphanTracker(t *testing.T) {
+	tracker := NewOrphanTracker()
+	pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now(), nil)
+	pod.SetUID("pod-uid")
+	deployment := newUnstructuredWithAnnotations("apps/v1", "Deployment", "default", "deployment-name", time.Now(), nil)
+	deployment.SetUID("deployment-uid")
+	since, tracked := tracker.Track(podsGVR, *pod)
+	if tracked {
+		t.Error("expected the pod not to have been tracked yet")
+	}
+	if _, tracked := tracker.Track(deploymentsGVR, *deployment); tracked {
+		t.Error("expected the deployment not to have been tracked yet")
+	}
+	if again, tracked := tracker.Track(podsGVR, *pod); !tracked || !again.Equal(since) {
+		t.Errorf("expected the pod to have been tracked since %s, got %s", since, again)
+	}
+	// A pod recreated with the same name is a different pod
+	recreatedPod := pod.DeepCopy()
+	recreatedPod.SetUID("recreated-pod-uid")
+	if _, tracked := tracker.Track(podsGVR, *recreatedPod); tracked {
+		t.Error("expected the recreated pod not to have been tracked yet")
+	}
+	tracker.Finish(map[schema.GroupVersionResource]bool{podsGVR: true, deploymentsGVR: true})
+	if orphans := tracker.Snapshot(); len(orphans) != 3 {
+		t.Errorf("expected the orphans found during the execution to be kept, got %+v", orphans)
+	}
+	// Only the recreated pod is found during the next execution, in which deployments could not be listed
+	tracker.Track(podsGVR, *recreatedPod)
+	tracker.Finish(map[schema.GroupVersionResource]bool{podsGVR: true})
+	orphans := tracker.Snapshot()
+	if len(orphans) != 2 || orphans[0].uid != "deployment-uid" || orphans[1].uid != "recreated-pod-uid" {
+		t.Errorf("expected only the orphans found or whose resource could not be listed to be kept, got %+v", orphans)
+	}
+	tracker.Forget(*deployment)
+	if orphans := tracker.Snapshot(); len(orphans) != 1 || orphans[0].uid != "recreated-pod-uid" {
+		t.Errorf("expected the deployment to have been forgotten, got %+v", orphans)
+	}
+}
+
+func TestOrphanTracker_isSafeForConcurrentUse(t *testing.T) {
+	tracker := NewOrphanTracker()
+	pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now(), nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				tracker.Track(podsGVR, *pod)
+				tracker.Snapshot()
+				tracker.Finish(map[schema.GroupVersionResource]bool{podsGVR: true})
+				tracker.Forget(*pod)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestReconcile_withReapOrphansDeletedBySomeoneElse(t *testing.T) {
+	defer func(previous bool, previousTracker *OrphanTracker) {
+		reapOrphans, orphanTracker = previous, previousTracker
+	}(reapOrphans, orphanTracker)
+	reapOrphans, orphanTracker = true, NewOrphanTracker()
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList, deploymentsAPIResourceList)
+	pod := newUnstructuredWithAnnotations("v1", "Pod", "default", "orphaned-pod-name", time.Now(), nil)
+	pod.SetUID("orphaned-pod-uid")
+	pod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "deleted-deployment-name", UID: "deleted-deployment-uid"}})
+	if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if orphans := orphanTracker.Snapshot(); len(orphans) != 1 {
+		t.Fatalf("expected the orphaned pod to be tracked, got %+v", orphans)
+	}
+	if err := dynamicClient.Resource(podsGVR).Namespace("default").Delete(context.TODO(), pod.GetName(), metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if orphans := orphanTracker.Snapshot(); len(orphans) != 0 {
+		t.Errorf("expected the pod deleted by someone else to have been forgotten, got %+v", orphans)
+	}
+}
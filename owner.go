@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// MaximumOwnerDepth is the maximum number of owner references to walk up when inheriting a TTL from an owner
+const MaximumOwnerDepth = 5
+
+var (
+	ErrNoOwner          = errors.New("resource has no owner")
+	ErrUnknownOwnerGVK  = errors.New("owner's kind is not served by the API server")
+	ErrOwnerHasNoTTL    = errors.New("owner has no TTL")
+	ErrOwnerTTLDisabled = errors.New("owner's TTL is explicitly disabled")
+)
+
+// apiResourceInfo contains what is needed to query a kind through the dynamic client
+type apiResourceInfo struct {
+	GVR        schema.GroupVersionResource
+	Namespaced bool
+}
+
+// indexResourcesByKind maps every GroupVersionKind returned by the discovery API to the information required to query it
+func indexResourcesByKind(resources []*metav1.APIResourceList) map[schema.GroupVersionKind]apiResourceInfo {
+	index := make(map[schema.GroupVersionKind]apiResourceInfo)
+	for _, resource := range resources {
+		gv, err := schema.ParseGroupVersion(resource.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, apiResource := range resource.APIResources {
+			// Skip subresources such as pods/status
+			if strings.Contains(apiResource.Name, "/") {
+				continue
+			}
+			index[gv.WithKind(apiResource.Kind)] = apiResourceInfo{
+				GVR:        gv.WithResource(apiResource.Name),
+				Namespaced: apiResource.Namespaced,
+			}
+		}
+	}
+	return index
+}
+
+// getOwnerReference returns the controller owner reference of an item, or its first owner reference if none of its
+// owners is flagged as controller
+func getOwnerReference(item unstructured.Unstructured) (metav1.OwnerReference, bool) {
+	ownerReferences := item.GetOwnerReferences()
+	if len(ownerReferences) == 0 {
+		return metav1.OwnerReference{}, false
+	}
+	for _, ownerReference := range ownerReferences {
+		if ownerReference.Controller != nil && *ownerReference.Controller {
+			return ownerReference, true
+		}
+	}
+	return ownerReferences[0], true
+}
+
+// getExcludedOwnerKind returns the kind of the first owner of an item whose kind is in excludedOwnerKinds, if any
+func getExcludedOwnerKind(item unstructured.Unstructured) (string, bool) {
+	for _, ownerReference := range item.GetOwnerReferences() {
+		if contains(excludedOwnerKinds, ownerReference.Kind) {
+			return ownerReference.Kind, true
+		}
+	}
+	return "", false
+}
+
+// OwnerResolver retrieves the owners of resources, caching every lookup so that each owner is only retrieved once per
+// execution
+type OwnerResolver struct {
+	dynamicClient   dynamic.Interface
+	resourcesByKind map[schema.GroupVersionKind]apiResourceInfo
+	cache           map[string]ownerLookupResult
+}
+
+type ownerLookupResult struct {
+	owner *unstructured.Unstructured
+	err   error
+}
+
+// NewOwnerResolver creates a new OwnerResolver for the given API resources
+func NewOwnerResolver(dynamicClient dynamic.Interface, resources []*metav1.APIResourceList) *OwnerResolver {
+	return &OwnerResolver{
+		dynamicClient:   dynamicClient,
+		resourcesByKind: indexResourcesByKind(resources),
+		cache:           make(map[string]ownerLookupResult),
+	}
+}
+
+// GetOwner retrieves the owner of an item using its owner references
+func (r *OwnerResolver) GetOwner(item unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	ownerReference, exists := getOwnerReference(item)
+	if !exists {
+		return nil, ErrNoOwner
+	}
+	return r.lookupOwner(item.GetNamespace(), ownerReference)
+}
+
+// IsOrphaned checks whether none of the owners referenced by an item exist anymore.
+//
+// An owner that was recreated with the same name is considered not to exist, since its UID no longer matches the one
+// of the owner reference. Returns false if the item has no owner references.
+func (r *OwnerResolver) IsOrphaned(item unstructured.Unstructured) (bool, error) {
+	ownerReferences := item.GetOwnerReferences()
+	if len(ownerReferences) == 0 {
+		return false, nil
+	}
+	for _, ownerReference := range ownerReferences {
+		owner, err := r.lookupOwner(item.GetNamespace(), ownerReference)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+		if owner.GetUID() == ownerReference.UID {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// lookupOwner retrieves the owner referenced by an owner reference, caching the result
+func (r *OwnerResolver) lookupOwner(namespace string, ownerReference metav1.OwnerReference) (*unstructured.Unstructured, error) {
+	key := namespace + "/" + ownerReference.APIVersion + "/" + ownerReference.Kind + "/" + ownerReference.Name
+	if result, cached := r.cache[key]; cached {
+		return result.owner, result.err
+	}
+	owner, err := r.getOwner(namespace, ownerReference)
+	r.cache[key] = ownerLookupResult{owner: owner, err: err}
+	return owner, err
+}
+
+func (r *OwnerResolver) getOwner(namespace string, ownerReference metav1.OwnerReference) (*unstructured.Unstructured, error) {
+	gv, err := schema.ParseGroupVersion(ownerReference.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	info, exists := r.resourcesByKind[gv.WithKind(ownerReference.Kind)]
+	if !exists {
+		return nil, ErrUnknownOwnerGVK
+	}
+	if info.Namespaced {
+		return r.dynamicClient.Resource(info.GVR).Namespace(namespace).Get(context.TODO(), ownerReference.Name, metav1.GetOptions{})
+	}
+	return r.dynamicClient.Resource(info.GVR).Get(context.TODO(), ownerReference.Name, metav1.GetOptions{})
+}
+
+// GetTTLRelativeToOwner resolves the TTL of an item annotated with AnnotationTTLRelativeToOwner by applying the
+// percentage specified in said annotation to the TTL of the item's owner
+func (r *OwnerResolver) GetTTLRelativeToOwner(item unstructured.Unstructured, percentage string) (time.Duration, error) {
+	ratio, err := parsePercentage(percentage)
+	if err != nil {
+		return 0, err
+	}
+	owner, err := r.GetOwner(item)
+	if err != nil {
+		return 0, err
+	}
+	_, ownerTTL, exists := getTTLAnnotation(owner.GetAnnotations())
+	if !exists {
+		return 0, ErrOwnerHasNoTTL
+	}
+	if isTTLDisabled(ownerTTL) {
+		return 0, ErrOwnerTTLDisabled
+	}
+	ownerTTLInDuration, err := parseTTL(ownerTTL)
+	if err != nil {
+		return 0, fmt.Errorf("owner has an invalid TTL '%s': %w", ownerTTL, err)
+	}
+	return time.Duration(float64(ownerTTLInDuration) * ratio), nil
+}
+
+// GetInheritedTTL walks up the owner references of an item until it finds an owner with a TTL, and returns said TTL
+// as well as the start time of the owner it was found on.
+//
+// For instance, a Pod owned by a ReplicaSet owned by a Deployment annotated with a TTL inherits the Deployment's TTL.
+func (r *OwnerResolver) GetInheritedTTL(item unstructured.Unstructured) (string, metav1.Time, error) {
+	current := item
+	for depth := 0; depth < MaximumOwnerDepth; depth++ {
+		owner, err := r.GetOwner(current)
+		if err != nil {
+			return "", metav1.Time{}, err
+		}
+		if _, ttl, exists := getTTLAnnotation(owner.GetAnnotations()); exists {
+			return ttl, getStartTime(*owner), nil
+		}
+		current = *owner
+	}
+	return "", metav1.Time{}, ErrOwnerHasNoTTL
+}
+
+// parsePercentage parses a percentage such as "50%" into a ratio such as 0.5
+func parsePercentage(percentage string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(percentage), "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage '%s': %w", percentage, err)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("invalid percentage '%s': must be a finite number", percentage)
+	}
+	if value <= 0 {
+		return 0, fmt.Errorf("invalid percentage '%s': must be greater than 0", percentage)
+	}
+	return value / 100, nil
+}
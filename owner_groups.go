@@ -0,0 +1,56 @@
+package main
+
+import (
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// OwnerGroups groups resources by their controller owner in order to enforce maxCountPerOwner
+type OwnerGroups struct {
+	groups  map[types.UID][]unstructured.Unstructured
+	deleted map[string]bool
+}
+
+// NewOwnerGroups creates a new OwnerGroups
+func NewOwnerGroups() *OwnerGroups {
+	return &OwnerGroups{groups: make(map[types.UID][]unstructured.Unstructured), deleted: make(map[string]bool)}
+}
+
+// Add adds an item to the group of its controller owner. Items without a controller owner are ignored.
+func (g *OwnerGroups) Add(item unstructured.Unstructured) {
+	controllerReference := metav1.GetControllerOf(&item)
+	if controllerReference == nil {
+		return
+	}
+	g.groups[controllerReference.UID] = append(g.groups[controllerReference.UID], item)
+}
+
+// MarkDeleted marks an item as deleted so that it no longer counts toward the number of resources owned by its owner
+func (g *OwnerGroups) MarkDeleted(item unstructured.Unstructured) {
+	g.deleted[item.GetNamespace()+"/"+item.GetName()] = true
+}
+
+// ResourcesExceeding returns, for each owner that owns more than maxCount resources, its oldest resources beyond the
+// maxCount most recent ones
+func (g *OwnerGroups) ResourcesExceeding(maxCount int) []unstructured.Unstructured {
+	var exceeding []unstructured.Unstructured
+	for _, group := range g.groups {
+		var remaining []unstructured.Unstructured
+		for _, item := range group {
+			if !g.deleted[item.GetNamespace()+"/"+item.GetName()] {
+				remaining = append(remaining, item)
+			}
+		}
+		if len(remaining) <= maxCount {
+			continue
+		}
+		sort.Slice(remaining, func(i, j int) bool {
+			return remaining[i].GetCreationTimestamp().After(remaining[j].GetCreationTimestamp().Time)
+		})
+		exceeding = append(exceeding, remaining[maxCount:]...)
+	}
+	return exceeding
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestParsePercentage(t *testing.T) {
+	scenarios := []struct {
+		percentage    string
+		expectedRatio float64
+		expectedErr   bool
+	}{
+		{percentage: "50%", expectedRatio: 0.5},
+		{percentage: " 150% ", expectedRatio: 1.5},
+		{percentage: "25", expectedRatio: 0.25},
+		{percentage: "0%", expectedErr: true},
+		{percentage: "-10%", expectedErr: true},
+		{percentage: "abc%", expectedErr: true},
+		{percentage: "NaN%", expectedErr: true},
+		{percentage: "Inf%", expectedErr: true},
+		{percentage: "+Inf%", expectedErr: true},
+		{percentage: "-Inf%", expectedErr: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.percentage, func(t *testing.T) {
+			ratio, err := parsePercentage(scenario.percentage)
+			if scenario.expectedErr != (err != nil) {
+				t.Fatalf("expected error to be %v, got %v", scenario.expectedErr, err)
+			}
+			if ratio != scenario.expectedRatio {
+				t.Errorf("expected ratio to be %v, got %v", scenario.expectedRatio, ratio)
+			}
+		})
+	}
+}
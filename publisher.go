@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DeletionMessage is the message published for each resource deleted by the controller
+type DeletionMessage struct {
+	Group     string    `json:"group"`
+	Version   string    `json:"version"`
+	Resource  string    `json:"resource"`
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace,omitempty"`
+	Name      string    `json:"name"`
+	UID       string    `json:"uid"`
+	TTL       string    `json:"ttl"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// Publisher publishes deletion messages to an external messaging system
+type Publisher interface {
+	Publish(message DeletionMessage) error
+	Close()
+}
+
+// NATSPublisher is a Publisher that publishes deletion messages to a NATS subject
+type NATSPublisher struct {
+	connection *nats.Conn
+	subject    string
+}
+
+// NewNATSPublisher creates a new NATSPublisher.
+//
+// If the NATS server cannot be reached, the connection is retried in the background.
+func NewNATSPublisher(url, subject string) (*NATSPublisher, error) {
+	connection, err := nats.Connect(url,
+		nats.Name("k8s-ttl-controller"),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &NATSPublisher{connection: connection, subject: subject}, nil
+}
+
+// Publish publishes a deletion message to the NATS subject.
+//
+// Messages are buffered by the NATS client, meaning that this does not block on the NATS server.
+func (p *NATSPublisher) Publish(message DeletionMessage) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return p.connection.Publish(p.subject, payload)
+}
+
+// Close flushes the messages that have yet to be sent and closes the connection to the NATS server
+func (p *NATSPublisher) Close() {
+	_ = p.connection.Drain()
+}
+
+// publishDeletion publishes a message for a deleted item if a publisher is configured.
+//
+// Failures are logged rather than returned, as publishing must never prevent the controller from reconciling.
+func publishDeletion(gvr schema.GroupVersionResource, item unstructured.Unstructured, ttl string) {
+	if publisher == nil {
+		return
+	}
+	err := publisher.Publish(DeletionMessage{
+		Group:     gvr.Group,
+		Version:   gvr.Version,
+		Resource:  gvr.Resource,
+		Kind:      item.GetKind(),
+		Namespace: item.GetNamespace(),
+		Name:      item.GetName(),
+		UID:       string(item.GetUID()),
+		TTL:       ttl,
+		DeletedAt: time.Now(),
+	})
+	if err != nil {
+		logger.Info(fmt.Sprintf("[%s/%s] failed to publish deletion message: %s", gvr.Resource, item.GetName(), err))
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TwiN/kevent"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// PurgePolicy is a policy under which resources of a given kind without a TTL are deleted once they have been in one of
+// the required phases and are older than a maximum age, regardless of their annotations
+type PurgePolicy struct {
+	maxAge         string
+	maxAgeDuration time.Duration
+	phases         []string // Phases one of which the status.phase of a resource must be in to be purged
+}
+
+// ParsePurgePolicy parses a purge policy in the format maxAge:Phase1|Phase2 (e.g. 2d:Failed|Succeeded).
+//
+// At least one phase is required, so that resources that are still in use are never purged.
+func ParsePurgePolicy(value string) (*PurgePolicy, error) {
+	maxAge, phases, found := strings.Cut(value, ":")
+	if !found {
+		return nil, fmt.Errorf("invalid purge policy '%s', must be in the format maxAge:Phase1|Phase2", value)
+	}
+	maxAgeDuration, err := parseTTL(strings.TrimSpace(maxAge))
+	if err != nil {
+		return nil, fmt.Errorf("invalid maximum age in purge policy '%s': %w", value, err)
+	}
+	policy := &PurgePolicy{maxAge: strings.TrimSpace(maxAge), maxAgeDuration: maxAgeDuration}
+	for _, phase := range strings.Split(phases, "|") {
+		if phase = strings.TrimSpace(phase); phase != "" {
+			policy.phases = append(policy.phases, phase)
+		}
+	}
+	if len(policy.phases) == 0 {
+		return nil, fmt.Errorf("invalid purge policy '%s', at least one phase is required", value)
+	}
+	return policy, nil
+}
+
+// Matches checks whether an item is in one of the policy's phases and older than its maximum age, returning its phase
+func (p *PurgePolicy) Matches(item unstructured.Unstructured) (string, bool) {
+	phase, _, _ := unstructured.NestedString(item.Object, "status", "phase")
+	if phase == "" || !contains(p.phases, phase) {
+		return phase, false
+	}
+	return phase, time.Since(item.GetCreationTimestamp().Time) >= p.maxAgeDuration
+}
+
+// String returns the policy in the format it was parsed from
+func (p *PurgePolicy) String() string {
+	return p.maxAge + ":" + strings.Join(p.phases, "|")
+}
+
+// purgeUnannotatedResource deletes an item without a TTL if it matches the purge policy of its kind, and creates an
+// event reflecting the outcome.
+//
+// Returns whether the item matched the purge policy, regardless of whether it was deleted.
+func purgeUnannotatedResource(dynamicClient dynamic.Interface, eventManager *kevent.EventManager, namespaces *NamespaceCache, gvr schema.GroupVersionResource, item unstructured.Unstructured) bool {
+	policy, exists := purgePolicies[item.GetKind()]
+	if !exists || item.GetDeletionTimestamp() != nil {
+		return false
+	}
+	phase, matches := policy.Matches(item)
+	if !matches {
+		return false
+	}
+	waitForDeletionPause()
+	var attempted bool
+	defer throttleAfterDeletion(&attempted)
+	deletionMutex.Lock()
+	defer deletionMutex.Unlock()
+	age := time.Since(item.GetCreationTimestamp().Time).Round(time.Second)
+	logger.Info(fmt.Sprintf("[%s/%s] has no TTL, but is in phase %s and is %s old, which exceeds the maximum age of %s for %s", gvr.Resource, item.GetName(), phase, age, policy.maxAge, item.GetKind()))
+	if !isDeletionAllowed(namespaces, gvr, item) {
+		return true
+	}
+	deleted, err := NewDeleter(dynamicClient).Delete(gvr, item, fmt.Sprintf("in phase %s and older than %s", phase, policy.maxAge))
+	if err == nil && !deleted {
+		// The deletion was only simulated (e.g. dry run)
+		return true
+	}
+	if err != nil {
+		logger.Info(fmt.Sprintf("[%s/%s] failed to delete: %s", gvr.Resource, item.GetName(), err))
+		recordFailedDeletion(item)
+		createDeletionEvent(eventManager, gvr, item, "FailedToPurgeUnannotated", "Unable to delete resource matching the purge policy of its kind:"+err.Error(), true, "", time.Time{})
+	} else {
+		logger.Info(fmt.Sprintf("[%s/%s] deleted (uid=%s, resourceVersion=%s)", gvr.Resource, item.GetName(), item.GetUID(), item.GetResourceVersion()))
+		createDeletionEvent(eventManager, gvr, item, "PurgedUnannotated", fmt.Sprintf("Deleted resource because it is in phase %s and older than %s (uid=%s, resourceVersion=%s)", phase, policy.maxAge, item.GetUID(), item.GetResourceVersion()), false, "", time.Time{})
+		recordDeletion(gvr, item)
+		publishDeletion(gvr, item, "")
+	}
+	attempted = true
+	return true
+}
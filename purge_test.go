@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParsePurgePolicy(t *testing.T) {
+	scenarios := []struct {
+		input          string
+		expectedString string
+		expectedErr    bool
+	}{
+		{input: "2d:Failed|Succeeded", expectedString: "2d:Failed|Succeeded"},
+		{input: " 12h : Failed ", expectedString: "12h:Failed"},
+		{input: "2d", expectedErr: true},
+		{input: "2d:", expectedErr: true},
+		{input: "2d:|", expectedErr: true},
+		{input: "forever:Failed", expectedErr: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.input, func(t *testing.T) {
+			policy, err := ParsePurgePolicy(scenario.input)
+			if scenario.expectedErr != (err != nil) {
+				t.Fatalf("expected error=%t, got %v", scenario.expectedErr, err)
+			}
+			if err == nil && policy.String() != scenario.expectedString {
+				t.Errorf("expected %s, got %s", scenario.expectedString, policy)
+			}
+		})
+	}
+}
+
+func TestReconcile_withUnannotatedPurge(t *testing.T) {
+	defer func(previous map[string]*PurgePolicy) { purgePolicies = previous }(purgePolicies)
+	policy, _ := ParsePurgePolicy("2d:Failed|Succeeded")
+	purgePolicies = map[string]*PurgePolicy{"Pod": policy}
+	kubernetesClient, dynamicClient, eventManager := newTestClients(podsAPIResourceList)
+	newPod := func(name string, age time.Duration, phase string, annotations map[string]interface{}) *unstructured.Unstructured {
+		pod := newUnstructuredWithAnnotations("v1", "Pod", "default", name, time.Now().Add(-age), annotations)
+		if phase != "" {
+			_ = unstructured.SetNestedField(pod.Object, phase, "status", "phase")
+		}
+		return pod
+	}
+	pods := []*unstructured.Unstructured{
+		newPod("old-failed-pod-name", 3*24*time.Hour, "Failed", nil),
+		newPod("old-succeeded-pod-name", 3*24*time.Hour, "Succeeded", nil),
+		newPod("old-running-pod-name", 3*24*time.Hour, "Running", nil),
+		newPod("old-pod-without-phase-name", 3*24*time.Hour, "", nil),
+		newPod("recent-failed-pod-name", time.Hour, "Failed", nil),
+		newPod("old-failed-pod-with-ttl-name", 3*24*time.Hour, "Failed", map[string]interface{}{AnnotationTTL: "7d"}),
+	}
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	remaining := listNames(t, dynamicClient, podsGVR, "default")
+	if len(remaining) != 4 || remaining["old-failed-pod-name"] || remaining["old-succeeded-pod-name"] {
+		t.Errorf("expected only the old pods without a TTL in a purged phase to have been deleted, got %v", remaining)
+	}
+}
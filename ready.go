@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// ReadyConditionType is the type of the status condition from which the TTL of items annotated with
+// AnnotationStartTTLWhenReady is calculated
+const ReadyConditionType = "Ready"
+
+// startsTTLWhenReady checks whether the TTL of an item only starts once it becomes ready
+func startsTTLWhenReady(item unstructured.Unstructured) bool {
+	return item.GetAnnotations()[AnnotationStartTTLWhenReady] == "true"
+}
+
+// getReadyTime returns the time at which the Ready status condition of an item last transitioned to True.
+//
+// The second value is false if the item has no Ready status condition, and the third is false if its Ready status
+// condition is not True, or if its lastTransitionTime is missing or invalid.
+func getReadyTime(item unstructured.Unstructured) (time.Time, bool, bool) {
+	conditions, found, err := unstructured.NestedSlice(item.Object, "status", "conditions")
+	if err != nil || !found {
+		return time.Time{}, false, false
+	}
+	for _, condition := range conditions {
+		fields, ok := condition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if conditionType, _, _ := unstructured.NestedString(fields, "type"); conditionType != ReadyConditionType {
+			continue
+		}
+		if status, _, _ := unstructured.NestedString(fields, "status"); !strings.EqualFold(status, "True") {
+			return time.Time{}, true, false
+		}
+		lastTransitionTime, _, _ := unstructured.NestedString(fields, "lastTransitionTime")
+		readyAt, err := time.Parse(time.RFC3339, lastTransitionTime)
+		return readyAt, true, err == nil
+	}
+	return time.Time{}, false, false
+}
+
+// isWaitingToBecomeReady checks whether the TTL of an item annotated with AnnotationStartTTLWhenReady has yet to start,
+// which is the case as long as it has a Ready status condition that isn't True.
+//
+// Items without a Ready status condition are never waiting, as their TTL falls back to starting from their usual start
+// time.
+func isWaitingToBecomeReady(item unstructured.Unstructured) bool {
+	if !startsTTLWhenReady(item) {
+		return false
+	}
+	_, hasCondition, ready := getReadyTime(item)
+	return hasCondition && !ready
+}
+
+// withStatus returns the full object of an item annotated with AnnotationStartTTLWhenReady if it was listed without
+// its status (e.g. through metadata-only listing), since its Ready status condition is needed to calculate its TTL.
+//
+// Returns false if the full object cannot be retrieved, in which case whether the item is ready is unknown and the item
+// must be skipped, lest its TTL be calculated from its creation timestamp.
+func withStatus(ctx context.Context, dynamicClient dynamic.Interface, gvr schema.GroupVersionResource, item unstructured.Unstructured) (unstructured.Unstructured, bool) {
+	if _, hasStatus := item.Object["status"]; hasStatus || !startsTTLWhenReady(item) {
+		return item, true
+	}
+	fullItem, err := dynamicClient.Resource(gvr).Namespace(item.GetNamespace()).Get(ctx, item.GetName(), metav1.GetOptions{})
+	if err != nil {
+		logger.Info(fmt.Sprintf("[%s/%s] failed to retrieve its status to determine whether it is ready, skipping: %s", gvr.Resource, item.GetName(), err))
+		return item, false
+	}
+	return *fullItem, true
+}
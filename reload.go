@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xhit/go-str2duration/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LiveConfiguration is the subset of the configuration that can be changed while the controller is running, through
+// the ConfigMap referenced by ConfigConfigMapEnv.
+//
+// The keys of the ConfigMap are the names of the environment variables of the settings they override.
+type LiveConfiguration struct {
+	APIResourcesToWatch []string
+	APIGroupsToWatch    []string
+	DryRun              bool
+	Paused              bool
+	GlobalTTLExtension  time.Duration
+}
+
+// liveConfigurationMutex guards the settings of the LiveConfiguration, which may be reloaded while the watcher, the
+// scheduler and the reconciliation are reading them
+var liveConfigurationMutex sync.RWMutex
+
+// currentLiveConfiguration returns the live configuration the controller is currently running with.
+//
+// Code that may run while the configuration is being reloaded must read these settings through this function rather
+// than through their global variables.
+func currentLiveConfiguration() LiveConfiguration {
+	liveConfigurationMutex.RLock()
+	defer liveConfigurationMutex.RUnlock()
+	return LiveConfiguration{
+		APIResourcesToWatch: apiResourcesToWatch,
+		APIGroupsToWatch:    apiGroupsToWatch,
+		DryRun:              dryRun,
+		Paused:              paused,
+		GlobalTTLExtension:  globalTTLExtension,
+	}
+}
+
+// apply makes the controller run with the live configuration.
+//
+// Deletions may be made by the watcher at any time, so the configuration is only changed while no deletion is in
+// progress.
+func (c LiveConfiguration) apply() {
+	deletionMutex.Lock()
+	defer deletionMutex.Unlock()
+	liveConfigurationMutex.Lock()
+	defer liveConfigurationMutex.Unlock()
+	apiResourcesToWatch = c.APIResourcesToWatch
+	apiGroupsToWatch = c.APIGroupsToWatch
+	dryRun = c.DryRun
+	paused = c.Paused
+	globalTTLExtension = c.GlobalTTLExtension
+}
+
+// String returns the live configuration in the same format as the configuration logs
+func (c LiveConfiguration) String() string {
+	return fmt.Sprintf("%s=%s, %s=%s, %s=%t, %s=%t, %s=%s", APIResourcesToWatchEnv, formatList(c.APIResourcesToWatch), APIGroupsToWatchEnv, formatAPIGroups(c.APIGroupsToWatch), DryRunEnv, c.DryRun, PausedEnv, c.Paused, GlobalTTLExtensionEnv, c.GlobalTTLExtension)
+}
+
+// parseLiveConfiguration parses the data of a ConfigMap into a live configuration, using base for the settings that
+// the ConfigMap doesn't override.
+//
+// Returns an error if a key is not a setting that can be changed while the controller is running, or if a value is
+// invalid, in which case nothing should be applied.
+func parseLiveConfiguration(data map[string]string, base LiveConfiguration) (LiveConfiguration, error) {
+	configuration := base
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := strings.TrimSpace(data[key])
+		var err error
+		switch key {
+		case APIResourcesToWatchEnv:
+			configuration.APIResourcesToWatch, err = parseLiveList(value)
+		case APIGroupsToWatchEnv:
+			configuration.APIGroupsToWatch = parseAPIGroups(value)
+		case DryRunEnv:
+			configuration.DryRun, err = parseLiveBool(value)
+		case PausedEnv:
+			configuration.Paused, err = parseLiveBool(value)
+		case GlobalTTLExtensionEnv:
+			configuration.GlobalTTLExtension, err = str2duration.ParseDuration(value)
+			if err == nil && configuration.GlobalTTLExtension < 0 {
+				err = fmt.Errorf("must not be negative")
+			}
+		default:
+			err = fmt.Errorf("unknown setting or setting that cannot be changed while the controller is running")
+		}
+		if err != nil {
+			return base, fmt.Errorf("invalid value '%s' for %s: %w", value, key, err)
+		}
+	}
+	return configuration, nil
+}
+
+// parseLiveList parses a comma-separated list, rejecting empty entries since they are most likely typos
+func parseLiveList(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	values := strings.Split(value, ",")
+	for _, v := range values {
+		if strings.TrimSpace(v) == "" {
+			return nil, fmt.Errorf("must not contain empty entries")
+		}
+	}
+	return values, nil
+}
+
+// parseLiveBool parses a boolean, which unlike for environment variables must be explicitly "true" or "false"
+func parseLiveBool(value string) (bool, error) {
+	switch value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("must be either true or false")
+}
+
+// ConfigReloader periodically re-reads the ConfigMap referenced by ConfigConfigMapEnv and applies the live
+// configuration it contains, so that operators can change the behavior of the controller without restarting it
+// (e.g. pausing deletions during an incident).
+type ConfigReloader struct {
+	kubernetesClient kubernetes.Interface
+	namespace        string
+	name             string
+
+	base            LiveConfiguration // Configuration from the environment, which applies to the settings the ConfigMap doesn't override
+	resourceVersion string            // Resource version of the ConfigMap last loaded, to avoid parsing it again if it hasn't changed
+}
+
+// NewConfigReloader creates a new ConfigReloader from a reference to a ConfigMap in the format "namespace/name".
+//
+// The live configuration the controller is currently running with is used for the settings the ConfigMap doesn't
+// override.
+func NewConfigReloader(kubernetesClient kubernetes.Interface, configMap string) (*ConfigReloader, error) {
+	namespace, name, found := strings.Cut(configMap, "/")
+	if !found || namespace == "" || name == "" {
+		return nil, fmt.Errorf("invalid ConfigMap reference '%s', expected namespace/name", configMap)
+	}
+	return &ConfigReloader{kubernetesClient: kubernetesClient, namespace: namespace, name: name, base: currentLiveConfiguration()}, nil
+}
+
+// Reload re-reads the ConfigMap and applies its live configuration if it has changed.
+//
+// If the ConfigMap doesn't exist, the configuration from the environment is restored. If the ConfigMap contains an
+// invalid configuration, it is rejected and the current configuration is kept.
+func (r *ConfigReloader) Reload() error {
+	configMap, err := r.kubernetesClient.CoreV1().ConfigMaps(r.namespace).Get(context.TODO(), r.name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	var data map[string]string
+	resourceVersion := ""
+	if err == nil {
+		data, resourceVersion = configMap.Data, configMap.GetResourceVersion()
+	}
+	if resourceVersion == r.resourceVersion {
+		return nil
+	}
+	r.resourceVersion = resourceVersion
+	configuration, err := parseLiveConfiguration(data, r.base)
+	if err != nil {
+		logger.Error(fmt.Sprintf("[Configuration] Rejected ConfigMap %s/%s (resourceVersion=%s), keeping the current configuration: %s", r.namespace, r.name, resourceVersion, err))
+		return nil
+	}
+	if configuration.String() == currentLiveConfiguration().String() {
+		return nil
+	}
+	configuration.apply()
+	logger.Info(fmt.Sprintf("[Configuration] Reloaded from ConfigMap %s/%s (resourceVersion=%s): %s", r.namespace, r.name, resourceVersion, configuration))
+	logger.Info(fmt.Sprintf("[Configuration] Version: %s", getConfigurationVersion()))
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseLiveConfiguration(t *testing.T) {
+	base := LiveConfiguration{APIResourcesToWatch: []string{"pods"}, GlobalTTLExtension: time.Hour}
+	scenarios := []struct {
+		name          string
+		data          map[string]string
+		expected      LiveConfiguration
+		expectedError bool
+	}{
+		{
+			name:     "empty",
+			data:     map[string]string{},
+			expected: base,
+		},
+		{
+			name:     "overrides",
+			data:     map[string]string{PausedEnv: "true", DryRunEnv: "true", APIResourcesToWatchEnv: "jobs,configmaps", APIGroupsToWatchEnv: ",batch", GlobalTTLExtensionEnv: "2h"},
+			expected: LiveConfiguration{APIResourcesToWatch: []string{"jobs", "configmaps"}, APIGroupsToWatch: []string{"", "batch"}, DryRun: true, Paused: true, GlobalTTLExtension: 2 * time.Hour},
+		},
+		{
+			name:     "clear-filter",
+			data:     map[string]string{APIResourcesToWatchEnv: ""},
+			expected: LiveConfiguration{GlobalTTLExtension: time.Hour},
+		},
+		{
+			name:          "invalid-bool",
+			data:          map[string]string{PausedEnv: "yes"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid-duration",
+			data:          map[string]string{GlobalTTLExtensionEnv: "soon"},
+			expectedError: true,
+		},
+		{
+			name:          "empty-entry",
+			data:          map[string]string{APIResourcesToWatchEnv: "pods,,jobs"},
+			expectedError: true,
+		},
+		{
+			name:          "not-reloadable",
+			data:          map[string]string{WatchModeEnv: "true"},
+			expectedError: true,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			configuration, err := parseLiveConfiguration(scenario.data, base)
+			if scenario.expectedError {
+				if err == nil {
+					t.Error("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if configuration.String() != scenario.expected.String() {
+				t.Errorf("expected %s, got %s", scenario.expected, configuration)
+			}
+		})
+	}
+}
+
+func TestConfigReloader_Reload(t *testing.T) {
+	defer currentLiveConfiguration().apply()
+	LiveConfiguration{}.apply()
+	kubernetesClient := fakekubernetes.NewSimpleClientset()
+	reloader, err := NewConfigReloader(kubernetesClient, "kube-system/k8s-ttl-controller-config")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Nothing changes as long as the ConfigMap doesn't exist
+	if err := reloader.Reload(); err != nil || paused {
+		t.Fatalf("expected nothing to have changed, got paused=%t and error %v", paused, err)
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "k8s-ttl-controller-config", Namespace: "kube-system", ResourceVersion: "1"},
+		Data:       map[string]string{PausedEnv: "true"},
+	}
+	if _, err := kubernetesClient.CoreV1().ConfigMaps("kube-system").Create(context.TODO(), configMap, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := reloader.Reload(); err != nil || !paused {
+		t.Fatalf("expected deletions to have been paused, got paused=%t and error %v", paused, err)
+	}
+	// An invalid configuration is rejected as a whole
+	configMap.ResourceVersion = "2"
+	configMap.Data = map[string]string{PausedEnv: "false", DryRunEnv: "maybe"}
+	if _, err := kubernetesClient.CoreV1().ConfigMaps("kube-system").Update(context.TODO(), configMap, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := reloader.Reload(); err != nil || !paused {
+		t.Fatalf("expected the invalid configuration to have been rejected, got paused=%t and error %v", paused, err)
+	}
+	// Deleting the ConfigMap restores the configuration from the environment
+	if err := kubernetesClient.CoreV1().ConfigMaps("kube-system").Delete(context.TODO(), configMap.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := reloader.Reload(); err != nil || paused {
+		t.Fatalf("expected the configuration from the environment to have been restored, got paused=%t and error %v", paused, err)
+	}
+}
+
+func TestNewConfigReloader_withInvalidReference(t *testing.T) {
+	if _, err := NewConfigReloader(fakekubernetes.NewSimpleClientset(), "k8s-ttl-controller-config"); err == nil {
+		t.Error("expected an error, got none")
+	}
+}
+
+// TestLiveConfiguration_applyWhileWatching is meant to be run with -race, as the watcher reads the live configuration
+// from its event handlers while it is being reloaded
+func TestLiveConfiguration_applyWhileWatching(t *testing.T) {
+	defer currentLiveConfiguration().apply()
+	watchablePodsAPIResourceList := &metav1.APIResourceList{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "pods", Kind: "Pod", Namespaced: true, Verbs: []string{"delete", "get", "list", "watch"}}},
+	}
+	_, dynamicClient, eventManager := newTestClients(watchablePodsAPIResourceList)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	w := NewWatcher(dynamicClient, nil, eventManager, []*metav1.APIResourceList{watchablePodsAPIResourceList})
+	w.Start(stopCh)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			LiveConfiguration{DryRun: i%2 == 0, Paused: i%2 == 1, GlobalTTLExtension: time.Duration(i) * time.Minute, APIResourcesToWatch: []string{"pods"}}.apply()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		pod := newUnstructuredWithAnnotations("v1", "Pod", "default", fmt.Sprintf("pod-name-%d", i), time.Now(), map[string]interface{}{AnnotationTTL: "3d"})
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_ = isReconcilable(podsGVR, watchablePodsAPIResourceList.APIResources[0])
+	}
+	<-done
+	// Wait for the watcher to have scheduled the deletion of every pod
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w.mutex.Lock()
+		scheduled := len(w.timers)
+		w.mutex.Unlock()
+		if scheduled == 50 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the watcher to have scheduled the deletion of 50 pods, got %d", scheduled)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
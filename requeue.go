@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/TwiN/kevent"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// RequeuedDeletionMaxAge is the duration after which a failed deletion is no longer retried ahead of the other resources
+const RequeuedDeletionMaxAge = time.Hour
+
+// requeuedDeletion is the deletion of an expired resource that failed and must be retried
+type requeuedDeletion struct {
+	gvr        schema.GroupVersionResource
+	namespace  string
+	name       string
+	uid        types.UID
+	requeuedAt time.Time
+}
+
+// DeletionRequeue keeps track of the deletions of expired resources that failed, so that they can be retried at the
+// start of the next execution rather than whenever the resource is reached by the next execution.
+//
+// The number of requeued deletions is bounded, and requeued deletions older than RequeuedDeletionMaxAge are dropped.
+// Either way, these resources are still retried by the following executions as usual.
+type DeletionRequeue struct {
+	maxSize   int
+	deletions map[string]requeuedDeletion // Failed deletions, keyed by GVR and namespace/name
+	mutex     sync.Mutex
+}
+
+// NewDeletionRequeue creates a new DeletionRequeue that keeps track of at most maxSize failed deletions
+func NewDeletionRequeue(maxSize int) *DeletionRequeue {
+	return &DeletionRequeue{maxSize: maxSize, deletions: make(map[string]requeuedDeletion)}
+}
+
+// Add requeues the deletion of an item that failed to be deleted
+func (r *DeletionRequeue) Add(gvr schema.GroupVersionResource, item unstructured.Unstructured) {
+	key := timerKey(gvr, item.GetNamespace()+"/"+item.GetName())
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if existing, exists := r.deletions[key]; exists && existing.uid == item.GetUID() {
+		// Keep the original time so that deletions that keep failing eventually age out
+		return
+	}
+	if len(r.deletions) >= r.maxSize {
+		logger.Debug(fmt.Sprintf("[%s/%s] could not be requeued for deletion, because %d deletions are already requeued", gvr.Resource, item.GetName(), r.maxSize))
+		return
+	}
+	r.deletions[key] = requeuedDeletion{gvr: gvr, namespace: item.GetNamespace(), name: item.GetName(), uid: item.GetUID(), requeuedAt: time.Now()}
+}
+
+// Retry retries the requeued deletions, dropping those that are older than RequeuedDeletionMaxAge.
+//
+// Returns the keys of the items whose deletion was retried, so that they can be skipped for the rest of the execution.
+func (r *DeletionRequeue) Retry(dynamicClient dynamic.Interface, eventManager *kevent.EventManager, namespaces *NamespaceCache) map[string]bool {
+	r.mutex.Lock()
+	deletions := r.deletions
+	r.deletions = make(map[string]requeuedDeletion)
+	r.mutex.Unlock()
+	retried := make(map[string]bool)
+	for key, deletion := range deletions {
+		if time.Since(deletion.requeuedAt) > RequeuedDeletionMaxAge {
+			logger.Debug(fmt.Sprintf("[%s/%s] is no longer requeued for deletion, because it was requeued more than %s ago", deletion.gvr.Resource, deletion.name, RequeuedDeletionMaxAge))
+			continue
+		}
+		item, err := dynamicClient.Resource(deletion.gvr).Namespace(deletion.namespace).Get(context.TODO(), deletion.name, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				logger.Info(fmt.Sprintf("[%s/%s] failed to retrieve resource requeued for deletion: %s", deletion.gvr.Resource, deletion.name, err))
+			}
+			continue
+		}
+		// Make sure that the resource wasn't recreated since its deletion failed
+		if item.GetUID() != deletion.uid || item.GetDeletionTimestamp() != nil {
+			continue
+		}
+		ttl, expiresAt, schedulable := getSchedulableExpiration(*item)
+		if !schedulable || !isExpired(expiresAt) {
+			continue
+		}
+		logger.Info(fmt.Sprintf("[%s/%s] failed to be deleted by a previous execution, retrying", deletion.gvr.Resource, deletion.name))
+		retried[key] = true
+		if !deleteExpiredResource(dynamicClient, eventManager, namespaces, deletion.gvr, *item, ttl, expiresAt) {
+			// deleteExpiredResource requeues the deletion again if it failed, but the original time must be kept
+			r.mutex.Lock()
+			if requeued, exists := r.deletions[key]; exists {
+				requeued.requeuedAt = deletion.requeuedAt
+				r.deletions[key] = requeued
+			}
+			r.mutex.Unlock()
+		}
+	}
+	return retried
+}
+
+// Snapshot returns the requeued deletions
+func (r *DeletionRequeue) Snapshot() []requeuedDeletion {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	deletions := make([]requeuedDeletion, 0, len(r.deletions))
+	for _, deletion := range r.deletions {
+		deletions = append(deletions, deletion)
+	}
+	return deletions
+}
+
+// Restore requeues a deletion from a Snapshot, keeping the time at which it was originally requeued
+func (r *DeletionRequeue) Restore(deletion requeuedDeletion) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.deletions) >= r.maxSize {
+		return
+	}
+	r.deletions[timerKey(deletion.gvr, deletion.namespace+"/"+deletion.name)] = deletion
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/TwiN/kevent"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// DeletionScheduler schedules the deletion of resources that expire before the next execution, so that short TTLs
+// aren't exceeded by up to ExecutionInterval.
+//
+// The number of deletions scheduled per execution is bounded, and resources whose deletion could not be scheduled are
+// deleted by the next execution as usual.
+type DeletionScheduler struct {
+	limit                int
+	scheduledInExecution int
+	timers               map[string]*time.Timer // Scheduled deletions, keyed by GVR and namespace/name
+	mutex                sync.Mutex
+}
+
+// NewDeletionScheduler creates a new DeletionScheduler that schedules at most limit deletions per execution
+func NewDeletionScheduler(limit int) *DeletionScheduler {
+	return &DeletionScheduler{limit: limit, timers: make(map[string]*time.Timer)}
+}
+
+// NewExecution resets the number of deletions scheduled in the current execution
+func (s *DeletionScheduler) NewExecution() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.scheduledInExecution = 0
+}
+
+// Schedule schedules the deletion of an item at the time it expires.
+//
+// Returns false if the item's deletion cannot be scheduled, either because the limit of the current execution has been
+// reached, or because the item does not have a TTL of its own.
+func (s *DeletionScheduler) Schedule(dynamicClient dynamic.Interface, eventManager *kevent.EventManager, gvr schema.GroupVersionResource, item unstructured.Unstructured) bool {
+	_, expiresAt, schedulable := getSchedulableExpiration(item)
+	if !schedulable {
+		return false
+	}
+	key := timerKey(gvr, item.GetNamespace()+"/"+item.GetName())
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, exists := s.timers[key]; exists {
+		// The deletion was already scheduled by a previous execution
+		return true
+	}
+	if s.scheduledInExecution >= s.limit {
+		logger.Debug(fmt.Sprintf("[%s/%s] could not be scheduled for deletion, because %d deletions have already been scheduled in this execution", gvr.Resource, item.GetName(), s.limit))
+		return false
+	}
+	s.scheduledInExecution++
+	s.timers[key] = time.AfterFunc(time.Until(expiresAt.Add(clockSkewTolerance)), func() {
+		s.mutex.Lock()
+		delete(s.timers, key)
+		s.mutex.Unlock()
+		s.expire(dynamicClient, eventManager, gvr, item)
+	})
+	return true
+}
+
+// expire retrieves the latest version of an item whose deletion was scheduled, and deletes it if it has expired.
+//
+// If the item was updated in a way that extended its TTL, it is left to the next execution.
+func (s *DeletionScheduler) expire(dynamicClient dynamic.Interface, eventManager *kevent.EventManager, gvr schema.GroupVersionResource, scheduledItem unstructured.Unstructured) {
+	item, err := dynamicClient.Resource(gvr).Namespace(scheduledItem.GetNamespace()).Get(context.TODO(), scheduledItem.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			logger.Info(fmt.Sprintf("[%s/%s] failed to retrieve resource scheduled for deletion: %s", gvr.Resource, scheduledItem.GetName(), err))
+		}
+		return
+	}
+	// Make sure that the resource wasn't recreated since its deletion was scheduled
+	if item.GetUID() != scheduledItem.GetUID() || item.GetDeletionTimestamp() != nil {
+		return
+	}
+	ttl, expiresAt, schedulable := getSchedulableExpiration(*item)
+	if !schedulable || !isExpired(expiresAt) {
+		return
+	}
+	deleteExpiredResource(dynamicClient, eventManager, NewNamespaceCache(dynamicClient), gvr, *item, ttl, expiresAt)
+}
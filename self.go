@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// CoordinationFieldManager is the field manager used by the controller when writing its own coordination objects, such
+// as the ConfigMap its state is persisted in, which allows recognizing them regardless of their name.
+//
+// It must not be used for changes made to the resources the controller reconciles (e.g. AnnotationLastEvaluatedAt), as
+// those would then never be deleted.
+const CoordinationFieldManager = "k8s-ttl-controller-coordination"
+
+var configMapsGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+// getOwnResourceReason checks whether an item is one of the controller's own objects, which must never be deleted
+// regardless of their annotations, as the controller would otherwise be deleting itself or the objects it relies on.
+//
+// The controller's own objects are its Pod, the ConfigMaps its state is persisted in and its configuration is reloaded
+// from, as well as any object it wrote as CoordinationFieldManager.
+//
+// Returns the reason why the item is one of the controller's own objects, if it is.
+func getOwnResourceReason(gvr schema.GroupVersionResource, item unstructured.Unstructured) (string, bool) {
+	reference := item.GetNamespace() + "/" + item.GetName()
+	if gvr.Group == "" && gvr.Resource == configMapsGVR.Resource {
+		if stateConfigMap != "" && reference == stateConfigMap {
+			return fmt.Sprintf("it is the ConfigMap the state of the controller is persisted in (see %s)", StateConfigMapEnv), true
+		}
+		if configConfigMap != "" && reference == configConfigMap {
+			return fmt.Sprintf("it is the ConfigMap the configuration of the controller is reloaded from (see %s)", ConfigConfigMapEnv), true
+		}
+	}
+	if gvr.Group == "" && gvr.Resource == "pods" {
+		if namespace, name, ok := getControllerPod(); ok && reference == namespace+"/"+name {
+			return "it is the Pod the controller is running in", true
+		}
+	}
+	for _, managedFields := range item.GetManagedFields() {
+		if managedFields.Manager == CoordinationFieldManager {
+			return "it is managed by the controller itself", true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestGetOwnResourceReason(t *testing.T) {
+	defer func(previousState, previousConfig string) {
+		stateConfigMap, configConfigMap = previousState, previousConfig
+	}(stateConfigMap, configConfigMap)
+	stateConfigMap, configConfigMap = "kube-system/k8s-ttl-controller-state", "kube-system/k8s-ttl-controller-config"
+	t.Setenv(PodNamespaceEnv, "kube-system")
+	t.Setenv(PodNameEnv, "k8s-ttl-controller-abc12")
+	newItem := func(namespace, name string, managers ...string) unstructured.Unstructured {
+		item := unstructured.Unstructured{}
+		item.SetNamespace(namespace)
+		item.SetName(name)
+		var managedFields []metav1.ManagedFieldsEntry
+		for _, manager := range managers {
+			managedFields = append(managedFields, metav1.ManagedFieldsEntry{Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate})
+		}
+		item.SetManagedFields(managedFields)
+		return item
+	}
+	leasesGVR := schema.GroupVersionResource{Group: "coordination.k8s.io", Version: "v1", Resource: "leases"}
+	scenarios := []struct {
+		name     string
+		gvr      schema.GroupVersionResource
+		item     unstructured.Unstructured
+		expected bool
+	}{
+		{name: "state-configmap", gvr: configMapsGVR, item: newItem("kube-system", "k8s-ttl-controller-state"), expected: true},
+		{name: "config-configmap", gvr: configMapsGVR, item: newItem("kube-system", "k8s-ttl-controller-config"), expected: true},
+		{name: "configmap-with-same-name-in-other-namespace", gvr: configMapsGVR, item: newItem("default", "k8s-ttl-controller-state"), expected: false},
+		{name: "controller-pod", gvr: podsGVR, item: newItem("kube-system", "k8s-ttl-controller-abc12"), expected: true},
+		{name: "other-pod", gvr: podsGVR, item: newItem("kube-system", "k8s-ttl-controller-def34"), expected: false},
+		{name: "managed-by-controller", gvr: leasesGVR, item: newItem("kube-system", "k8s-ttl-controller", "kube-controller-manager", CoordinationFieldManager), expected: true},
+		{name: "managed-by-someone-else", gvr: leasesGVR, item: newItem("kube-system", "k8s-ttl-controller", "kubectl"), expected: false},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			if _, own := getOwnResourceReason(scenario.gvr, scenario.item); own != scenario.expected {
+				t.Errorf("expected %t, got %t", scenario.expected, own)
+			}
+		})
+	}
+}
+
+func TestReconcile_withOwnResources(t *testing.T) {
+	defer func(previous string) { stateConfigMap = previous }(stateConfigMap)
+	stateConfigMap = "default/k8s-ttl-controller-state"
+	kubernetesClient, dynamicClient, eventManager := newTestClients(&metav1.APIResourceList{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: []string{"delete", "get", "list"}}},
+	})
+	configMaps := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "ConfigMap", "default", "k8s-ttl-controller-state", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+		newUnstructuredWithAnnotations("v1", "ConfigMap", "default", "expired-configmap-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+	}
+	for _, configMap := range configMaps {
+		if _, err := dynamicClient.Resource(configMapsGVR).Namespace("default").Create(context.TODO(), configMap, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, configMapsGVR, "default"); len(remaining) != 1 || !remaining["k8s-ttl-controller-state"] {
+		t.Errorf("expected only the ConfigMap the state of the controller is persisted in to be left, got %v", remaining)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// StateConfigMapKey is the key of the ConfigMap's data under which the state of the controller is stored
+	StateConfigMapKey = "state.json"
+
+	// MaxPersistedOrphans is the maximum number of orphans persisted, which keeps the state well within the size limit
+	// of a ConfigMap. The orphans that have been orphaned for the longest are persisted first, as they are the closest to
+	// being deleted.
+	MaxPersistedOrphans = 2000
+
+	// MaxPersistedWarnings is the maximum number of pre-deletion warnings persisted. The warnings of the items expiring
+	// the soonest are persisted first, since forgetting the others only means warning about them again.
+	MaxPersistedWarnings = 2000
+
+	// MaxPersistedCascadeParents is the maximum number of cascade parents persisted, which applies to both the parents
+	// found and those whose children are being reaped.
+	MaxPersistedCascadeParents = 2000
+)
+
+// ControllerState is the state of the controller that is persisted across restarts
+type ControllerState struct {
+	ExecutionFailures          int                  `json:"executionFailures"`
+	TransientExecutionFailures int                  `json:"transientExecutionFailures"`
+	Orphans                    []PersistedOrphan    `json:"orphans,omitempty"`
+	WarnedBeforeDeletion       map[string]time.Time `json:"warnedBeforeDeletion,omitempty"`
+	RequeuedDeletions          []PersistedDeletion  `json:"requeuedDeletions,omitempty"`
+	CascadeParents             []PersistedParent    `json:"cascadeParentResources,omitempty"`
+	DeletedCascadeParents      []string             `json:"deletedCascadeParents,omitempty"`
+	SavedAt                    time.Time            `json:"savedAt"`
+}
+
+// PersistedDeletion is a requeued deletion as persisted in the ControllerState
+type PersistedDeletion struct {
+	Group      string    `json:"group,omitempty"`
+	Version    string    `json:"version"`
+	Resource   string    `json:"resource"`
+	Namespace  string    `json:"namespace,omitempty"`
+	Name       string    `json:"name"`
+	UID        types.UID `json:"uid,omitempty"`
+	RequeuedAt time.Time `json:"requeuedAt"`
+}
+
+// PersistedParent is a parent tracked by the CascadeTracker as persisted in the ControllerState
+type PersistedParent struct {
+	Group    string `json:"group,omitempty"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+	ID       string `json:"id"`
+}
+
+// PersistedOrphan is an orphaned resource tracked by the OrphanTracker as persisted in the ControllerState
+type PersistedOrphan struct {
+	Group         string    `json:"group,omitempty"`
+	Version       string    `json:"version"`
+	Resource      string    `json:"resource"`
+	UID           types.UID `json:"uid"`
+	OrphanedSince time.Time `json:"orphanedSince"`
+}
+
+// StateStore persists the state of the controller in a ConfigMap, so that it survives restarts
+type StateStore struct {
+	kubernetesClient kubernetes.Interface
+	namespace        string
+	name             string
+}
+
+// NewStateStore creates a new StateStore from a reference to a ConfigMap in the format "namespace/name"
+func NewStateStore(kubernetesClient kubernetes.Interface, configMap string) (*StateStore, error) {
+	namespace, name, found := strings.Cut(configMap, "/")
+	if !found || namespace == "" || name == "" {
+		return nil, fmt.Errorf("invalid ConfigMap reference '%s', expected namespace/name", configMap)
+	}
+	return &StateStore{kubernetesClient: kubernetesClient, namespace: namespace, name: name}, nil
+}
+
+// Load retrieves the persisted state of the controller.
+//
+// Returns nil if no state has been persisted yet.
+func (s *StateStore) Load() (*ControllerState, error) {
+	configMap, err := s.kubernetesClient.CoreV1().ConfigMaps(s.namespace).Get(context.TODO(), s.name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	data, exists := configMap.Data[StateConfigMapKey]
+	if !exists {
+		return nil, nil
+	}
+	state := &ControllerState{}
+	if err := json.Unmarshal([]byte(data), state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Save persists the state of the controller, creating the ConfigMap if it doesn't exist
+func (s *StateStore) Save(state ControllerState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	configMaps := s.kubernetesClient.CoreV1().ConfigMaps(s.namespace)
+	configMap, err := configMaps.Get(context.TODO(), s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: s.name, Namespace: s.namespace, Labels: map[string]string{"app": "k8s-ttl-controller"}},
+			Data:       map[string]string{StateConfigMapKey: string(data)},
+		}
+		_, err = configMaps.Create(context.TODO(), configMap, metav1.CreateOptions{FieldManager: CoordinationFieldManager})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[StateConfigMapKey] = string(data)
+	_, err = configMaps.Update(context.TODO(), configMap, metav1.UpdateOptions{FieldManager: CoordinationFieldManager})
+	return err
+}
+
+// captureState captures the current state of the controller.
+//
+// Must not be called while an execution is in progress.
+func captureState() ControllerState {
+	state := ControllerState{
+		ExecutionFailures:          executionFailedCounter,
+		TransientExecutionFailures: transientExecutionFailedCounter,
+		SavedAt:                    time.Now(),
+	}
+	var warnings []string
+	expiries := make(map[string]time.Time)
+	warnedBeforeDeletion.Range(func(key, expiresAt any) bool {
+		warnings = append(warnings, key.(string))
+		expiries[key.(string)] = expiresAt.(time.Time)
+		return true
+	})
+	if len(warnings) > MaxPersistedWarnings {
+		logger.Info(fmt.Sprintf("Only persisting the %d soonest expiring of %d pre-deletion warnings", MaxPersistedWarnings, len(warnings)))
+		sort.Slice(warnings, func(i, j int) bool { return expiries[warnings[i]].Before(expiries[warnings[j]]) })
+		warnings = warnings[:MaxPersistedWarnings]
+	}
+	for _, key := range warnings {
+		if state.WarnedBeforeDeletion == nil {
+			state.WarnedBeforeDeletion = make(map[string]time.Time)
+		}
+		state.WarnedBeforeDeletion[key] = expiries[key]
+	}
+	orphans := orphanTracker.Snapshot()
+	if len(orphans) > MaxPersistedOrphans {
+		logger.Info(fmt.Sprintf("Only persisting the %d oldest of %d orphans", MaxPersistedOrphans, len(orphans)))
+		orphans = orphans[:MaxPersistedOrphans]
+	}
+	for _, orphan := range orphans {
+		state.Orphans = append(state.Orphans, PersistedOrphan{
+			Group:         orphan.gvr.Group,
+			Version:       orphan.gvr.Version,
+			Resource:      orphan.gvr.Resource,
+			UID:           orphan.uid,
+			OrphanedSince: orphan.since,
+		})
+	}
+	if requeue != nil {
+		for _, deletion := range requeue.Snapshot() {
+			state.RequeuedDeletions = append(state.RequeuedDeletions, PersistedDeletion{
+				Group:      deletion.gvr.Group,
+				Version:    deletion.gvr.Version,
+				Resource:   deletion.gvr.Resource,
+				Namespace:  deletion.namespace,
+				Name:       deletion.name,
+				UID:        deletion.uid,
+				RequeuedAt: deletion.requeuedAt,
+			})
+		}
+	}
+	if cascadeTracker != nil {
+		var parents []cascadeParent
+		parents, state.DeletedCascadeParents = cascadeTracker.Snapshot()
+		if len(parents) > MaxPersistedCascadeParents {
+			logger.Info(fmt.Sprintf("Only persisting %d of %d cascade parents", MaxPersistedCascadeParents, len(parents)))
+			parents = parents[:MaxPersistedCascadeParents]
+		}
+		if len(state.DeletedCascadeParents) > MaxPersistedCascadeParents {
+			logger.Info(fmt.Sprintf("Only persisting %d of %d deleted cascade parents", MaxPersistedCascadeParents, len(state.DeletedCascadeParents)))
+			state.DeletedCascadeParents = state.DeletedCascadeParents[:MaxPersistedCascadeParents]
+		}
+		for _, parent := range parents {
+			state.CascadeParents = append(state.CascadeParents, PersistedParent{
+				Group:    parent.gvr.Group,
+				Version:  parent.gvr.Version,
+				Resource: parent.gvr.Resource,
+				ID:       parent.id,
+			})
+		}
+	}
+	return state
+}
+
+// restoreState restores a previously captured state of the controller
+func restoreState(state *ControllerState) {
+	executionFailedCounter = state.ExecutionFailures
+	transientExecutionFailedCounter = state.TransientExecutionFailures
+	consecutiveFailures.Set(float64(executionFailedCounter))
+	for _, orphan := range state.Orphans {
+		orphanTracker.Restore(trackedOrphan{
+			gvr:   schema.GroupVersionResource{Group: orphan.Group, Version: orphan.Version, Resource: orphan.Resource},
+			uid:   orphan.UID,
+			since: orphan.OrphanedSince,
+		})
+	}
+	for key, expiresAt := range state.WarnedBeforeDeletion {
+		warnedBeforeDeletion.Store(key, expiresAt)
+	}
+	if requeue != nil {
+		for _, deletion := range state.RequeuedDeletions {
+			requeue.Restore(requeuedDeletion{
+				gvr:        schema.GroupVersionResource{Group: deletion.Group, Version: deletion.Version, Resource: deletion.Resource},
+				namespace:  deletion.Namespace,
+				name:       deletion.Name,
+				uid:        deletion.UID,
+				requeuedAt: deletion.RequeuedAt,
+			})
+		}
+	}
+	if cascadeTracker != nil {
+		var parents []cascadeParent
+		for _, parent := range state.CascadeParents {
+			parents = append(parents, cascadeParent{
+				gvr: schema.GroupVersionResource{Group: parent.Group, Version: parent.Version, Resource: parent.Resource},
+				id:  parent.ID,
+			})
+		}
+		cascadeTracker.Restore(parents, state.DeletedCascadeParents)
+	}
+}
+
+// loadState creates the StateStore of the ConfigMap referenced by StateConfigMapEnv and restores the state persisted in
+// it, if any
+func loadState() (*StateStore, error) {
+	kubernetesClient, _, err := CreateClients()
+	if err != nil {
+		return nil, err
+	}
+	store, err := NewStateStore(kubernetesClient, stateConfigMap)
+	if err != nil {
+		return nil, err
+	}
+	state, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	if state != nil {
+		restoreState(state)
+		logger.Info(fmt.Sprintf("Restored state saved at %s from ConfigMap %s", state.SavedAt.Format(time.RFC3339), stateConfigMap))
+	}
+	return store, nil
+}
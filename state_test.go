@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestStateStore(t *testing.T) {
+	defer func(previousRequeue *DeletionRequeue, previousOrphanTracker *OrphanTracker, previousCascadeTracker *CascadeTracker, previousExecutionFailures int) {
+		requeue, orphanTracker, cascadeTracker, executionFailedCounter = previousRequeue, previousOrphanTracker, previousCascadeTracker, previousExecutionFailures
+	}(requeue, orphanTracker, cascadeTracker, executionFailedCounter)
+	store, err := NewStateStore(fakekubernetes.NewSimpleClientset(), "kube-system/k8s-ttl-controller-state")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state, err := store.Load(); err != nil || state != nil {
+		t.Fatalf("expected no state to have been persisted yet, got %v and error %v", state, err)
+	}
+	orphanedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	requeue = NewDeletionRequeue(10)
+	requeue.Add(podsGVR, *newUnstructuredWithAnnotations("v1", "Pod", "default", "failed-pod-name", time.Now(), map[string]interface{}{}))
+	orphanTracker = NewOrphanTracker()
+	orphanTracker.Restore(trackedOrphan{gvr: podsGVR, uid: "orphaned-pod-uid", since: orphanedAt})
+	cascadeTracker = NewCascadeTracker()
+	cascadeTracker.Restore([]cascadeParent{{gvr: deploymentsGVR, id: "project-foo"}}, []string{"project-bar"})
+	executionFailedCounter = 3
+	// Saving twice makes sure that the ConfigMap is updated once it has been created
+	for i := 0; i < 2; i++ {
+		if err := store.Save(captureState()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// Simulate a restart
+	requeue = NewDeletionRequeue(10)
+	orphanTracker = NewOrphanTracker()
+	cascadeTracker = NewCascadeTracker()
+	executionFailedCounter = 0
+	state, err := store.Load()
+	if err != nil || state == nil {
+		t.Fatalf("expected state to have been persisted, got %v and error %v", state, err)
+	}
+	restoreState(state)
+	if executionFailedCounter != 3 {
+		t.Errorf("expected 3 execution failures to have been restored, got %d", executionFailedCounter)
+	}
+	if orphans := orphanTracker.Snapshot(); len(orphans) != 1 || orphans[0].gvr != podsGVR || orphans[0].uid != "orphaned-pod-uid" || !orphans[0].since.Equal(orphanedAt) {
+		t.Errorf("expected orphaned resource to have been restored with %s, got %+v", orphanedAt, orphans)
+	}
+	if deletions := requeue.Snapshot(); len(deletions) != 1 || deletions[0].gvr != podsGVR || deletions[0].name != "failed-pod-name" {
+		t.Errorf("expected requeued deletion to have been restored, got %+v", deletions)
+	}
+	if known, deleted := cascadeTracker.Snapshot(); len(known) != 1 || known[0].gvr != deploymentsGVR || known[0].id != "project-foo" || len(deleted) != 1 || deleted[0] != "project-bar" {
+		t.Errorf("expected cascade parents to have been restored with their resource, got known=%+v and deleted=%v", known, deleted)
+	}
+}
+
+func TestCaptureState_withTooManyOrphans(t *testing.T) {
+	defer func(previous *OrphanTracker) { orphanTracker = previous }(orphanTracker)
+	orphanTracker = NewOrphanTracker()
+	oldest := time.Now().Add(-24 * time.Hour)
+	for i := 0; i <= MaxPersistedOrphans; i++ {
+		orphanTracker.Restore(trackedOrphan{gvr: podsGVR, uid: types.UID(fmt.Sprintf("pod-uid-%d", i)), since: oldest.Add(time.Duration(i) * time.Second)})
+	}
+	state := captureState()
+	if len(state.Orphans) != MaxPersistedOrphans {
+		t.Fatalf("expected %d orphans to be persisted, got %d", MaxPersistedOrphans, len(state.Orphans))
+	}
+	if state.Orphans[0].UID != "pod-uid-0" || state.Orphans[MaxPersistedOrphans-1].UID != types.UID(fmt.Sprintf("pod-uid-%d", MaxPersistedOrphans-1)) {
+		t.Errorf("expected the oldest orphans to be persisted, got %s to %s", state.Orphans[0].UID, state.Orphans[MaxPersistedOrphans-1].UID)
+	}
+}
+
+func TestCaptureState_withTooManyWarningsAndCascadeParents(t *testing.T) {
+	defer func(previous *CascadeTracker) { cascadeTracker = previous }(cascadeTracker)
+	defer warnedBeforeDeletion.Range(func(key, _ any) bool {
+		warnedBeforeDeletion.Delete(key)
+		return true
+	})
+	cascadeTracker = NewCascadeTracker()
+	soonest := time.Now().Add(time.Hour)
+	var parents []cascadeParent
+	var deleted []string
+	for i := 0; i <= MaxPersistedWarnings; i++ {
+		warnedBeforeDeletion.Store(fmt.Sprintf("pod-%d", i), soonest.Add(time.Duration(i)*time.Second))
+	}
+	for i := 0; i <= MaxPersistedCascadeParents; i++ {
+		parents = append(parents, cascadeParent{gvr: deploymentsGVR, id: fmt.Sprintf("parent-%d", i)})
+		deleted = append(deleted, fmt.Sprintf("deleted-parent-%d", i))
+	}
+	cascadeTracker.Restore(parents, deleted)
+	state := captureState()
+	if len(state.WarnedBeforeDeletion) != MaxPersistedWarnings {
+		t.Errorf("expected %d warnings to be persisted, got %d", MaxPersistedWarnings, len(state.WarnedBeforeDeletion))
+	}
+	if _, persisted := state.WarnedBeforeDeletion[fmt.Sprintf("pod-%d", MaxPersistedWarnings)]; persisted {
+		t.Error("expected the warning of the item expiring last not to be persisted")
+	}
+	if len(state.CascadeParents) != MaxPersistedCascadeParents || len(state.DeletedCascadeParents) != MaxPersistedCascadeParents {
+		t.Errorf("expected %d cascade parents and deleted cascade parents to be persisted, got %d and %d", MaxPersistedCascadeParents, len(state.CascadeParents), len(state.DeletedCascadeParents))
+	}
+}
+
+func TestNewStateStore_withInvalidReference(t *testing.T) {
+	for _, reference := range []string{"k8s-ttl-controller-state", "/k8s-ttl-controller-state", "kube-system/"} {
+		if _, err := NewStateStore(fakekubernetes.NewSimpleClientset(), reference); err == nil {
+			t.Errorf("expected an error for reference '%s'", reference)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TwiN/kevent"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	statefulSetsGVR           = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}
+	persistentVolumeClaimsGVR = schema.GroupVersionResource{Version: "v1", Resource: "persistentvolumeclaims"}
+)
+
+// isStatefulSet checks whether a GVR is that of StatefulSets, regardless of its version
+func isStatefulSet(gvr schema.GroupVersionResource) bool {
+	return gvr.Group == statefulSetsGVR.Group && gvr.Resource == statefulSetsGVR.Resource
+}
+
+// deleteStatefulSetPersistentVolumeClaims deletes the PersistentVolumeClaims created from the volume claim templates of
+// a StatefulSet that was just deleted, which Kubernetes leaves behind unless the StatefulSet has a retention policy.
+//
+// PersistentVolumeClaims are matched using both the StatefulSet's selector and the naming convention of the StatefulSet
+// controller, which is <template>-<statefulset>-<ordinal>. Each of them goes through the same checks as any other
+// deletion (see isDeletionAllowed), so that protected labels, excluded namespaces and the allowlist are honored.
+//
+// Must be called without holding the deletionMutex, which is acquired for each PersistentVolumeClaim.
+func deleteStatefulSetPersistentVolumeClaims(dynamicClient dynamic.Interface, eventManager *kevent.EventManager, namespaces *NamespaceCache, statefulSet unstructured.Unstructured) {
+	templates, _, _ := unstructured.NestedSlice(statefulSet.Object, "spec", "volumeClaimTemplates")
+	var templateNames []string
+	for _, template := range templates {
+		if fields, ok := template.(map[string]interface{}); ok {
+			if name, _, _ := unstructured.NestedString(fields, "metadata", "name"); name != "" {
+				templateNames = append(templateNames, name)
+			}
+		}
+	}
+	if len(templateNames) == 0 {
+		return
+	}
+	selector, err := getStatefulSetSelector(statefulSet)
+	if err != nil {
+		logger.Info(fmt.Sprintf("[%s/%s] has an invalid selector, its persistent volume claims will not be deleted: %s", statefulSetsGVR.Resource, statefulSet.GetName(), err))
+		return
+	}
+	list, err := dynamicClient.Resource(persistentVolumeClaimsGVR).Namespace(statefulSet.GetNamespace()).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		logger.Info(fmt.Sprintf("[%s/%s] failed to list its persistent volume claims: %s", statefulSetsGVR.Resource, statefulSet.GetName(), err))
+		return
+	}
+	for _, claim := range list.Items {
+		if !isStatefulSetPersistentVolumeClaim(statefulSet.GetName(), templateNames, claim.GetName()) {
+			continue
+		}
+		claim.SetKind("PersistentVolumeClaim")
+		deleteStatefulSetPersistentVolumeClaim(dynamicClient, eventManager, namespaces, statefulSet, claim)
+	}
+}
+
+// deleteStatefulSetPersistentVolumeClaim deletes a PersistentVolumeClaim of a deleted StatefulSet and creates an event
+// reflecting the outcome
+func deleteStatefulSetPersistentVolumeClaim(dynamicClient dynamic.Interface, eventManager *kevent.EventManager, namespaces *NamespaceCache, statefulSet, claim unstructured.Unstructured) {
+	waitForDeletionPause()
+	var attempted bool
+	defer throttleAfterDeletion(&attempted)
+	deletionMutex.Lock()
+	defer deletionMutex.Unlock()
+	if !isDeletionAllowed(namespaces, persistentVolumeClaimsGVR, claim) {
+		return
+	}
+	_, err := NewDeleter(dynamicClient).Delete(persistentVolumeClaimsGVR, claim, fmt.Sprintf("StatefulSet %s was deleted", statefulSet.GetName()))
+	if err != nil {
+		logger.Info(fmt.Sprintf("[%s/%s] failed to delete: %s", persistentVolumeClaimsGVR.Resource, claim.GetName(), err))
+		recordFailedDeletion(claim)
+		createDeletionEvent(eventManager, persistentVolumeClaimsGVR, claim, "FailedToDeleteStatefulSetPVC", "Unable to delete persistent volume claim of deleted StatefulSet:"+err.Error(), true, "", time.Time{})
+	} else {
+		logger.Info(fmt.Sprintf("[%s/%s] deleted (uid=%s, resourceVersion=%s)", persistentVolumeClaimsGVR.Resource, claim.GetName(), claim.GetUID(), claim.GetResourceVersion()))
+		createDeletionEvent(eventManager, persistentVolumeClaimsGVR, claim, "DeletedStatefulSetPVC", fmt.Sprintf("Deleted persistent volume claim because its StatefulSet %s was deleted (uid=%s, resourceVersion=%s)", statefulSet.GetName(), claim.GetUID(), claim.GetResourceVersion()), false, "", time.Time{})
+		recordDeletion(persistentVolumeClaimsGVR, claim)
+		publishDeletion(persistentVolumeClaimsGVR, claim, "")
+	}
+	attempted = true
+}
+
+// getStatefulSetSelector returns the label selector of a StatefulSet in its string form
+func getStatefulSetSelector(statefulSet unstructured.Unstructured) (string, error) {
+	fields, found, err := unstructured.NestedMap(statefulSet.Object, "spec", "selector")
+	if err != nil || !found {
+		return "", err
+	}
+	labelSelector := &metav1.LabelSelector{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(fields, labelSelector); err != nil {
+		return "", err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return "", err
+	}
+	return selector.String(), nil
+}
+
+// isStatefulSetPersistentVolumeClaim checks whether the name of a PersistentVolumeClaim follows the naming convention of
+// the claims created from the volume claim templates of a StatefulSet, which is <template>-<statefulset>-<ordinal>
+func isStatefulSetPersistentVolumeClaim(statefulSetName string, templateNames []string, claimName string) bool {
+	for _, templateName := range templateNames {
+		ordinal, found := strings.CutPrefix(claimName, templateName+"-"+statefulSetName+"-")
+		if !found {
+			continue
+		}
+		if _, err := strconv.ParseUint(ordinal, 10, 32); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsStatefulSetPersistentVolumeClaim(t *testing.T) {
+	scenarios := []struct {
+		claimName string
+		expected  bool
+	}{
+		{claimName: "data-web-0", expected: true},
+		{claimName: "logs-web-12", expected: true},
+		{claimName: "data-web", expected: false},
+		{claimName: "data-web-", expected: false},
+		{claimName: "data-web-backup", expected: false},
+		{claimName: "data-web-api-0", expected: false},
+		{claimName: "data-web--1", expected: false},
+		{claimName: "cache-web-0", expected: false},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.claimName, func(t *testing.T) {
+			if actual := isStatefulSetPersistentVolumeClaim("web", []string{"data", "logs"}, scenario.claimName); actual != scenario.expected {
+				t.Errorf("expected %t, got %t", scenario.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReconcile_withDeleteStatefulSetPVCs(t *testing.T) {
+	defer func(previous bool) { deleteStatefulSetPVCs = previous }(deleteStatefulSetPVCs)
+	defer func(previous map[string]string) { protectedLabels = previous }(protectedLabels)
+	deleteStatefulSetPVCs = true
+	protectedLabels = parseProtectedLabels("keep=true")
+	kubernetesClient, dynamicClient, eventManager := newTestClients(&metav1.APIResourceList{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{{Name: "statefulsets", Kind: "StatefulSet", Namespaced: true, Verbs: []string{"delete", "get", "list"}}},
+	})
+	statefulSet := newUnstructuredWithAnnotations("apps/v1", "StatefulSet", "default", "web", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	statefulSet.Object["spec"] = map[string]interface{}{
+		"selector":             map[string]interface{}{"matchLabels": map[string]interface{}{"app": "web"}},
+		"volumeClaimTemplates": []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"name": "data"}}},
+	}
+	if _, err := dynamicClient.Resource(statefulSetsGVR).Namespace("default").Create(context.TODO(), statefulSet, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	claims := map[string]map[string]string{
+		"data-web-0":      {"app": "web"},
+		"data-web-1":      {"app": "web"},
+		"data-web-2":      {"app": "web", "keep": "true"},
+		"data-web-backup": {"app": "web"},
+		"data-web-3":      {"app": "something-else"},
+		"data-web-4":      {"app": "web"},
+	}
+	for name, labels := range claims {
+		var annotations map[string]interface{}
+		if name == "data-web-4" {
+			annotations = map[string]interface{}{AnnotationTTL: "never"}
+		}
+		claim := newUnstructuredWithAnnotations("v1", "PersistentVolumeClaim", "default", name, time.Now().Add(-time.Hour), annotations)
+		claim.SetLabels(labels)
+		if _, err := dynamicClient.Resource(persistentVolumeClaimsGVR).Namespace("default").Create(context.TODO(), claim, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, statefulSetsGVR, "default"); len(remaining) != 0 {
+		t.Errorf("expected the expired statefulset to have been deleted, got %v", remaining)
+	}
+	remaining := listNames(t, dynamicClient, persistentVolumeClaimsGVR, "default")
+	if len(remaining) != 4 || !remaining["data-web-2"] || !remaining["data-web-backup"] || !remaining["data-web-3"] || !remaining["data-web-4"] {
+		t.Errorf("expected only the claims that are excluded from deletion or don't belong to the statefulset to be left, got %v", remaining)
+	}
+}
+
+func TestReconcile_withDeleteStatefulSetPVCsAndAllowlist(t *testing.T) {
+	defer func(previous bool) { deleteStatefulSetPVCs = previous }(deleteStatefulSetPVCs)
+	defer func(previous Allowlist) { allowlist = previous }(allowlist)
+	deleteStatefulSetPVCs = true
+	filePath := filepath.Join(t.TempDir(), "allowlist.yaml")
+	if err := os.WriteFile(filePath, []byte("- resource: statefulsets\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var err error
+	if allowlist, err = LoadAllowlist(filePath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubernetesClient, dynamicClient, eventManager := newTestClients(&metav1.APIResourceList{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{{Name: "statefulsets", Kind: "StatefulSet", Namespaced: true, Verbs: []string{"delete", "get", "list"}}},
+	})
+	statefulSet := newUnstructuredWithAnnotations("apps/v1", "StatefulSet", "default", "web", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	statefulSet.Object["spec"] = map[string]interface{}{
+		"selector":             map[string]interface{}{"matchLabels": map[string]interface{}{"app": "web"}},
+		"volumeClaimTemplates": []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"name": "data"}}},
+	}
+	claim := newUnstructuredWithAnnotations("v1", "PersistentVolumeClaim", "default", "data-web-0", time.Now().Add(-time.Hour), nil)
+	claim.SetLabels(map[string]string{"app": "web"})
+	if _, err := dynamicClient.Resource(statefulSetsGVR).Namespace("default").Create(context.TODO(), statefulSet, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := dynamicClient.Resource(persistentVolumeClaimsGVR).Namespace("default").Create(context.TODO(), claim, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, statefulSetsGVR, "default"); len(remaining) != 0 {
+		t.Errorf("expected the expired statefulset to have been deleted, got %v", remaining)
+	}
+	if remaining := listNames(t, dynamicClient, persistentVolumeClaimsGVR, "default"); len(remaining) != 1 {
+		t.Errorf("expected the claim to be left behind since it does not match the allowlist, got %v", remaining)
+	}
+}
+
+func TestReconcile_withoutDeleteStatefulSetPVCs(t *testing.T) {
+	kubernetesClient, dynamicClient, eventManager := newTestClients(&metav1.APIResourceList{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{{Name: "statefulsets", Kind: "StatefulSet", Namespaced: true, Verbs: []string{"delete", "get", "list"}}},
+	})
+	statefulSet := newUnstructuredWithAnnotations("apps/v1", "StatefulSet", "default", "web", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	statefulSet.Object["spec"] = map[string]interface{}{
+		"selector":             map[string]interface{}{"matchLabels": map[string]interface{}{"app": "web"}},
+		"volumeClaimTemplates": []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"name": "data"}}},
+	}
+	claim := newUnstructuredWithAnnotations("v1", "PersistentVolumeClaim", "default", "data-web-0", time.Now().Add(-time.Hour), nil)
+	claim.SetLabels(map[string]string{"app": "web"})
+	if _, err := dynamicClient.Resource(statefulSetsGVR).Namespace("default").Create(context.TODO(), statefulSet, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := dynamicClient.Resource(persistentVolumeClaimsGVR).Namespace("default").Create(context.TODO(), claim, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Reconcile(kubernetesClient, dynamicClient, eventManager); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if remaining := listNames(t, dynamicClient, persistentVolumeClaimsGVR, "default"); len(remaining) != 1 {
+		t.Errorf("expected the claim to be left behind when %s is not enabled, got %v", DeleteStatefulSetPVCsEnv, remaining)
+	}
+}
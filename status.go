@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// StatusDiscoveryCacheDuration is how long the API resources discovered by the StatusHandler are reused for, which
+// keeps requests from each triggering a discovery of every API resource served by the API server
+const StatusDiscoveryCacheDuration = time.Minute
+
+// ResourceStatus describes whether a resource will be deleted by the controller, and when
+type ResourceStatus struct {
+	Resource  string     `json:"resource"`
+	Namespace string     `json:"namespace,omitempty"`
+	Name      string     `json:"name"`
+	Managed   bool       `json:"managed"`
+	Reason    string     `json:"reason,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Expired   bool       `json:"expired,omitempty"`
+}
+
+// StatusHandler serves the status of a single resource on GET /status?resource=<resource>&namespace=<namespace>&name=<name>.
+//
+// The resource is retrieved from the API server on every request rather than from a cache, so the status reflects the
+// resource's latest annotations. The clients and the API resources, on the other hand, are reused across requests.
+type StatusHandler struct {
+	createClients func() (kubernetes.Interface, dynamic.Interface, error)
+
+	mutex            sync.Mutex
+	kubernetesClient kubernetes.Interface
+	dynamicClient    dynamic.Interface
+	resources        []*metav1.APIResourceList
+	discoveredAt     time.Time
+}
+
+// NewStatusHandler creates a new StatusHandler using createClients to create the clients used to serve requests
+func NewStatusHandler(createClients func() (kubernetes.Interface, dynamic.Interface, error)) *StatusHandler {
+	return &StatusHandler{createClients: createClients}
+}
+
+// getClientsAndResources returns the dynamic client and the API resources used to serve requests.
+//
+// The clients are only created once, and the API resources are only discovered again once they are older than
+// StatusDiscoveryCacheDuration.
+func (h *StatusHandler) getClientsAndResources() (dynamic.Interface, []*metav1.APIResourceList, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.kubernetesClient == nil {
+		kubernetesClient, dynamicClient, err := h.createClients()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create Kubernetes clients: %w", err)
+		}
+		h.kubernetesClient, h.dynamicClient = kubernetesClient, dynamicClient
+	}
+	if h.resources == nil || time.Since(h.discoveredAt) > StatusDiscoveryCacheDuration {
+		_, resources, err := h.kubernetesClient.Discovery().ServerGroupsAndResources()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to discover API resources: %w", err)
+		}
+		h.resources, h.discoveredAt = resources, time.Now()
+	}
+	return h.dynamicClient, h.resources, nil
+}
+
+func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	resource, namespace, name := query.Get("resource"), query.Get("namespace"), query.Get("name")
+	if resource == "" || name == "" {
+		http.Error(w, "the resource and name query parameters are required", http.StatusBadRequest)
+		return
+	}
+	dynamicClient, resources, err := h.getClientsAndResources()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	gvr, apiResource, found := findAPIResource(resources, resource)
+	if !found {
+		http.Error(w, fmt.Sprintf("resource %s not found", resource), http.StatusNotFound)
+		return
+	}
+	if !apiResource.Namespaced {
+		namespace = ""
+	}
+	item, err := dynamicClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			http.Error(w, fmt.Sprintf("%s %s not found", gvr.Resource, name), http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("failed to retrieve %s %s: %s", gvr.Resource, name, err), http.StatusInternalServerError)
+		}
+		return
+	}
+	status := getResourceStatus(NewOwnerResolver(dynamicClient, resources), gvr, *item)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+// findAPIResource finds the API resource matching a resource name such as "deployments", "deployment", "deploy" or
+// "deployments.apps"
+func findAPIResource(resources []*metav1.APIResourceList, resource string) (schema.GroupVersionResource, metav1.APIResource, bool) {
+	resource, group, hasGroup := strings.Cut(strings.ToLower(resource), ".")
+	for _, resourceList := range resources {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil || (hasGroup && gv.Group != group) {
+			continue
+		}
+		for _, apiResource := range resourceList.APIResources {
+			if strings.Contains(apiResource.Name, "/") {
+				continue
+			}
+			if apiResource.Name == resource || apiResource.SingularName == resource || contains(apiResource.ShortNames, resource) {
+				return gv.WithResource(apiResource.Name), apiResource, true
+			}
+		}
+	}
+	return schema.GroupVersionResource{}, metav1.APIResource{}, false
+}
+
+// getResourceStatus resolves the expiration of an item the same way DoReconcile does, and reports items that the
+// controller never deletes regardless of their TTL as unmanaged
+func getResourceStatus(ownerResolver *OwnerResolver, gvr schema.GroupVersionResource, item unstructured.Unstructured) ResourceStatus {
+	status := ResourceStatus{Resource: gvr.GroupResource().String(), Namespace: item.GetNamespace(), Name: item.GetName()}
+	if reason, _, excluded := getExclusionReason(gvr, item); excluded {
+		status.Reason = "resource will not be deleted, because " + reason
+		return status
+	}
+	expiration, err := resolveExpiration(ownerResolver, item)
+	if err != nil {
+		if errors.Is(err, ErrNoTTL) {
+			status.Reason = err.Error()
+		} else {
+			status.Reason = "resource " + err.Error()
+		}
+		return status
+	}
+	status.Managed, status.TTL, status.ExpiresAt, status.Expired = true, expiration.TTL, &expiration.ExpiresAt, isExpired(expiration.ExpiresAt)
+	return status
+}
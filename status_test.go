@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+func TestStatusHandler(t *testing.T) {
+	defer func(previous map[string]string) { protectedLabels = previous }(protectedLabels)
+	protectedLabels = map[string]string{"protected": "true"}
+	kubernetesClient, dynamicClient, _ := newTestClients(podsAPIResourceList)
+	protectedPod := newUnstructuredWithAnnotations("v1", "Pod", "default", "protected-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	protectedPod.SetLabels(map[string]string{"protected": "true"})
+	ownPod := newUnstructuredWithAnnotations("v1", "Pod", "default", "own-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"})
+	ownPod.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: CoordinationFieldManager, Operation: metav1.ManagedFieldsOperationApply}})
+	pods := []*unstructured.Unstructured{
+		protectedPod,
+		ownPod,
+		newUnstructuredWithAnnotations("v1", "Pod", "kube-system", "system-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "not-expired-pod-name", time.Now(), map[string]interface{}{AnnotationTTL: "3d"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "disabled-pod-name", time.Now(), map[string]interface{}{AnnotationTTL: "never"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "unannotated-pod-name", time.Now(), nil),
+	}
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace(pod.GetNamespace()).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	clientsCreated := 0
+	handler := NewStatusHandler(func() (kubernetes.Interface, dynamic.Interface, error) {
+		clientsCreated++
+		return kubernetesClient, dynamicClient, nil
+	})
+	scenarios := []struct {
+		name               string
+		query              string
+		expectedStatusCode int
+		expectedManaged    bool
+		expectedExpired    bool
+	}{
+		{name: "expired", query: "resource=pods&namespace=default&name=expired-pod-name", expectedStatusCode: http.StatusOK, expectedManaged: true, expectedExpired: true},
+		{name: "not-expired", query: "resource=pods&namespace=default&name=not-expired-pod-name", expectedStatusCode: http.StatusOK, expectedManaged: true},
+		{name: "disabled", query: "resource=pods&namespace=default&name=disabled-pod-name", expectedStatusCode: http.StatusOK},
+		{name: "unannotated", query: "resource=pods&namespace=default&name=unannotated-pod-name", expectedStatusCode: http.StatusOK},
+		{name: "protected-label", query: "resource=pods&namespace=default&name=protected-pod-name", expectedStatusCode: http.StatusOK},
+		{name: "own-resource", query: "resource=pods&namespace=default&name=own-pod-name", expectedStatusCode: http.StatusOK},
+		{name: "system-namespace", query: "resource=pods&namespace=kube-system&name=system-pod-name", expectedStatusCode: http.StatusOK},
+		{name: "resource-not-found", query: "resource=pods&namespace=default&name=nonexistent-pod-name", expectedStatusCode: http.StatusNotFound},
+		{name: "unknown-api-resource", query: "resource=widgets&namespace=default&name=widget-name", expectedStatusCode: http.StatusNotFound},
+		{name: "missing-name", query: "resource=pods&namespace=default", expectedStatusCode: http.StatusBadRequest},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status?"+scenario.query, nil))
+			if recorder.Code != scenario.expectedStatusCode {
+				t.Fatalf("expected status code %d, got %d: %s", scenario.expectedStatusCode, recorder.Code, recorder.Body.String())
+			}
+			if recorder.Code != http.StatusOK {
+				return
+			}
+			var status ResourceStatus
+			if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if status.Managed != scenario.expectedManaged || status.Expired != scenario.expectedExpired {
+				t.Errorf("expected managed=%t and expired=%t, got %+v", scenario.expectedManaged, scenario.expectedExpired, status)
+			}
+			if status.Managed && status.ExpiresAt == nil {
+				t.Error("expected the expiration of a managed resource to be returned")
+			}
+			if !status.Managed && status.Reason == "" {
+				t.Error("expected the reason why the resource is not managed to be returned")
+			}
+		})
+	}
+	// The clients and the API resources are reused across requests
+	discoveries := 0
+	for _, action := range kubernetesClient.Actions() {
+		if action.GetResource().Resource == "resource" {
+			discoveries++
+		}
+	}
+	if clientsCreated != 1 || discoveries != 1 {
+		t.Errorf("expected the clients to be created and the API resources to be discovered once, got %d and %d", clientsCreated, discoveries)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// ResourceSummary aggregates what was found while checking the items of a resource, so that a single log line can be
+// emitted per resource instead of one per item when LOG_AGGREGATE is enabled
+type ResourceSummary struct {
+	resource      string
+	checked       int
+	expired       int
+	pending       int
+	soonestExpiry time.Time
+}
+
+// NewResourceSummary creates a new ResourceSummary for the given resource
+func NewResourceSummary(resource string) *ResourceSummary {
+	return &ResourceSummary{resource: resource}
+}
+
+// Checked records that an item was checked
+func (s *ResourceSummary) Checked() {
+	s.checked++
+}
+
+// Expired records that an item has expired
+func (s *ResourceSummary) Expired() {
+	s.expired++
+}
+
+// Pending records that an item has a TTL that has yet to expire
+func (s *ResourceSummary) Pending(expiresAt time.Time) {
+	s.pending++
+	if s.soonestExpiry.IsZero() || expiresAt.Before(s.soonestExpiry) {
+		s.soonestExpiry = expiresAt
+	}
+}
+
+// String returns a human-readable summary, such as "checked 412 pods, 3 expired, 409 pending, soonest expiry in 12m0s"
+func (s *ResourceSummary) String() string {
+	summary := fmt.Sprintf("checked %d %s, %d expired, %d pending", s.checked, s.resource, s.expired, s.pending)
+	if !s.soonestExpiry.IsZero() {
+		summary += fmt.Sprintf(", soonest expiry in %s", time.Until(s.soonestExpiry).Round(time.Second))
+	}
+	return summary
+}
+
+// logPerItem logs a message about a single item at the info level, or at the debug level if per-item logs are
+// aggregated into summaries
+func logPerItem(message string) {
+	if logAggregate {
+		logger.Debug(message)
+	} else {
+		logger.Info(message)
+	}
+}
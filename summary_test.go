@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestResourceSummary(t *testing.T) {
+	summary := NewResourceSummary("pods")
+	if summary.String() != "checked 0 pods, 0 expired, 0 pending" {
+		t.Errorf("unexpected summary for no items: %s", summary)
+	}
+	for i := 0; i < 4; i++ {
+		summary.Checked()
+	}
+	summary.Expired()
+	summary.Pending(time.Now().Add(time.Hour))
+	summary.Pending(time.Now().Add(12*time.Minute + 30*time.Second))
+	if !strings.HasPrefix(summary.String(), "checked 4 pods, 1 expired, 2 pending, soonest expiry in 12m") {
+		t.Errorf("unexpected summary: %s", summary)
+	}
+}
+
+func TestNewExecutionSummary(t *testing.T) {
+	deletedPerResource := map[string]int{"pods": 3, "jobs.batch": 9, "configmaps": 3}
+	for i := 0; i < ExecutionSummaryTopResources; i++ {
+		deletedPerResource["resource"+strconv.Itoa(i)+".example.com"] = 1
+	}
+	result := ExecutionResult{Scanned: 2450, Deleted: 25, FailedDeletions: 1, DeletedPerResource: deletedPerResource}
+	result.ListFailures = map[schema.GroupVersionResource]error{{Version: "v1", Resource: "secrets"}: errors.New("forbidden")}
+	summary := newExecutionSummary(time.Now().Add(-time.Second), result, ErrListFailed)
+	if summary.Scanned != 2450 || summary.Deleted != 25 || summary.FailedDeletions != 1 || summary.ListFailures != 1 {
+		t.Errorf("unexpected counts in summary: %+v", summary)
+	}
+	if summary.DurationMs < 1000 {
+		t.Errorf("expected duration to be at least 1000ms, got %dms", summary.DurationMs)
+	}
+	if summary.Error != ErrListFailed.Error() {
+		t.Errorf("expected error to be %q, got %q", ErrListFailed, summary.Error)
+	}
+	if len(summary.TopResources) != ExecutionSummaryTopResources {
+		t.Fatalf("expected %d top resources, got %d", ExecutionSummaryTopResources, len(summary.TopResources))
+	}
+	expected := []ResourceDeletions{{Resource: "jobs.batch", Deleted: 9}, {Resource: "configmaps", Deleted: 3}, {Resource: "pods", Deleted: 3}, {Resource: "resource0.example.com", Deleted: 1}}
+	for i, resource := range expected {
+		if summary.TopResources[i] != resource {
+			t.Errorf("expected top resource #%d to be %+v, got %+v", i+1, resource, summary.TopResources[i])
+		}
+	}
+}
+
+func TestWriteExecutionSummary(t *testing.T) {
+	defer func(previous io.Writer) { executionSummaryWriter = previous }(executionSummaryWriter)
+	buffer := &bytes.Buffer{}
+	executionSummaryWriter = buffer
+	writeExecutionSummary(time.Now(), ExecutionResult{Scanned: 2, Deleted: 1, DeletedPerResource: map[string]int{"pods": 1}}, nil)
+	writeExecutionSummary(time.Now(), ExecutionResult{}, ErrDiscoveryFailed)
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one line per execution, got %q", buffer.String())
+	}
+	var summary ExecutionSummary
+	if err := json.Unmarshal([]byte(lines[0]), &summary); err != nil {
+		t.Fatalf("failed to unmarshal summary: %s", err)
+	}
+	if summary.Scanned != 2 || summary.Deleted != 1 || len(summary.TopResources) != 1 || summary.Error != "" {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if strings.Contains(lines[0], `"error"`) {
+		t.Errorf("expected error to be omitted for a successful execution, got %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"topResources":[]`) || !strings.Contains(lines[1], `"error":`) {
+		t.Errorf("unexpected summary for a failed execution: %s", lines[1])
+	}
+}
+
+func TestOpenExecutionSummaryWriter(t *testing.T) {
+	if writer, err := openExecutionSummaryWriter("stdout"); err != nil || writer != os.Stdout {
+		t.Errorf("expected stdout, got %v (%v)", writer, err)
+	}
+	for _, destination := range []string{"fd:", "fd:abc", "fd:2"} {
+		if _, err := openExecutionSummaryWriter(destination); err == nil {
+			t.Errorf("expected an error for %s", destination)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "summary.jsonl")
+	for i := 0; i < 2; i++ {
+		writer, err := openExecutionSummaryWriter(path)
+		if err != nil {
+			t.Fatalf("failed to open %s: %s", path, err)
+		}
+		_, _ = writer.Write([]byte("{}\n"))
+		_ = writer.(*os.File).Close()
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{}\n{}\n" {
+		t.Errorf("expected the file to be appended to, got %q", data)
+	}
+}
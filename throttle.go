@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Throttler is in charge of pacing the calls made to the API server.
+//
+// When adaptive, the duration slept between each call increases when the API server responds slowly and decreases
+// back toward the minimum when it responds quickly. Otherwise, it always sleeps for the minimum duration.
+//
+// If a jitter is configured, a random duration of up to the jitter is added to each sleep, so that calls made to the
+// API server don't form a regular pattern that may align with that of other controllers.
+type Throttler struct {
+	adaptive         bool
+	minimum          time.Duration
+	maximum          time.Duration
+	latencyThreshold time.Duration
+	jitter           time.Duration
+
+	current time.Duration
+	mutex   sync.Mutex
+}
+
+// NewThrottler creates a new Throttler
+func NewThrottler(adaptive bool, minimum, maximum, latencyThreshold, jitter time.Duration) *Throttler {
+	if maximum < minimum {
+		maximum = minimum
+	}
+	return &Throttler{
+		adaptive:         adaptive,
+		minimum:          minimum,
+		maximum:          maximum,
+		latencyThreshold: latencyThreshold,
+		jitter:           max(jitter, 0),
+		current:          minimum,
+	}
+}
+
+// Observe adjusts the throttle duration based on the latency of a call made to the API server
+func (t *Throttler) Observe(latency time.Duration) {
+	if !t.adaptive {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	previous := t.current
+	if latency > t.latencyThreshold {
+		t.current = min(max(t.current*2, time.Millisecond), t.maximum)
+	} else if latency < t.latencyThreshold/2 {
+		t.current = max(t.current/2, t.minimum)
+	}
+	if t.current != previous {
+		logger.Debug(fmt.Sprintf("[Throttler] Adjusted throttle duration from %s to %s after observing a latency of %s", previous, t.current, latency))
+	}
+}
+
+// Duration returns the current throttle duration
+func (t *Throttler) Duration() time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.current
+}
+
+// Sleep sleeps for the current throttle duration plus a random jitter, keeping track of the total time spent sleeping
+func (t *Throttler) Sleep() {
+	duration := t.Duration()
+	if t.jitter > 0 {
+		duration += rand.N(t.jitter + 1)
+	}
+	time.Sleep(duration)
+	throttleSecondsTotal.Add(duration.Seconds())
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestThrottler_Observe(t *testing.T) {
+	scenarios := []struct {
+		name             string
+		adaptive         bool
+		latencies        []time.Duration
+		expectedDuration time.Duration
+	}{
+		{
+			name:             "not-adaptive",
+			adaptive:         false,
+			latencies:        []time.Duration{5 * time.Second, 5 * time.Second},
+			expectedDuration: 50 * time.Millisecond,
+		},
+		{
+			name:             "slow-api-server-increases-duration",
+			adaptive:         true,
+			latencies:        []time.Duration{2 * time.Second, 2 * time.Second},
+			expectedDuration: 200 * time.Millisecond,
+		},
+		{
+			name:             "slow-api-server-increases-duration-up-to-maximum",
+			adaptive:         true,
+			latencies:        []time.Duration{2 * time.Second, 2 * time.Second, 2 * time.Second, 2 * time.Second, 2 * time.Second, 2 * time.Second, 2 * time.Second},
+			expectedDuration: time.Second,
+		},
+		{
+			name:             "fast-api-server-decreases-duration-down-to-minimum",
+			adaptive:         true,
+			latencies:        []time.Duration{2 * time.Second, 2 * time.Second, 10 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond},
+			expectedDuration: 50 * time.Millisecond,
+		},
+		{
+			name:             "latency-between-half-threshold-and-threshold-keeps-duration",
+			adaptive:         true,
+			latencies:        []time.Duration{2 * time.Second, 750 * time.Millisecond},
+			expectedDuration: 100 * time.Millisecond,
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			throttler := NewThrottler(scenario.adaptive, 50*time.Millisecond, time.Second, time.Second, 0)
+			for _, latency := range scenario.latencies {
+				throttler.Observe(latency)
+			}
+			if throttler.Duration() != scenario.expectedDuration {
+				t.Errorf("expected %s, got %s", scenario.expectedDuration, throttler.Duration())
+			}
+		})
+	}
+}
+
+func TestThrottler_Sleep(t *testing.T) {
+	throttler := NewThrottler(false, 10*time.Millisecond, 10*time.Millisecond, time.Second, 0)
+	before := testutil.ToFloat64(throttleSecondsTotal)
+	throttler.Sleep()
+	throttler.Sleep()
+	if slept := testutil.ToFloat64(throttleSecondsTotal) - before; slept < 0.019 || slept > 0.021 {
+		t.Errorf("expected 20ms of throttling to have been recorded, got %fs", slept)
+	}
+}
+
+func TestThrottler_SleepWithJitter(t *testing.T) {
+	throttler := NewThrottler(false, 10*time.Millisecond, 10*time.Millisecond, time.Second, 10*time.Millisecond)
+	for i := 0; i < 5; i++ {
+		before := testutil.ToFloat64(throttleSecondsTotal)
+		throttler.Sleep()
+		if slept := testutil.ToFloat64(throttleSecondsTotal) - before; slept < 0.010 || slept > 0.020 {
+			t.Errorf("expected between 10ms and 20ms of throttling to have been recorded, got %fs", slept)
+		}
+	}
+}
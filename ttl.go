@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xhit/go-str2duration/v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var (
+	ErrISO8601CalendarUnits = errors.New("years and months are not supported, because their duration varies")
+	ErrTTLTooLong           = errors.New("TTL is too long")
+
+	iso8601DurationPattern = regexp.MustCompile(`^P(?:(\d+(?:\.\d+)?)Y)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)W)?(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+)
+
+// DefaultMaxValidTTL is the longest TTL considered valid unless overridden through MaxValidTTLEnv. Longer TTLs are far
+// more likely to be typos (e.g. an extra zero) than intentional, and durations past a few centuries cannot be
+// represented at all.
+const DefaultMaxValidTTL = 10 * 365 * 24 * time.Hour
+
+// DisabledTTLValues are the TTL values, compared case-insensitively, that explicitly mark a resource as never expiring
+var DisabledTTLValues = []string{"never", "disabled", "0"}
+
+// isTTLDisabled checks whether a TTL is one of DisabledTTLValues or any other zero duration, such as "0s" or "PT0S"
+func isTTLDisabled(ttl string) bool {
+	ttl = strings.TrimSpace(ttl)
+	for _, value := range DisabledTTLValues {
+		if strings.EqualFold(ttl, value) {
+			return true
+		}
+	}
+	duration, err := parseDuration(ttl)
+	return err == nil && duration == 0
+}
+
+// capTTL caps the TTL of an item at the maximum TTL of its kind, if one is configured through MaxTTLByKindEnv.
+//
+// Returns whether the TTL was capped.
+func capTTL(item unstructured.Unstructured, ttl string, ttlInDuration time.Duration) (string, time.Duration, bool) {
+	maxTTL, exists := maxTTLByKind[item.GetKind()]
+	if !exists || ttlInDuration <= maxTTL {
+		return ttl, ttlInDuration, false
+	}
+	return maxTTL.String(), maxTTL, true
+}
+
+// isExpired checks whether an item that expires at expiresAt has expired by more than clockSkewTolerance
+func isExpired(expiresAt time.Time) bool {
+	return time.Now().After(expiresAt.Add(clockSkewTolerance))
+}
+
+// parseTTL parses a TTL, which may either be a duration such as "3d12h", or an ISO 8601 duration such as "P3DT12H".
+//
+// Returns an error wrapping ErrTTLTooLong if the TTL is longer than maxValidTTL.
+func parseTTL(ttl string) (time.Duration, error) {
+	duration, err := parseDuration(ttl)
+	if err != nil {
+		return 0, err
+	}
+	if duration > maxValidTTL {
+		return 0, fmt.Errorf("%w: %s exceeds the maximum of %s", ErrTTLTooLong, ttl, str2duration.String(maxValidTTL))
+	}
+	return duration, nil
+}
+
+// parseDuration parses a duration in the same formats as parseTTL, without checking it against maxValidTTL
+func parseDuration(value string) (time.Duration, error) {
+	if strings.HasPrefix(value, "P") {
+		return parseISO8601Duration(value)
+	}
+	return str2duration.ParseDuration(value)
+}
+
+// parseISO8601Duration parses an ISO 8601 duration such as "P3DT12H".
+//
+// Years and months are rejected, as the number of days they span depends on when they start.
+func parseISO8601Duration(value string) (time.Duration, error) {
+	matches := iso8601DurationPattern.FindStringSubmatch(value)
+	if matches == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return 0, fmt.Errorf("invalid ISO 8601 duration '%s'", value)
+	}
+	if matches[1] != "" || matches[2] != "" {
+		return 0, fmt.Errorf("invalid ISO 8601 duration '%s': %w", value, ErrISO8601CalendarUnits)
+	}
+	units := []time.Duration{0, 0, 7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var duration time.Duration
+	for i, unit := range units {
+		if matches[i+1] == "" || unit == 0 {
+			continue
+		}
+		amount, err := strconv.ParseFloat(matches[i+1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO 8601 duration '%s': %w", value, err)
+		}
+		// Converting a float that's out of the range of time.Duration would silently produce a negative duration
+		if amount*float64(unit) >= float64(math.MaxInt64-duration) {
+			return 0, fmt.Errorf("invalid ISO 8601 duration '%s': %w", value, ErrTTLTooLong)
+		}
+		duration += time.Duration(amount * float64(unit))
+	}
+	return duration, nil
+}
+
+// getDeletionJitter returns the duration by which the deletion of an item annotated with AnnotationDeletionJitter
+// should be delayed after it expires.
+//
+// The jitter is derived from the item's UID, which spreads out the deletion of items that expire at the same time while
+// keeping the deletion time of each item stable across executions.
+func getDeletionJitter(item unstructured.Unstructured) time.Duration {
+	value, exists := item.GetAnnotations()[AnnotationDeletionJitter]
+	if !exists {
+		return 0
+	}
+	maximumJitter, err := parseTTL(value)
+	if err != nil || maximumJitter <= 0 {
+		logger.Debug(fmt.Sprintf("[%s/%s] has an invalid deletion jitter '%s', ignoring", item.GetKind(), item.GetName(), value))
+		return 0
+	}
+	hash := fnv.New64a()
+	if uid := item.GetUID(); uid != "" {
+		_, _ = hash.Write([]byte(uid))
+	} else {
+		_, _ = hash.Write([]byte(item.GetNamespace() + "/" + item.GetName()))
+	}
+	return time.Duration(hash.Sum64() % uint64(maximumJitter))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseTTL(t *testing.T) {
+	scenarios := []struct {
+		ttl              string
+		expectedDuration time.Duration
+		expectedError    bool
+	}{
+		{ttl: "1h", expectedDuration: time.Hour},
+		{ttl: "3d12h", expectedDuration: 84 * time.Hour},
+		{ttl: "PT1H", expectedDuration: time.Hour},
+		{ttl: "P3DT12H", expectedDuration: 84 * time.Hour},
+		{ttl: "P1W", expectedDuration: 7 * 24 * time.Hour},
+		{ttl: "PT1M30S", expectedDuration: 90 * time.Second},
+		{ttl: "PT0.5S", expectedDuration: 500 * time.Millisecond},
+		{ttl: "P1Y", expectedError: true},
+		{ttl: "P1M", expectedError: true},
+		{ttl: "P", expectedError: true},
+		{ttl: "P1DT", expectedError: true},
+		{ttl: "PT1X", expectedError: true},
+		{ttl: "invalid", expectedError: true},
+		{ttl: "3650d", expectedDuration: DefaultMaxValidTTL},
+		{ttl: "3651d", expectedError: true},
+		{ttl: "1000000d", expectedError: true},
+		{ttl: "P3650D", expectedDuration: DefaultMaxValidTTL},
+		{ttl: "P1000000D", expectedError: true},
+		{ttl: "PT99999999999999999999S", expectedError: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.ttl, func(t *testing.T) {
+			duration, err := parseTTL(scenario.ttl)
+			if scenario.expectedError != (err != nil) {
+				t.Fatalf("expected error to be %t, got %v", scenario.expectedError, err)
+			}
+			if duration != scenario.expectedDuration {
+				t.Errorf("expected %s, got %s", scenario.expectedDuration, duration)
+			}
+		})
+	}
+}
+
+func TestParseTTL_withMaxValidTTL(t *testing.T) {
+	defer func(previous time.Duration) { maxValidTTL = previous }(maxValidTTL)
+	maxValidTTL = 30 * 24 * time.Hour
+	if duration, err := parseTTL("30d"); err != nil || duration != maxValidTTL {
+		t.Errorf("expected a TTL equal to the maximum to be valid, got %s (%v)", duration, err)
+	}
+	if _, err := parseTTL("300d"); !errors.Is(err, ErrTTLTooLong) {
+		t.Errorf("expected %v, got %v", ErrTTLTooLong, err)
+	}
+	// The maximum itself may be longer than the default
+	if duration, err := parseDuration("20000d"); err != nil || duration != 20000*24*time.Hour {
+		t.Errorf("expected parseDuration not to be bound by the maximum, got %s (%v)", duration, err)
+	}
+}
+
+func TestGetDeletionJitter(t *testing.T) {
+	item := newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now(), map[string]interface{}{AnnotationDeletionJitter: "30m"})
+	item.SetUID("2f6c6d8e-7a8b-4c1d-9e0f-1a2b3c4d5e6f")
+	jitter := getDeletionJitter(*item)
+	if jitter < 0 || jitter >= 30*time.Minute {
+		t.Errorf("expected jitter to be between 0 and 30m, got %s", jitter)
+	}
+	if getDeletionJitter(*item) != jitter {
+		t.Error("expected jitter to be deterministic")
+	}
+	otherItem := item.DeepCopy()
+	otherItem.SetUID("9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d")
+	if getDeletionJitter(*otherItem) == jitter {
+		t.Error("expected items with different UIDs to have different jitters")
+	}
+	if jitter := getDeletionJitter(*newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now(), map[string]interface{}{})); jitter != 0 {
+		t.Errorf("expected no jitter for an item without the annotation, got %s", jitter)
+	}
+	if jitter := getDeletionJitter(*newUnstructuredWithAnnotations("v1", "Pod", "default", "pod-name", time.Now(), map[string]interface{}{AnnotationDeletionJitter: "invalid"})); jitter != 0 {
+		t.Errorf("expected no jitter for an item with an invalid annotation, got %s", jitter)
+	}
+}
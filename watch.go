@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TwiN/kevent"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Watcher uses informers to watch resources and delete them as soon as their TTL expires, rather than waiting for the
+// next execution to notice that they have expired.
+//
+// Only resources with a TTL of their own are handled by the Watcher. Resources whose deletion depends on their owner or
+// on other resources (e.g. AnnotationTTLRelativeToOwner, AnnotationKeepLast) are left to the periodic reconciliation,
+// which lists them from the Watcher's cache rather than from the API server.
+//
+// If a metadata client is provided, resources that don't require full objects are watched as PartialObjectMetadata,
+// which keeps the memory footprint of the cache to a minimum.
+type Watcher struct {
+	dynamicClient   dynamic.Interface
+	eventManager    *kevent.EventManager
+	factory         dynamicinformer.DynamicSharedInformerFactory
+	metadataFactory metadatainformer.SharedInformerFactory
+	informers       map[schema.GroupVersionResource]cache.SharedIndexInformer
+	kinds           map[schema.GroupVersionResource]string // Kind of each watched resource
+
+	timers map[string]*time.Timer // Scheduled deletions, keyed by GVR and namespace/name
+	mutex  sync.Mutex
+}
+
+// NewWatcher creates a new Watcher for every API resource that can be reconciled and watched.
+//
+// metadataClient may be nil, in which case all resources are watched as full objects.
+func NewWatcher(dynamicClient dynamic.Interface, metadataClient metadata.Interface, eventManager *kevent.EventManager, resources []*metav1.APIResourceList) *Watcher {
+	w := &Watcher{
+		dynamicClient: dynamicClient,
+		eventManager:  eventManager,
+		factory:       dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0),
+		informers:     make(map[schema.GroupVersionResource]cache.SharedIndexInformer),
+		kinds:         make(map[schema.GroupVersionResource]string),
+		timers:        make(map[string]*time.Timer),
+	}
+	if metadataClient != nil {
+		w.metadataFactory = metadatainformer.NewSharedInformerFactory(metadataClient, 0)
+	}
+	for _, resource := range resources {
+		gv, err := schema.ParseGroupVersion(resource.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, apiResource := range resource.APIResources {
+			gvr := gv.WithResource(apiResource.Name)
+			// Skip subresources such as pods/status, as well as resources that cannot be watched
+			if strings.Contains(apiResource.Name, "/") || !isReconcilable(gvr, apiResource) || !contains(apiResource.Verbs, "watch") {
+				continue
+			}
+			var informer cache.SharedIndexInformer
+			if w.metadataFactory != nil && !requiresFullObjects(apiResource) {
+				informer = w.metadataFactory.ForResource(gvr).Informer()
+			} else {
+				informer = w.factory.ForResource(gvr).Informer()
+			}
+			_, _ = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+				AddFunc:    func(obj interface{}) { w.schedule(gvr, obj) },
+				UpdateFunc: func(_, obj interface{}) { w.schedule(gvr, obj) },
+				DeleteFunc: func(obj interface{}) { w.schedule(gvr, obj) },
+			})
+			w.informers[gvr] = informer
+			w.kinds[gvr] = apiResource.Kind
+		}
+	}
+	return w
+}
+
+// Start starts the informers in the background.
+//
+// Until the cache of an informer has synced, its resource is listed from the API server as usual.
+func (w *Watcher) Start(stopCh <-chan struct{}) {
+	logger.Info(fmt.Sprintf("[Watcher] Watching %d API resources", len(w.informers)))
+	w.factory.Start(stopCh)
+	if w.metadataFactory != nil {
+		w.metadataFactory.Start(stopCh)
+	}
+}
+
+// List returns the items of a resource from the cache of its informer.
+//
+// Returns false if the resource is not watched, or if the cache of its informer has not synced yet.
+func (w *Watcher) List(gvr schema.GroupVersionResource) ([]unstructured.Unstructured, bool) {
+	informer, exists := w.informers[gvr]
+	if !exists || !informer.HasSynced() {
+		return nil, false
+	}
+	objects := informer.GetStore().List()
+	items := make([]unstructured.Unstructured, 0, len(objects))
+	for _, obj := range objects {
+		if item, ok := w.toItem(gvr, obj); ok {
+			items = append(items, *item)
+		}
+	}
+	return items, true
+}
+
+// Handles checks whether the deletion of an item is taken care of by the Watcher
+func (w *Watcher) Handles(gvr schema.GroupVersionResource, item unstructured.Unstructured) bool {
+	informer, exists := w.informers[gvr]
+	if !exists || !informer.HasSynced() {
+		return false
+	}
+	_, _, handled := getSchedulableExpiration(item)
+	return handled
+}
+
+// schedule (re)schedules the deletion of an item that was added, updated or deleted
+func (w *Watcher) schedule(gvr schema.GroupVersionResource, obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	item, ok := w.toItem(gvr, obj)
+	if !ok {
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(item)
+	if err != nil {
+		return
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if timer, exists := w.timers[timerKey(gvr, key)]; exists {
+		timer.Stop()
+		delete(w.timers, timerKey(gvr, key))
+	}
+	// If the item was deleted, it is no longer in the store and there's nothing left to schedule
+	if _, exists, _ := w.informers[gvr].GetStore().GetByKey(key); !exists || item.GetDeletionTimestamp() != nil {
+		return
+	}
+	ttl, expiresAt, handled := getSchedulableExpiration(*item)
+	if !handled {
+		return
+	}
+	if time.Now().Before(expiresAt) {
+		logger.Debug(fmt.Sprintf("[%s/%s] is configured with a TTL of %s, scheduling its deletion in %s", gvr.Resource, item.GetName(), ttl, time.Until(expiresAt).Round(time.Second)))
+	}
+	w.scheduleAt(gvr, key, expiresAt.Add(clockSkewTolerance))
+}
+
+// scheduleAt schedules the deletion of the item with the given key at a given time.
+//
+// Must be called with the mutex held.
+func (w *Watcher) scheduleAt(gvr schema.GroupVersionResource, key string, at time.Time) {
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(at), func() {
+		w.mutex.Lock()
+		// The deletion may have been rescheduled or cancelled while this timer was firing
+		if w.timers[timerKey(gvr, key)] != timer {
+			w.mutex.Unlock()
+			return
+		}
+		delete(w.timers, timerKey(gvr, key))
+		w.mutex.Unlock()
+		// The mutex is released before expiring the item, as its deletion may have to wait for deletions to resume
+		w.expire(gvr, key)
+	})
+	w.timers[timerKey(gvr, key)] = timer
+}
+
+// toItem converts an object from the cache of an informer, which is either an Unstructured or a PartialObjectMetadata,
+// to an Unstructured
+func (w *Watcher) toItem(gvr schema.GroupVersionResource, obj interface{}) (*unstructured.Unstructured, bool) {
+	switch object := obj.(type) {
+	case *unstructured.Unstructured:
+		return object, true
+	case *metav1.PartialObjectMetadata:
+		item, err := toUnstructured(gvr, w.kinds[gvr], object)
+		return item, err == nil
+	}
+	return nil, false
+}
+
+// timerKey returns the key of the timer scheduling the deletion of an item
+func timerKey(gvr schema.GroupVersionResource, key string) string {
+	return gvr.String() + "|" + key
+}
+
+// expire deletes the item with the given key if it has expired.
+//
+// Must be called without holding the mutex.
+func (w *Watcher) expire(gvr schema.GroupVersionResource, key string) {
+	obj, exists, err := w.informers[gvr].GetStore().GetByKey(key)
+	if err != nil || !exists {
+		return
+	}
+	item, ok := w.toItem(gvr, obj)
+	if !ok || item.GetDeletionTimestamp() != nil {
+		return
+	}
+	ttl, expiresAt, handled := getSchedulableExpiration(*item)
+	if !handled {
+		return
+	}
+	if !isExpired(expiresAt) {
+		w.reschedule(gvr, key, expiresAt.Add(clockSkewTolerance))
+		return
+	}
+	if !deleteExpiredResource(w.dynamicClient, w.eventManager, NewNamespaceCache(w.dynamicClient), gvr, *item, ttl, expiresAt) {
+		// The deletion was either deferred or failed, so try again later
+		w.reschedule(gvr, key, time.Now().Add(ExecutionInterval))
+	}
+}
+
+// reschedule schedules the deletion of the item with the given key at a given time, unless its deletion was already
+// rescheduled (e.g. because it was updated) since its timer fired
+func (w *Watcher) reschedule(gvr schema.GroupVersionResource, key string, at time.Time) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if _, exists := w.timers[timerKey(gvr, key)]; exists {
+		return
+	}
+	w.scheduleAt(gvr, key, at)
+}
+
+// getSchedulableExpiration returns the TTL of an item and the time at which it expires, as long as the item's deletion can
+// be scheduled ahead of time, which is only the case for items with a TTL of their own
+func getSchedulableExpiration(item unstructured.Unstructured) (string, time.Time, bool) {
+	annotations := item.GetAnnotations()
+	// Whether these items should be deleted depends on other resources, so they are left to the reconciliation
+	if _, exists := annotations[AnnotationTTLRelativeToOwner]; exists {
+		return "", time.Time{}, false
+	}
+	if _, exists := annotations[AnnotationKeepLast]; exists {
+		return "", time.Time{}, false
+	}
+	if startsTTLWhenReady(item) {
+		// The Ready condition of these items may not be cached, so they are left to the reconciliation
+		return "", time.Time{}, false
+	}
+	if deleteAt, exists, err := getDeleteAt(item); exists {
+		// Invalid delete-at timestamps are left to the reconciliation, which takes care of logging them
+		return getDeleteAtTTL(item, deleteAt), deleteAt, err == nil
+	}
+	_, ttl, exists := getTTL(item)
+	if !exists || isTTLDisabled(ttl) {
+		// Items with a disabled TTL never expire, so there is nothing to schedule
+		return "", time.Time{}, false
+	}
+	if invalid, _ := hasInvalidRefreshedAt(item); invalid {
+		// Invalid refreshed-at timestamps are left to the reconciliation, which takes care of reporting them
+		return "", time.Time{}, false
+	}
+	ttlInDuration, err := parseTTL(ttl)
+	if err != nil {
+		// Invalid TTLs are left to the reconciliation, which takes care of logging them
+		return "", time.Time{}, false
+	}
+	ttl, ttlInDuration, _ = capTTL(item, ttl, ttlInDuration)
+	return ttl, getStartTime(item).Add(ttlInDuration).Add(getDeletionJitter(item)).Add(currentLiveConfiguration().GlobalTTLExtension), true
+}
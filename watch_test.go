@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestWatcher(t *testing.T) {
+	watchablePodsAPIResourceList := &metav1.APIResourceList{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "pods", Kind: "Pod", Namespaced: true, Verbs: []string{"delete", "get", "list", "watch"}}},
+	}
+	_, dynamicClient, eventManager := newTestClients(watchablePodsAPIResourceList)
+	pods := []*unstructured.Unstructured{
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expired-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "expiring-pod-name", time.Now(), map[string]interface{}{AnnotationTTL: "2s"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "not-expired-pod-name", time.Now(), map[string]interface{}{AnnotationTTL: "3d"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "keep-last-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "5m", AnnotationKeepLast: "1"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "zero-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "0"}),
+		newUnstructuredWithAnnotations("v1", "Pod", "default", "zero-seconds-pod-name", time.Now().Add(-time.Hour), map[string]interface{}{AnnotationTTL: "0s"}),
+	}
+	for _, pod := range pods {
+		if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	w := NewWatcher(dynamicClient, nil, eventManager, []*metav1.APIResourceList{watchablePodsAPIResourceList})
+	w.Start(stopCh)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		remaining := listNames(t, dynamicClient, podsGVR, "default")
+		if !remaining["expired-pod-name"] && !remaining["expiring-pod-name"] {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the expired pod and the expiring pod to have been deleted by the watcher, got %v", remaining)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	remaining := listNames(t, dynamicClient, podsGVR, "default")
+	if !remaining["not-expired-pod-name"] || !remaining["keep-last-pod-name"] || !remaining["zero-pod-name"] || !remaining["zero-seconds-pod-name"] {
+		t.Errorf("expected the pod that has not expired, the keep-last pod and the pods with a disabled TTL to have been left alone, got %v", remaining)
+	}
+	if _, synced := w.List(podsGVR); !synced {
+		t.Error("expected pods to be served from the watcher's cache")
+	}
+	if w.Handles(podsGVR, *pods[3]) {
+		t.Error("expected the keep-last pod to be left to the reconciliation")
+	}
+	if w.Handles(podsGVR, *pods[4]) || w.Handles(podsGVR, *pods[5]) {
+		t.Error("expected the pods with a disabled TTL not to be handled by the watcher")
+	}
+	if !w.Handles(podsGVR, *pods[2]) {
+		t.Error("expected the pod that has not expired to be handled by the watcher")
+	}
+}
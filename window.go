@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// DeletionWindow is a daily window of time, in UTC, during which deletions are allowed
+type DeletionWindow struct {
+	raw   string
+	start time.Duration // Offset from midnight at which the window starts
+	end   time.Duration // Offset from midnight at which the window ends
+}
+
+// ParseDeletionWindow parses a deletion window in the format HH:MM-HH:MM (e.g. 22:00-06:00).
+//
+// Windows whose end is before their start span over midnight.
+func ParseDeletionWindow(window string) (*DeletionWindow, error) {
+	parts := strings.Split(strings.TrimSpace(window), "-")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid deletion window '%s': expected format HH:MM-HH:MM", window)
+	}
+	start, err := parseTimeOfDay(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid deletion window '%s': %w", window, err)
+	}
+	end, err := parseTimeOfDay(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid deletion window '%s': %w", window, err)
+	}
+	return &DeletionWindow{raw: window, start: start, end: end}, nil
+}
+
+// Contains returns whether the given time is within the deletion window
+func (w *DeletionWindow) Contains(t time.Time) bool {
+	t = t.UTC()
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.start <= w.end {
+		return offset >= w.start && offset < w.end
+	}
+	return offset >= w.start || offset < w.end
+}
+
+func (w *DeletionWindow) String() string {
+	return w.raw
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// getDeletionWindow returns the deletion window that applies to an item, which is the one specified on the item's
+// namespace through AnnotationDeletionWindow if any, or the global deletion window otherwise.
+//
+// Returns nil if no deletion window applies, meaning that deletions are allowed at all times.
+func getDeletionWindow(namespaces *NamespaceCache, item unstructured.Unstructured) *DeletionWindow {
+	if value, exists := namespaces.GetAnnotation(item.GetNamespace(), AnnotationDeletionWindow); exists {
+		window, err := ParseDeletionWindow(value)
+		if err == nil {
+			return window
+		}
+		logger.Info(fmt.Sprintf("Namespace %s has an invalid %s annotation, falling back to the global deletion window: %s", item.GetNamespace(), AnnotationDeletionWindow, err))
+	}
+	return deletionWindow
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDeletionWindow(t *testing.T) {
+	scenarios := []struct {
+		window        string
+		expectedError bool
+	}{
+		{window: "22:00-06:00", expectedError: false},
+		{window: "09:30-17:00", expectedError: false},
+		{window: "22:00", expectedError: true},
+		{window: "22:00-25:00", expectedError: true},
+		{window: "a-b", expectedError: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.window, func(t *testing.T) {
+			_, err := ParseDeletionWindow(scenario.window)
+			if scenario.expectedError != (err != nil) {
+				t.Errorf("expected error to be %t, got %v", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestDeletionWindow_Contains(t *testing.T) {
+	scenarios := []struct {
+		window   string
+		time     time.Time
+		expected bool
+	}{
+		{window: "09:00-17:00", time: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), expected: true},
+		{window: "09:00-17:00", time: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), expected: true},
+		{window: "09:00-17:00", time: time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC), expected: false},
+		{window: "09:00-17:00", time: time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC), expected: false},
+		{window: "22:00-06:00", time: time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC), expected: true},
+		{window: "22:00-06:00", time: time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC), expected: true},
+		{window: "22:00-06:00", time: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), expected: false},
+		{window: "22:00-06:00", time: time.Date(2024, 1, 1, 1, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60)), expected: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.window+"-"+scenario.time.String(), func(t *testing.T) {
+			window, err := ParseDeletionWindow(scenario.window)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := window.Contains(scenario.time); actual != scenario.expected {
+				t.Errorf("expected %t, got %t", scenario.expected, actual)
+			}
+		})
+	}
+}